@@ -0,0 +1,63 @@
+package browser
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Frames of the spinner shown while background operations run
+var activityFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// How often the spinner turns
+const activityInterval = 100 * time.Millisecond
+
+// Custom message turning the spinner
+type activityTickMsg struct{}
+
+// startOp counts a background operation as running until finishOp is
+// called from its finish message
+func (m *FileManager) startOp() {
+	m.activeOps++
+}
+
+// finishOp counts a background operation as done
+func (m *FileManager) finishOp() {
+	m.activeOps = max(m.activeOps-1, 0)
+}
+
+// startActivity starts turning the spinner once an operation is running
+func (m *FileManager) startActivity() tea.Cmd {
+	if m.activeOps == 0 || m.activityTicking {
+		return nil
+	}
+	m.activityTicking = true
+	return activityTick()
+}
+
+// activityTick turns the spinner after a while
+func activityTick() tea.Cmd {
+	return tea.Tick(activityInterval, func(time.Time) tea.Msg {
+		return activityTickMsg{}
+	})
+}
+
+// handleActivityTick turns the spinner, stopping once nothing is running
+func (m *FileManager) handleActivityTick() tea.Cmd {
+	if m.activeOps == 0 {
+		m.activityTicking = false
+		return nil
+	}
+	m.activityFrame = (m.activityFrame + 1) % len(activityFrames)
+	return activityTick()
+}
+
+// activityIndicator returns the spinner and the number of operations
+// running, or "" when there are none
+func (m *FileManager) activityIndicator() string {
+	if m.activeOps == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s %d", activityFrames[m.activityFrame], m.activeOps)
+}
@@ -0,0 +1,487 @@
+package browser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// errArchiveReadOnly is returned when writing inside an archive
+var errArchiveReadOnly = errors.New("archives are read-only, extract with U")
+
+// isArchive reports whether a file can be browsed as an archive
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveMember is a file or directory inside an archive
+type archiveMember struct {
+	info fs.FileInfo
+	open func() (io.ReadCloser, error) // nil for directories
+}
+
+// archiveFS shows the contents of an archive as a read-only directory at the
+// archive's path. Paths outside it go to the filesystem it was opened from,
+// so copying out of the archive works like any other copy.
+type archiveFS struct {
+	root     string                   // Path of the archive file
+	outer    FS                       // Filesystem for everything outside the archive
+	members  map[string]archiveMember // By slash-separated path below root, "." is root
+	children map[string][]string      // Member names by directory
+	closer   io.Closer                // Closes the archive file, if kept open
+}
+
+// openArchive reads the index of an archive through the filesystem it is on
+func openArchive(archivePath string, outer FS) (*archiveFS, error) {
+	info, err := outer.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	a := &archiveFS{
+		root:     archivePath,
+		outer:    outer,
+		members:  map[string]archiveMember{".": {info: archiveDirInfo{name: ".", modTime: info.ModTime()}}},
+		children: map[string][]string{},
+	}
+
+	if isZip(archivePath) {
+		err = a.readZip()
+	} else {
+		err = a.readTar()
+	}
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	for _, names := range a.children {
+		sort.Strings(names)
+	}
+	return a, nil
+}
+
+// readZip indexes a zip archive. Members are read from it on demand, so it
+// stays open until Close.
+func (a *archiveFS) readZip() error {
+	reader, closer, err := openZip(a.outer, a.root)
+	if err != nil {
+		return err
+	}
+	a.closer = closer
+	for _, file := range reader.File {
+		a.add(file.Name, file.FileInfo(), file.Open)
+	}
+	return nil
+}
+
+// readTar indexes a tar archive, gzipped or not, keeping only where each
+// member is
+func (a *archiveFS) readTar() error {
+	i := 0
+	return listArchive(a.outer, a.root, func(name string, info fs.FileInfo, _ func() (io.ReadCloser, error)) error {
+		a.add(name, info, a.tarMember(i))
+		i++
+		return nil
+	})
+}
+
+// tarMember returns a function opening the i-th member of the tar archive.
+// A tar can only be read in order, so the archive is read from its start
+// up to the member each time.
+func (a *archiveFS) tarMember(i int) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		archive, closer, err := openTar(a.outer, a.root)
+		if err != nil {
+			return nil, err
+		}
+		for n := 0; n <= i; n++ {
+			if _, err := archive.Next(); err != nil {
+				closer.Close()
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF // The archive changed since it was indexed
+				}
+				return nil, err
+			}
+		}
+		return readCloser{archive, closer}, nil
+	}
+}
+
+// readCloser reads from a reader and closes what it comes from
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// isZip reports whether an archive is a zip archive rather than a tar
+func isZip(archivePath string) bool {
+	return strings.HasSuffix(strings.ToLower(archivePath), ".zip")
+}
+
+// openZip opens a zip archive through fsys. Zip needs random access, so an
+// archive fsys can't seek in, like one inside another archive, is read whole.
+func openZip(fsys FS, archivePath string) (*zip.Reader, io.Closer, error) {
+	info, err := fsys.Stat(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := fsys.Open(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if at, ok := file.(io.ReaderAt); ok {
+		reader, err := zip.NewReader(at, info.Size())
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return reader, file, nil
+	}
+
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, io.NopCloser(nil), nil
+}
+
+// openTar opens a tar archive through fsys, gunzipping it when its name
+// says it is compressed. The closer closes the archive file.
+func openTar(fsys FS, archivePath string) (*tar.Reader, io.Closer, error) {
+	file, err := fsys.Open(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	name := strings.ToLower(archivePath)
+	if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return tar.NewReader(gz), file, nil
+	}
+	return tar.NewReader(file), file, nil
+}
+
+// listArchive calls fn with each member of an archive, in the order it is
+// stored, and a function opening its contents that is valid until fn
+// returns. It stops at the first error fn returns.
+func listArchive(fsys FS, archivePath string, fn func(name string, info fs.FileInfo, open func() (io.ReadCloser, error)) error) error {
+	if isZip(archivePath) {
+		reader, closer, err := openZip(fsys, archivePath)
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		for _, member := range reader.File {
+			if err := fn(member.Name, member.FileInfo(), member.Open); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	archive, closer, err := openTar(fsys, archivePath)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	for {
+		// Next skips over the contents of the member before
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(archive), nil }
+		if err := fn(header.Name, header.FileInfo(), open); err != nil {
+			return err
+		}
+	}
+}
+
+// add records a member and any parent directories the archive leaves implicit
+func (a *archiveFS) add(name string, info fs.FileInfo, open func() (io.ReadCloser, error)) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	// Entries pointing outside the archive aren't listed
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return
+	}
+	if info.IsDir() {
+		open = nil
+	}
+	if _, ok := a.members[name]; !ok {
+		dir := path.Dir(name)
+		a.children[dir] = append(a.children[dir], path.Base(name))
+	}
+	a.members[name] = archiveMember{info: info, open: open}
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := a.members[dir]; ok {
+			break
+		}
+		a.members[dir] = archiveMember{info: archiveDirInfo{name: path.Base(dir), modTime: info.ModTime()}}
+		parent := path.Dir(dir)
+		a.children[parent] = append(a.children[parent], path.Base(dir))
+	}
+}
+
+// member returns the path of name below the archive root, or false when
+// name is outside the archive
+func (a *archiveFS) member(name string) (string, bool) {
+	if name == a.root {
+		return ".", true
+	}
+	rel, ok := strings.CutPrefix(name, a.root+string(filepath.Separator))
+	if !ok {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// contains reports whether a path is the archive or inside it
+func (a *archiveFS) contains(name string) bool {
+	_, ok := a.member(name)
+	return ok
+}
+
+func (a *archiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	rel, ok := a.member(name)
+	if !ok {
+		return a.outer.ReadDir(name)
+	}
+	member, ok := a.members[rel]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !member.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	var entries []fs.DirEntry
+	for _, child := range a.children[rel] {
+		entries = append(entries, fs.FileInfoToDirEntry(a.members[path.Join(rel, child)].info))
+	}
+	return entries, nil
+}
+
+func (a *archiveFS) Stat(name string) (fs.FileInfo, error) {
+	rel, ok := a.member(name)
+	if !ok {
+		return a.outer.Stat(name)
+	}
+	member, ok := a.members[rel]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return member.info, nil
+}
+
+func (a *archiveFS) Open(name string) (io.ReadCloser, error) {
+	rel, ok := a.member(name)
+	if !ok {
+		return a.outer.Open(name)
+	}
+	member, ok := a.members[rel]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if member.open == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	return member.open()
+}
+
+func (a *archiveFS) Create(name string) (io.WriteCloser, error) {
+	if a.contains(name) {
+		return nil, errArchiveReadOnly
+	}
+	return a.outer.Create(name)
+}
+
+func (a *archiveFS) MkdirAll(name string, perm fs.FileMode) error {
+	if a.contains(name) {
+		return errArchiveReadOnly
+	}
+	return a.outer.MkdirAll(name, perm)
+}
+
+func (a *archiveFS) Rename(oldpath, newpath string) error {
+	if a.contains(oldpath) || a.contains(newpath) {
+		return errArchiveReadOnly
+	}
+	return a.outer.Rename(oldpath, newpath)
+}
+
+func (a *archiveFS) RemoveAll(name string) error {
+	if a.contains(name) {
+		return errArchiveReadOnly
+	}
+	return a.outer.RemoveAll(name)
+}
+
+func (a *archiveFS) Chmod(name string, mode fs.FileMode) error {
+	if a.contains(name) {
+		return errArchiveReadOnly
+	}
+	if meta, ok := a.outer.(metadataFS); ok {
+		return meta.Chmod(name, mode)
+	}
+	return nil
+}
+
+func (a *archiveFS) Chtimes(name string, atime, mtime time.Time) error {
+	if a.contains(name) {
+		return errArchiveReadOnly
+	}
+	if meta, ok := a.outer.(metadataFS); ok {
+		return meta.Chtimes(name, atime, mtime)
+	}
+	return nil
+}
+
+// Close releases the archive file
+func (a *archiveFS) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// archiveDirInfo describes a directory only implied by member paths
+type archiveDirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (d archiveDirInfo) Name() string       { return d.name }
+func (d archiveDirInfo) Size() int64        { return 0 }
+func (d archiveDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0755 }
+func (d archiveDirInfo) ModTime() time.Time { return d.modTime }
+func (d archiveDirInfo) IsDir() bool        { return true }
+func (d archiveDirInfo) Sys() any           { return nil }
+
+// Custom message carrying an archive opened in the background
+type archiveOpenedMsg struct {
+	path    string
+	archive *archiveFS
+	err     error
+}
+
+// enterArchive reads the index of an archive in the background and starts
+// browsing it as a directory once read. Until then the listing shows a
+// placeholder, as when a directory is read.
+func (m *FileManager) enterArchive(entry FileEntry) tea.Cmd {
+	m.loadingDir = entry.Path
+	m.loadingSelect = ""
+	m.startOp()
+	outer := m.filesystem()
+	return func() tea.Msg {
+		archive, err := openArchive(entry.Path, outer)
+		if err != nil {
+			err = fmt.Errorf("opening %s: %w", entry.Name, err)
+		}
+		return archiveOpenedMsg{path: entry.Path, archive: archive, err: err}
+	}
+}
+
+// handleArchiveOpened browses an archive opened in the background, unless
+// another load was started since
+func (m *FileManager) handleArchiveOpened(msg archiveOpenedMsg) {
+	m.finishOp()
+	if msg.path != m.loadingDir {
+		if msg.archive != nil {
+			msg.archive.Close()
+		}
+		return
+	}
+	m.loadingDir = ""
+	if msg.err != nil {
+		m.setError(msg.err)
+		return
+	}
+	m.archive = msg.archive
+	if !m.openDir(msg.path, "") {
+		msg.archive.Close()
+		m.archive = nil
+	}
+}
+
+// leaveArchiveIfOutside closes the archive being browsed once navigation
+// has left it
+func (m *FileManager) leaveArchiveIfOutside() {
+	if m.archive != nil && !m.archive.contains(m.CurrentPath) {
+		m.archive.Close()
+		m.archive = nil
+	}
+}
+
+// extractSelected copies the selected archive member next to the archive
+func (m *FileManager) extractSelected() {
+	if m.archive == nil {
+		m.setStatus("Not in an archive")
+		return
+	}
+	if len(m.Entries) == 0 || m.Cursor >= len(m.Entries) {
+		return
+	}
+	entry := m.Entries[m.Cursor]
+
+	fsys := m.filesystem()
+	dir := filepath.Dir(m.archive.root)
+	dest := uniquePath(fsys, filepath.Join(dir, entry.Name))
+	m.runHook("pre_copy", dest, entry.Path)
+	if err := copyPath(fsys, entry.Path, dest); err != nil {
+		fsys.RemoveAll(dest)
+		m.setError(err)
+		return
+	}
+	m.runHook("post_copy", dest, entry.Path)
+	m.pushUndo(UndoAction{
+		Type:    "copy",
+		OldPath: entry.Path,
+		NewPath: dest,
+		Entry:   entry,
+		Archive: m.archive.root,
+	})
+	m.setStatus("Extracted %s to %s", entry.Name, dest)
+}
+
+// reextract copies an extracted member out of its archive again, opening
+// the archive unless it is the one being browsed
+func (m *FileManager) reextract(action *UndoAction) error {
+	fsys := m.filesystem()
+	if m.archive == nil || m.archive.root != action.Archive {
+		archive, err := openArchive(action.Archive, fsys)
+		if err != nil {
+			return err
+		}
+		defer archive.Close()
+		fsys = archive
+	}
+	return copyPath(fsys, action.OldPath, action.NewPath)
+}
@@ -0,0 +1,255 @@
+package browser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// writeZip creates a zip archive holding files by name
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTarGz creates a gzipped tar archive holding files in the given order
+func writeTarGz(t *testing.T, path string, files ...string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	archive := tar.NewWriter(gz)
+	for i := 0; i+1 < len(files); i += 2 {
+		name, content := files[i], files[i+1]
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		archive.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrowseArchive(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "docs.zip")
+	writeZip(t, archivePath, map[string]string{"guide/intro.txt": "hello", "README": "read me"})
+
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	m.selectByName("docs.zip")
+	send(m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	if m.archive == nil || m.CurrentPath != archivePath {
+		t.Fatalf("expected to browse the archive, at %s", m.CurrentPath)
+	}
+	// The guide directory is only implied by its member's path
+	if len(m.Entries) != 2 || m.Entries[0].Name != "guide" || !m.Entries[0].IsDir {
+		t.Fatalf("unexpected archive listing %v", m.Entries)
+	}
+
+	send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.selectByName("intro.txt") {
+		t.Fatalf("intro.txt not listed in %s", m.CurrentPath)
+	}
+	m.extractSelected()
+	if content, err := os.ReadFile(filepath.Join(dir, "intro.txt")); err != nil || string(content) != "hello" {
+		t.Fatalf("member not extracted next to the archive: %q, %v", content, err)
+	}
+	if err := m.filesystem().RemoveAll(m.Entries[m.Cursor].Path); err != errArchiveReadOnly {
+		t.Errorf("expected the archive to be read-only, got %v", err)
+	}
+	if m.confirmPermanentDelete(); m.confirm != nil || m.statusMsg != "Can't delete inside an archive" {
+		t.Errorf("expected permanent deletes refused inside the archive, got %q", m.statusMsg)
+	}
+
+	// h at the archive root returns to the real directory
+	send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}})
+	send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}})
+	if m.archive != nil || m.CurrentPath != dir {
+		t.Fatalf("expected to leave the archive for %s, at %s", dir, m.CurrentPath)
+	}
+	if m.Entries[m.Cursor].Name != "docs.zip" {
+		t.Errorf("cursor on %s after leaving the archive", m.Entries[m.Cursor].Name)
+	}
+
+	// Redoing the extraction outside the archive reads the member from it again
+	m.undoLastAction()
+	if _, err := os.Stat(filepath.Join(dir, "intro.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected the extracted member undone, got %v", err)
+	}
+	m.redoLastAction()
+	if content, err := os.ReadFile(filepath.Join(dir, "intro.txt")); err != nil || string(content) != "hello" {
+		t.Errorf("member not extracted again: %q, %v (%s)", content, err, m.statusMsg)
+	}
+}
+
+func TestBrowseTarArchive(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "src.tar.gz")
+	writeTarGz(t, archivePath, "a.txt", "first", "sub/b.txt", "second")
+
+	// Members are read from the archive when opened, not kept in memory
+	archive, err := openArchive(archivePath, OS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	for name, want := range map[string]string{"a.txt": "first", "sub/b.txt": "second"} {
+		file, err := archive.Open(filepath.Join(archivePath, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil || string(content) != want {
+			t.Errorf("expected %s to hold %q, got %q, %v", name, want, content, err)
+		}
+	}
+
+	// Entering one shows a placeholder until the index is read
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20}
+	m.Entries, _ = m.readDirectory(dir)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.loadingDir != archivePath || cmd == nil {
+		t.Fatalf("expected the archive opened in the background, loading %q", m.loadingDir)
+	}
+	m.Update(awaitLoad(cmd))
+	if m.archive == nil || m.CurrentPath != archivePath || len(m.Entries) != 2 {
+		t.Fatalf("expected to browse the archive, at %s with %v", m.CurrentPath, m.Entries)
+	}
+}
+
+func TestArchivePreview(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "docs.zip")
+	writeZip(t, archivePath, map[string]string{"guide/intro.txt": "hello", "README": "read me", "LICENSE": "mit"})
+
+	// The listing is read in the background and cached for View
+	m := &FileManager{CurrentPath: dir, Width: 100, Height: 20, autoPreview: true}
+	m.Entries, _ = m.readDirectory(dir)
+	cmd := m.startPreview()
+	if cmd == nil {
+		t.Fatal("expected the archive listed in the background")
+	}
+	m.Update(cmd())
+	preview := m.renderEntryPreview(m.Entries[m.Cursor], 60, 10)
+	for _, want := range []string{"guide/intro.txt", "5B", "README", "7B"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("expected %s in the preview:\n%s", want, preview)
+		}
+	}
+	if m.startPreview() != nil {
+		t.Error("expected the listing cached")
+	}
+
+	// The ... for members that don't fit is one of the lines
+	listing := listArchivePreview(OS, archivePath)
+	if preview := renderArchivePreview(listing, 60, 2); strings.Count(preview, "\n") != 1 || !strings.HasSuffix(preview, "...") {
+		t.Errorf("expected one member and ..., got:\n%s", preview)
+	}
+	if preview := renderArchivePreview(listing, 60, 3); strings.Count(preview, "\n") != 2 || strings.Contains(preview, "...") {
+		t.Errorf("expected all three members, got:\n%s", preview)
+	}
+
+	broken := filepath.Join(dir, "broken.tar.gz")
+	if err := os.WriteFile(broken, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if preview := renderArchivePreview(listArchivePreview(OS, broken), 60, 10); !strings.HasPrefix(preview, "Error reading archive") {
+		t.Errorf("expected an error line for a malformed archive, got %q", preview)
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "docs.zip")
+	writeZip(t, archivePath, map[string]string{"guide/intro.txt": "hello", "README": "read me"})
+	m := &FileManager{CurrentPath: dir, trashDir: t.TempDir()}
+	m.Entries, _ = m.readDirectory(dir)
+	m.selectByName("docs.zip")
+
+	// run runs an extraction to the end
+	run := func(cmd tea.Cmd) {
+		t.Helper()
+		if cmd == nil {
+			t.Fatalf("extraction didn't start: %s", m.statusMsg)
+		}
+		for {
+			switch msg := cmd().(type) {
+			case extractProgressMsg:
+				cmd = m.handleExtractProgress(msg)
+			case extractDoneMsg:
+				m.handleExtractDone(msg)
+				return
+			}
+		}
+	}
+	extract := func() { run(m.startExtract()) }
+
+	extract()
+	if content, err := os.ReadFile(filepath.Join(dir, "docs", "guide", "intro.txt")); err != nil || string(content) != "hello" {
+		t.Fatalf("expected guide/intro.txt extracted into docs: %q, %v", content, err)
+	}
+	if m.Entries[m.Cursor].Name != "docs" {
+		t.Errorf("expected the cursor on docs, got %s", m.Entries[m.Cursor].Name)
+	}
+	// Undo moves what was extracted to the trash, and redo extracts it again
+	m.undoLastAction()
+	if _, err := os.Stat(filepath.Join(dir, "docs")); !os.IsNotExist(err) {
+		t.Fatalf("expected undo to remove docs: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(m.trashDir, "docs", "README")); err != nil {
+		t.Fatalf("expected docs in the trash: %v", err)
+	}
+	run(m.redoLastAction())
+	if content, err := os.ReadFile(filepath.Join(dir, "docs", "README")); err != nil || string(content) != "read me" {
+		t.Fatalf("expected redo to extract docs again: %q, %v", content, err)
+	}
+	m.undoLastAction()
+
+	// Members can't be written outside the new directory
+	writeZip(t, archivePath, map[string]string{"ok.txt": "fine", "../evil.txt": "gotcha"})
+	m.selectByName("docs.zip")
+	extract()
+	if !m.statusIsError {
+		t.Errorf("expected a member leaving the directory to fail, got %q", m.statusMsg)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); !os.IsNotExist(err) {
+		t.Fatalf("member written outside the directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "docs")); !os.IsNotExist(err) {
+		t.Fatalf("expected the partial extraction removed: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Members listed in an archive's preview at most
+const archivePreviewLimit = 500
+
+// errPreviewFull stops listing an archive once the preview is full
+var errPreviewFull = errors.New("preview full")
+
+// archiveListing is a member listed in an archive's preview
+type archiveListing struct {
+	name string
+	size int64
+	dir  bool
+}
+
+// listArchivePreview lists the first members of an archive for its
+// preview. An archive that can't be read says why after the members read
+// so far.
+func listArchivePreview(fsys FS, archivePath string) loadedPreview {
+	var preview loadedPreview
+	err := listArchive(fsys, archivePath, func(name string, info fs.FileInfo, _ func() (io.ReadCloser, error)) error {
+		if len(preview.members) == archivePreviewLimit {
+			return errPreviewFull
+		}
+		preview.members = append(preview.members, archiveListing{
+			name: strings.TrimSuffix(name, "/"),
+			size: info.Size(),
+			dir:  info.IsDir(),
+		})
+		return nil
+	})
+	if errors.Is(err, errPreviewFull) {
+		preview.more = true
+	} else if err != nil {
+		preview.text = "Error reading archive: " + err.Error()
+	}
+	return preview
+}
+
+// renderArchivePreview renders the members of an archive with their sizes
+// in at most maxHeight lines, the last one "..." when more don't fit
+func renderArchivePreview(preview loadedPreview, colWidth, maxHeight int) string {
+	if len(preview.members) == 0 && preview.text == "" {
+		return "Empty archive"
+	}
+
+	nameWidth := max(colWidth-4-sizeColumnWidth-1, 0)
+	var lines []string
+	for _, member := range preview.members[:min(len(preview.members), max(maxHeight, 0))] {
+		line := truncateRight(member.name, nameWidth, "…")
+		size := ""
+		if member.dir {
+			line = dirStyle.Render(line + "/")
+		} else {
+			size = HumanSize(member.size)
+		}
+		line += strings.Repeat(" ", max(nameWidth+1-lipgloss.Width(line), 0))
+		lines = append(lines, line+" "+countStyle.Render(fmt.Sprintf("%*s", sizeColumnWidth, size)))
+	}
+	if preview.text != "" {
+		lines = append(lines, preview.text)
+	} else if preview.more || len(preview.members) > maxHeight {
+		lines = append(lines, "...")
+	}
+	if len(lines) > maxHeight && maxHeight > 0 {
+		lines = append(lines[:maxHeight-1], "...")
+	}
+	return strings.Join(lines, "\n")
+}
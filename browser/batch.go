@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// Error of the items a batch skipped after an earlier one failed
+var errBatchStopped = errors.New("skipped after an earlier failure")
+
+// runBatch calls op for items 0 to n-1 on up to workers goroutines and
+// returns each item's error by index. Items are started in order, so with
+// one worker they also run in order. With stopOnError, items not started
+// once one has failed are skipped with errBatchStopped.
+func runBatch(n, workers int, stopOnError bool, op func(i int) error) []error {
+	errs := make([]error, n)
+	var stopped atomic.Bool
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range max(min(workers, n), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if stopped.Load() {
+					errs[i] = errBatchStopped
+					continue
+				}
+				if errs[i] = op(i); errs[i] != nil && stopOnError {
+					stopped.Store(true)
+				}
+			}
+		}()
+	}
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return errs
+}
+
+// batchWorkersFor returns how many items of a batch touching paths may run
+// at once. Paths inside one another depend on each other's order, so such
+// batches run one item at a time.
+func (m *FileManager) batchWorkersFor(paths []string) int {
+	if nestedPaths(paths) {
+		return 1
+	}
+	return max(m.batchWorkers, 1)
+}
+
+// nestedPaths reports whether any of paths is, or is inside, another
+func nestedPaths(paths []string) bool {
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if seen[path] {
+			return true
+		}
+		seen[path] = true
+	}
+	for _, path := range paths {
+		for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if seen[dir] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// batchError sums up the failures of a batch, or returns nil when every
+// item worked. A single failure is returned as is.
+func batchError(errs []error) error {
+	var first error
+	failed, skipped := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, errBatchStopped):
+			skipped++
+		default:
+			failed++
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	if failed == 1 && skipped == 0 {
+		return first
+	}
+	summary := fmt.Sprintf("%d of %d failed", failed, len(errs))
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	return fmt.Errorf("%s: %w", summary, first)
+}
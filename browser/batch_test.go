@@ -0,0 +1,117 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunBatch(t *testing.T) {
+	// No more than the workers asked for run at once
+	var running, most atomic.Int32
+	errs := runBatch(20, 4, false, func(i int) error {
+		now := running.Add(1)
+		for {
+			seen := most.Load()
+			if now <= seen || most.CompareAndSwap(seen, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		if i%5 == 0 {
+			return fmt.Errorf("item %d failed", i)
+		}
+		return nil
+	})
+	if most.Load() > 4 {
+		t.Errorf("expected at most 4 items at once, saw %d", most.Load())
+	}
+	if err := batchError(errs); err == nil || !strings.HasPrefix(err.Error(), "4 of 20 failed") {
+		t.Errorf("expected every failure counted and the others run, got %v", err)
+	}
+
+	// Stopping at the first failure skips the items after it
+	var ran []int
+	errs = runBatch(5, 1, true, func(i int) error {
+		ran = append(ran, i)
+		if i == 1 {
+			return errFailed
+		}
+		return nil
+	})
+	if len(ran) != 2 || !errors.Is(errs[4], errBatchStopped) {
+		t.Fatalf("expected items 0 and 1 run, then the rest skipped, ran %v", ran)
+	}
+	if err := batchError(errs); err == nil || !strings.HasPrefix(err.Error(), "1 of 5 failed, 3 skipped") {
+		t.Errorf("expected the skipped items reported, got %v", err)
+	}
+}
+
+func TestNestedPaths(t *testing.T) {
+	for _, tc := range []struct {
+		paths  []string
+		nested bool
+	}{
+		{[]string{"/a/b", "/a/c", "/a-b/c"}, false},
+		{[]string{"/a/b", "/a/b/c/d"}, true},
+		{[]string{"/a/b", "/c", "/a/b"}, true},
+	} {
+		if got := nestedPaths(tc.paths); got != tc.nested {
+			t.Errorf("nestedPaths(%v) = %v, want %v", tc.paths, got, tc.nested)
+		}
+	}
+}
+
+func TestBulkOperationsInParallel(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	for i := range 12 {
+		if err := os.WriteFile(filepath.Join(src, fmt.Sprintf("f%02d.txt", i)), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: src, trashDir: t.TempDir(), batchWorkers: 4}
+	m.Entries, _ = m.readDirectory(src)
+	for i := range m.Entries {
+		m.Entries[i].Selected = true
+	}
+
+	// A copy of many entries is one undo step, recorded in selection order
+	m.copyFile()
+	m.CurrentPath = dst
+	paste(m)
+	if copied, _ := os.ReadDir(dst); len(copied) != 12 {
+		t.Fatalf("expected 12 entries copied, got %d", len(copied))
+	}
+	if len(m.undoStack) != 1 || len(m.undoStack[0].Batch) != 12 {
+		t.Fatalf("expected one batch of 12 copies, got %+v", m.undoStack)
+	}
+	for i, action := range m.undoStack[0].Batch {
+		if want := fmt.Sprintf("f%02d.txt", i); action.Entry.Name != want {
+			t.Fatalf("expected %s at %d in the undo batch, got %s", want, i, action.Entry.Name)
+		}
+	}
+
+	// Entries that fail don't stop the others
+	m.Entries, _ = m.readDirectory(dst)
+	for i := range m.Entries {
+		m.Entries[i].Selected = true
+	}
+	os.Remove(filepath.Join(dst, "f03.txt"))
+	os.Remove(filepath.Join(dst, "f07.txt"))
+	m.deleteFile()
+	if left, _ := os.ReadDir(dst); len(left) != 0 {
+		t.Fatalf("expected the other entries trashed, %d left", len(left))
+	}
+	if !m.statusIsError || !strings.HasPrefix(m.statusMsg, "2 of 12 failed") {
+		t.Errorf("expected the failures reported, got %q", m.statusMsg)
+	}
+	if len(m.undoStack) != 2 || len(m.undoStack[1].Batch) != 10 {
+		t.Errorf("expected the 10 deletes undone in one step, got %+v", m.undoStack[1])
+	}
+}
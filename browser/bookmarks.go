@@ -0,0 +1,71 @@
+package browser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Bookmarks returns the directory bookmarks, name to path
+func (m *FileManager) Bookmarks() map[string]string {
+	return m.bookmarks
+}
+
+// setBookmark bookmarks the current directory as letter and saves it
+func (m *FileManager) setBookmark(letter string) {
+	if m.archive != nil {
+		m.setStatus("Directories inside archives can't be bookmarked")
+		return
+	}
+	if m.addBookmark != nil {
+		if err := m.addBookmark(letter, m.CurrentPath); err != nil {
+			m.setError(fmt.Errorf("saving bookmark %s: %w", letter, err))
+			return
+		}
+	}
+	if m.bookmarks == nil {
+		m.bookmarks = make(map[string]string)
+	}
+	m.bookmarks[letter] = m.CurrentPath
+	m.setStatus("Bookmarked %s as %s", m.CurrentPath, letter)
+}
+
+// jumpToBookmark goes to the directory bookmarked as letter
+func (m *FileManager) jumpToBookmark(letter string) {
+	dir, ok := m.bookmarks[letter]
+	if !ok {
+		m.setError(fmt.Errorf("bookmark %s is not set", letter))
+		return
+	}
+	if info, err := m.filesystem().Stat(dir); err != nil || !info.IsDir() {
+		m.setError(fmt.Errorf("bookmark %s: %s no longer exists", letter, dir))
+		return
+	}
+	m.openDir(dir, "")
+}
+
+// renderBookmarks renders the bookmarks overlay shown while picking one to
+// jump to. Bookmarks named with more than a letter, from tfm bookmark add,
+// are listed too but can't be jumped to from here.
+func (m *FileManager) renderBookmarks() string {
+	names := make([]string, 0, len(m.bookmarks))
+	for name := range m.bookmarks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var content strings.Builder
+	content.WriteString("Bookmarks (letter jumps, ' for recent directories, esc cancels)")
+	if len(names) == 0 {
+		content.WriteString("\n  None yet: m and a letter bookmarks this directory")
+	}
+	width := 1
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+	for _, name := range names {
+		path := truncateLeft(m.bookmarks[name], max(m.Width-width-8, 0))
+		content.WriteString(fmt.Sprintf("\n  %-*s  %s", width, name, path))
+	}
+	return whichKeyStyle.Width(m.Width).Render(content.String())
+}
@@ -0,0 +1,3747 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fsnotify/fsnotify"
+)
+
+// FileEntry represents a file or directory
+type FileEntry struct {
+	Name      string
+	Path      string
+	IsDir     bool
+	IsSymlink bool   // Set from the directory listing, without a stat
+	Target    string // Absolute path a symlink points to, "" for other entries
+	Selected  bool   // Marked with space for a batch cut, copy or delete
+
+	dirEntry   fs.DirEntry // Listing entry Info is read from
+	fsys       FS          // Filesystem the entry is on, nil for the OS
+	info       fs.FileInfo // Cached result of Info
+	brokenLink bool        // Whether nothing exists at Target
+}
+
+// Info returns the entry's file info, without following symlinks. It is read
+// on first use (from the directory listing where possible) and then cached.
+func (e *FileEntry) Info() (fs.FileInfo, error) {
+	if e.info != nil {
+		return e.info, nil
+	}
+	var err error
+	if e.dirEntry != nil {
+		e.info, err = e.dirEntry.Info()
+	} else {
+		fsys := e.fsys
+		if fsys == nil {
+			fsys = OS
+		}
+		e.info, err = lstat(fsys, e.Path)
+	}
+	return e.info, err
+}
+
+// UndoAction represents an action that can be undone
+type UndoAction struct {
+	Type    string       // "delete", "move", "rename", "create", "mkdir", "extract" or "batch"
+	OldPath string       // Original path
+	NewPath string       // New path (for moves/renames)
+	Entry   FileEntry    // File information
+	OldName string       // Original name (for renames)
+	Batch   []UndoAction // Actions undone together, last first (for batches)
+	Summary string       // What a batch did, e.g. "deleting 3 items"
+	Archive string       // Archive OldPath is a member of (for extracted members)
+}
+
+// FileManager represents the application state
+type FileManager struct {
+	CurrentPath string
+	Entries     []FileEntry
+	Cursor      int
+	Width       int
+	Height      int
+	inline      bool              // Render in the normal screen instead of the alternate one
+	minimal     bool              // Show only the current column, without parent and preview
+	keymap      map[string]string // Extra keys mapped to default bindings in normal mode
+	fs          FS                // Filesystem operated on (nil uses OS)
+
+	previewCommands   map[string]string // Preview command per file extension
+	previewTheme      string            // Chroma style for source previews ("" leaves them plain)
+	openers           map[string]Opener // Open command per file extension
+	hooks             map[string]string // Commands run around operations by hook point
+	pendingHooks      []pendingHook     // Post hooks to start after the current message
+	autoPreview       bool              // Render previews (off skips reading files)
+	diffPreview       bool              // Preview files as their diff against git HEAD
+	largeDirThreshold int               // Ask before entering directories with more entries (0 never asks)
+	loadingDir        string            // Directory being read in the background
+	loadingSelect     string            // Entry to select once it's read
+	watcher           *fsnotify.Watcher // Watches the current directory, when on
+	watchedPath       string            // Directory the watcher is on
+	archive           *archiveFS        // Archive being browsed, nil on the real filesystem
+	runExecutables    string            // Enter on executables: executablesOpen or executablesAsk
+	enterFiles        string            // Enter on files: enterFilesOpen or enterFilesPreview
+	editor            string            // Command directories are opened with by e
+	renameOverwrite   string            // Rename onto an existing name: renameAsk, renameRefuse or renameSuffix
+	showDirCounts     bool              // Show item counts next to directories
+	showSizes         bool              // Show sizes in a column at the right of the current column
+	icons             map[string]string // Nerd Font glyphs by extension (nil when icons are off)
+	dirCounts         map[string]int    // Cached item counts by directory path
+	countingDirs      bool              // Whether a background count is running
+	dirSizes          map[string]int64  // Total sizes computed with ctrl+s, by directory path
+	sizingDirs        map[string]bool   // Directories whose total size is being computed
+	activeOps         int               // Background operations running, shown with a spinner
+	activityTicking   bool              // Whether the spinner is turning
+	activityFrame     int               // Spinner frame shown
+
+	// State for shortcuts
+	clipboard      []FileEntry          // Clipboard entries
+	clipboardOp    string               // Clipboard operation: "copy" or "cut"
+	cutFrom        string               // Directory the pending cut was made in
+	cutNavigated   bool                 // Whether we left cutFrom since the cut
+	pastedName     string               // Name of the last entry pasted, for the cursor to land on
+	checkingSpace  bool                 // Whether a paste waits for the space it needs to be checked
+	keepCutVisible bool                 // List cut entries dimmed instead of hiding them until pasted
+	searchMode     bool                 // Search mode active
+	searchQuery    string               // Current search text
+	searchHits     []string             // Names matching the last search, best first
+	searchIndex    int                  // Position in searchHits for n/N
+	searchCursor   int                  // Cursor before searching, restored by esc
+	substring      bool                 // Plain substring search instead of fuzzy ranking
+	renameMode     bool                 // Rename mode active
+	renameText     string               // Current rename text
+	renameExists   bool                 // Whether renameText names another existing entry
+	renameCursor   int                  // Caret position in renameText, in runes
+	renameStem     int                  // Runes of renameText selected from its start, 0 for none
+	zoxideMode     bool                 // Zoxide mode active
+	zoxideQuery    string               // Current zoxide query
+	relBaseMode    bool                 // Prompting for the base of a relative path copy
+	relBaseText    string               // Current base directory text
+	inputTimeout   time.Duration        // Typing modes left idle this long are cancelled (0 never)
+	inputSeq       int                  // Bumped by each key, so only the latest idle timer fires
+	workDir        string               // Working directory tfm was started from
+	pickKind       string               // What ctrl+y picks: PickAny, PickFile or PickDir
+	relativeTimes  bool                 // Show modification times like "3 minutes ago"
+	batchWorkers   int                  // Items of a bulk operation processed at once
+	batchStop      bool                 // Stop a bulk operation at its first failure
+	picked         string               // Path picked with ctrl+y before quitting
+	parentMode     bool                 // Parent column has focus
+	parentCursor   int                  // Selected entry in the parent column
+	lastCommand    string               // Last command (for double commands like dd)
+	commandTime    time.Time            // Time of last command
+	typeAhead      string               // Name typed to jump to an entry
+	typeAheadTime  time.Time            // Time of the last key typed ahead
+	showWhichKey   bool                 // Show shortcuts screen
+	pathDisplay    int                  // How entries are named in the current column
+	dirsOnly       bool                 // Hide files in the current column
+	listOptions    ListOptions          // Which entries directory listings include
+	confirm        *confirmation        // Pending confirmation prompt
+	prompt         *textPrompt          // Pending text prompt
+	pasteQueue     []queuedOp           // Cuts and copies waiting to be pasted together
+	marginScroll   bool                 // Scroll with a scrollOff margin instead of centering the cursor
+	scrollOff      int                  // Lines kept above/below the cursor
+	scrollTop      int                  // First entry shown when scrolling with a margin
+	showQueue      bool                 // Show the paste queue overlay
+	templateDir    string               // Directory T creates new files from
+	templates      []string             // Templates listed in the picker, nil when closed
+	templateCursor int                  // Selected template in the picker
+	showSortMenu   bool                 // Show the sort menu overlay
+	recentDirs     []string             // Recently visited directories, most recent first
+	recentLimit    int                  // How many recent directories are kept
+	recentPicks    []string             // Directories listed in the recent picker, nil when closed
+	recentCursor   int                  // Selected directory in the recent picker
+	finder         *fileFinder          // Internal file finder, nil when closed
+	previewLines   *previewLines        // Line cursor in the preview, nil when not yanking lines
+	previewScroll  int                  // First line of the file preview shown, moved with J/K
+	lineCounts     map[lineCountKey]int // Lines in the previews scrolled, counted once
+	quickLook      *quickLook           // File previewed across the whole screen, nil when closed
+	reorderMode    bool                 // J/K move the selected entry, saving the manual order
+	trashItems     []TrashItem          // Entries listed in the trash view, nil when closed
+	trashCursor    int                  // Selected entry in the trash view
+	count          int                  // Count typed before a motion, e.g. the 3 in 3h
+
+	// Entries matching the search typed so far, listed in place of Entries
+	filteredEntries []filteredEntry
+
+	// Git status of the listing, read in the background when git_status is on
+	gitStatus    bool                // Color entries by their git status
+	gitStates    map[string]gitState // Status of listed entries by path
+	gitStatesDir string              // Directory gitStates were read for, "" once stale
+	gitSeq       int                 // Bumped on reloads, so reads started before are dropped
+	gitLoading   bool                // Whether a read is running
+
+	// Previews worked out in the background, as View can't wait for them
+	previewCache   map[previewKey]loadedPreview // Previews by what they were made from
+	previewLoading map[previewKey]bool          // Previews being worked out
+	previewWanted  previewKey                   // Background preview the selected entry needs
+
+	// Listings of the parent directory and the previewed one, read in the background
+	listings        map[string]dirListing // Cached listings by directory path
+	listingsLoading map[string]bool       // Directories being read
+	listingSeq      int                   // Bumped on reloads, so cached listings are read again
+
+	// Applications offered by the open with menu
+	openWith     map[string][]Opener // Applications by file extension
+	openWithMenu *openWithMenu       // Open with menu, nil when closed
+
+	// File marks and directory bookmarks
+	marks       map[string]string             // Marked entries by letter
+	markMode    string                        // markSet, markJump, bookmarkSet or bookmarkJump while waiting for a letter
+	bookmarks   map[string]string             // Bookmarked directories by name
+	addBookmark func(name, path string) error // Saves a bookmark as it is set (nil keeps it in memory)
+
+	// Status bar feedback
+	statusMsg     string // Result of the last operation
+	statusIsError bool   // Errors stay until the next key instead of fading
+	statusSeq     int    // Bumped on every message so stale clears are ignored
+
+	// Undo system
+	undoStack []UndoAction // Stack of actions to undo
+	redoStack []UndoAction // Undone actions, to redo
+	relocated []string     // Entries the last undo put in the current directory, their own being gone
+	trashDir  string       // Directory deleted entries are moved to
+
+	// Trash kept across sessions instead of removed on exit
+	keepTrash      bool
+	trashRetention time.Duration // How long kept entries stay (0 is forever)
+	emptyingTrash  bool          // Whether the trash is being emptied in the background
+
+	deleting   *deleteJob  // Permanent delete running in the background
+	extracting *extractJob // Archive extraction running in the background
+}
+
+// Ways to name entries in the current column, cycled with P
+const (
+	pathDisplayName     = iota // Base name only
+	pathDisplayRelative        // Path relative to the current directory
+	pathDisplayAbsolute        // Full path
+	pathDisplayModes
+)
+
+// confirmation is a prompt answered with a single key
+type confirmation struct {
+	prompt  string                    // Text shown in the command line
+	actions map[string]func() tea.Cmd // Action for each accepted key
+}
+
+// textPrompt asks for a line of text in the command line
+type textPrompt struct {
+	label  string               // Shown before the text
+	text   string               // Text typed so far
+	submit func(string) tea.Cmd // Called with the text on enter
+}
+
+// Structure to define a shortcut
+type shortcut struct {
+	key         string
+	description string
+}
+
+// Map of shortcut contexts
+var shortcuts = map[string][]shortcut{
+	"normal": {
+		{"space", "select/unselect"},
+		{"esc", "clear selection"},
+		{"dd", "cut file"},
+		{"dD or DD", "delete file"},
+		{"dX", "delete permanently"},
+		{"yy", "copy file"},
+		{"yd", "copy directory path"},
+		{"Y", "copy selected path"},
+		{"yn", "copy selected name"},
+		{"yr", "copy relative path"},
+		{"yR", "copy path relative to..."},
+		{"pp", "paste file"},
+		{"W", "move into new directory"},
+		{"T", "new file from template"},
+		{"U", "extract archive or member"},
+		{"!", "run executable"},
+		{"so", "sort menu"},
+		{"o / O", "next sort mode/reverse sort"},
+		{"c / x", "queue copy/cut"},
+		{"ctrl+v", "paste queue"},
+		{"Q", "show paste queue"},
+		{"C", "clear paste queue"},
+		{"u", "undo"},
+		{"ctrl+r", "redo"},
+		{"E", "empty trash"},
+		{"gt", "show trash"},
+		{"a", "rename file"},
+		{"A", "bulk rename in editor"},
+		{"/", "search"},
+		{"n / N", "next/previous match"},
+		{"z", "navigate with zoxide"},
+		{"f", "find file below"},
+		{"m + letter", "bookmark directory"},
+		{"' + letter", "go to bookmark"},
+		{"''", "recent directories"},
+		{"M + letter", "mark file"},
+		{"` + letter", "go to marked file"},
+		{"gg", "go to first"},
+		{"G", "go to last"},
+		{"S", "open terminal"},
+		{"e", "edit directory"},
+		{"L", "open with application"},
+		{"P", "show names/paths"},
+		{"v", "copy lines from preview"},
+		{"V", "quick look"},
+		{"gr", "reorder entries"},
+		{"ctrl+o", "open externally"},
+		{"ctrl+y", "pick path and quit"},
+		{"%", "new file"},
+		{"+", "new directory"},
+		{"ctrl+p", "toggle previews"},
+		{"J/K", "scroll preview"},
+		{"ctrl+g", "toggle git diff preview"},
+		{"F", "show directories only"},
+		{"ctrl+s", "compute directory size"},
+		{"i", "show sizes"},
+		{".", "show hidden files"},
+		{"H", "focus parent column"},
+		{"R", "refresh"},
+		{"w", "watch for changes"},
+		{"?", "show/hide shortcuts"},
+		{"q", "quit"},
+		{"l, enter", "open file"},
+	},
+	"search": {
+		{"enter", "confirm search"},
+		{"esc", "cancel search"},
+	},
+	"rename": {
+		{"enter", "confirm rename"},
+		{"← / →", "move the caret"},
+		{"home / end", "go to start/end"},
+		{"esc", "cancel rename"},
+	},
+	"zoxide": {
+		{"enter", "navigate to directory"},
+		{"esc", "cancel navigation"},
+	},
+	"relbase": {
+		{"enter", "copy relative path"},
+		{"esc", "cancel copy"},
+	},
+	"prompt": {
+		{"enter", "confirm"},
+		{"esc", "cancel"},
+	},
+	"parent": {
+		{"j / k", "move in parent"},
+		{"l, enter", "open directory"},
+		{"esc, H", "back to listing"},
+	},
+	"quicklook": {
+		{"j / k", "scroll"},
+		{"ctrl+o", "open externally"},
+		{"esc", "back to listing"},
+	},
+	"reorder": {
+		{"j / k", "move cursor"},
+		{"J / K", "move entry down/up"},
+		{"esc", "done"},
+	},
+	"lines": {
+		{"j / k", "move line cursor"},
+		{"v", "select a range"},
+		{"y, enter", "copy to clipboard"},
+		{"esc", "back to listing"},
+	},
+	"sort": {
+		{"n s m e t", "sort by name, size, mtime, extension, type"},
+		{"esc", "cancel"},
+	},
+	"openwith": {
+		{"j / k", "move in applications"},
+		{"l, enter, 1-9", "open with application"},
+		{"esc", "cancel"},
+	},
+	"templates": {
+		{"j / k", "move in templates"},
+		{"l, enter", "name the new file"},
+		{"esc", "cancel"},
+	},
+	"trash": {
+		{"j / k", "move in trash"},
+		{"l, enter", "restore to original location"},
+		{"esc", "cancel"},
+	},
+	"recent": {
+		{"j / k", "move in recent directories"},
+		{"l, enter", "go to directory"},
+		{"esc", "cancel"},
+	},
+	"finder": {
+		{"type", "narrow the files"},
+		{"↑ / ↓", "move in matches"},
+		{"enter", "go to file"},
+		{"esc", "cancel"},
+	},
+}
+
+const (
+	maxCount       = 9999 // Largest count a motion can be given
+	contentLimit   = 10   // Limit of items in directory
+	emptyDirMsg    = "Empty directory"
+	noSelectionMsg = "No item selected"
+	loadingMsg     = "Loading…"
+	unreadableMsg  = "Can't read this directory"
+	statusTimeout  = 2 * time.Second // How long confirmations stay visible
+
+	// Below this size the layout is replaced by a "too small" message
+	minWidth  = 40
+	minHeight = 8
+)
+
+// Markdown renderer
+var markdownRenderer *glamour.TermRenderer
+
+func init() {
+	// Initialize renderer with default configuration
+	markdownRenderer, _ = glamour.NewTermRenderer(
+		glamour.WithAutoStyle(),
+		glamour.WithWordWrap(-1), // Disable automatic wrap
+	)
+}
+
+// Style for columns
+var (
+	columnStyle = lipgloss.NewStyle().
+			PaddingLeft(2).
+			PaddingRight(2)
+
+	selectedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("205")).
+			Bold(true)
+
+	markedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214"))
+
+	dirStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("39"))
+
+	pathStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("244")).
+			PaddingLeft(2).
+			PaddingBottom(1)
+
+	statusStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("234")).
+			Background(lipgloss.Color("252")).
+			PaddingLeft(2).
+			PaddingTop(0).
+			PaddingBottom(0)
+
+	searchBarStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("234")).
+			Background(lipgloss.Color("255")).
+			PaddingLeft(2).
+			PaddingTop(0).
+			PaddingBottom(0)
+
+	whichKeyStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("234")).
+			Background(lipgloss.Color("252")).
+			PaddingLeft(2).
+			PaddingRight(2)
+
+	emptyStateStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("244")).
+			Italic(true)
+
+	countStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("244"))
+
+	errorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("255")).
+			Background(lipgloss.Color("160")).
+			PaddingLeft(2).
+			PaddingTop(0).
+			PaddingBottom(0)
+
+	diffAddStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("34"))
+
+	diffRemoveStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("160"))
+
+	diffHunkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("37"))
+
+	diffMetaStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("244"))
+
+	warningStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("160")).
+			Bold(true)
+
+	cutStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Strikethrough(true)
+
+	linkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("44"))
+
+	brokenLinkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("160"))
+)
+
+// ListOptions controls which entries ReadDirectory returns
+type ListOptions struct {
+	ShowHidden     bool     // List all dotfiles
+	HiddenPatterns []string // Dotfiles matching these patterns are listed anyway
+	DirSort        string   // Sort mode for directories (empty sorts by name)
+	FileSort       string   // Sort mode for files (empty sorts by name)
+	Reverse        bool     // Reverse the sort within directories and files
+	FS             FS       // Filesystem to read (nil reads the OS)
+}
+
+// Sort modes, applied within the directory and file groups
+const (
+	sortName  = "name"  // Alphabetical
+	sortSize  = "size"  // Largest first
+	sortMtime = "mtime" // Most recently modified first
+	sortExt   = "ext"   // By extension, then name
+	sortType  = "type"  // Symlinks, then executables, then other entries
+)
+
+// showEntry reports whether a directory entry should be listed
+func (o ListOptions) showEntry(name string) bool {
+	if o.ShowHidden || !strings.HasPrefix(name, ".") {
+		return true
+	}
+	// Hidden files are skipped unless allowlisted
+	for _, pattern := range o.HiddenPatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadDirectory reads files from a directory. It fails when the directory
+// can't be listed, e.g. without permission to read it.
+func ReadDirectory(path string, opts ListOptions) ([]FileEntry, error) {
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = OS
+	}
+	files, err := fsys.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]FileEntry, 0, len(files))
+	hasOrder := false
+
+	// The type comes with the listing; sizes and times cost a stat per
+	// entry, so only fetch them up front to sort by. Symlinks are followed
+	// to tell links to directories apart.
+	for _, file := range files {
+		if file.Name() == orderFileName {
+			hasOrder = true
+		}
+		if opts.showEntry(file.Name()) {
+			entry := FileEntry{
+				Name:      file.Name(),
+				Path:      filepath.Join(path, file.Name()),
+				IsDir:     file.IsDir(),
+				IsSymlink: file.Type()&fs.ModeSymlink != 0,
+				dirEntry:  file,
+				fsys:      fsys,
+			}
+			// A link to a directory is listed and entered like one
+			if entry.IsSymlink {
+				if link, err := readSymlink(fsys, entry.Path); err == nil {
+					entry.Target = link.resolved
+					entry.brokenLink = link.broken
+					entry.IsDir = link.dir
+				}
+			}
+
+			mode := opts.groupSort(entry.IsDir)
+			if mode == sortSize || mode == sortMtime || mode == sortType {
+				entry.Info()
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	// Directories first, each group in its own order
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		a, b := entries[i], entries[j]
+		if opts.Reverse {
+			a, b = b, a
+		}
+		return lessBy(opts.groupSort(a.IsDir), a, b)
+	})
+	// A manual order overrides the sort for the entries it names
+	if hasOrder {
+		applyOrder(entries, readOrder(fsys, path))
+	}
+
+	return entries, nil
+}
+
+// groupSort returns the sort mode for directories or files
+func (o ListOptions) groupSort(isDir bool) string {
+	if isDir {
+		return o.DirSort
+	}
+	return o.FileSort
+}
+
+// lessBy orders two entries by a sort mode, falling back to the name.
+// Unknown modes sort by name. Sizes and times come from the cached info.
+func lessBy(mode string, a, b FileEntry) bool {
+	switch mode {
+	case sortSize:
+		if sa, sb := infoSize(a.info), infoSize(b.info); sa != sb {
+			return sa > sb
+		}
+	case sortMtime:
+		if ta, tb := infoModTime(a.info), infoModTime(b.info); !ta.Equal(tb) {
+			return ta.After(tb)
+		}
+	case sortExt:
+		ea, eb := strings.ToLower(filepath.Ext(a.Name)), strings.ToLower(filepath.Ext(b.Name))
+		if ea != eb {
+			return ea < eb
+		}
+	case sortType:
+		if ka, kb := typeRank(a), typeRank(b); ka != kb {
+			return ka < kb
+		}
+	}
+	return a.Name < b.Name
+}
+
+// typeRank orders entries by kind for sortType
+func typeRank(entry FileEntry) int {
+	switch {
+	case entry.IsSymlink:
+		return 0
+	case entry.info != nil && entry.info.Mode().IsRegular() && entry.info.Mode()&0111 != 0:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// infoSize returns the size from info, or 0 when it couldn't be read
+func infoSize(info fs.FileInfo) int64 {
+	if info == nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// infoModTime returns the modification time from info, or the zero time
+func infoModTime(info fs.FileInfo) time.Time {
+	if info == nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// readDirectory reads a directory for the current column, applying the display filters
+func (m *FileManager) readDirectory(path string) ([]FileEntry, error) {
+	return listDirectory(path, m.fsListOptions(), m.dirsOnly)
+}
+
+// fsListOptions returns the listing options, reading through the file
+// manager's filesystem
+func (m *FileManager) fsListOptions() ListOptions {
+	opts := m.listOptions
+	opts.FS = m.filesystem()
+	return opts
+}
+
+// listDirectory reads a directory, keeping only subdirectories if dirsOnly is set
+func listDirectory(path string, opts ListOptions, dirsOnly bool) ([]FileEntry, error) {
+	entries, err := ReadDirectory(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if dirsOnly {
+		dirs := entries[:0]
+		for _, entry := range entries {
+			if entry.IsDir {
+				dirs = append(dirs, entry)
+			}
+		}
+		entries = dirs
+	}
+	return entries, nil
+}
+
+// reloadEntries re-reads the current directory, showing why if it can't
+// be listed anymore
+func (m *FileManager) reloadEntries() {
+	entries, err := m.readDirectory(m.CurrentPath)
+	m.Entries = entries
+	m.forgetGitStatus()
+	m.listingSeq++
+	if err != nil {
+		m.setError(err)
+	}
+}
+
+// reloadKeepingSelection re-reads the current directory and keeps the cursor
+// on the same entry when it is still listed
+func (m *FileManager) reloadKeepingSelection() {
+	var selected string
+	if m.Cursor < len(m.Entries) {
+		selected = m.Entries[m.Cursor].Name
+	}
+	marked := make(map[string]bool)
+	for _, entry := range m.Entries {
+		if entry.Selected {
+			marked[entry.Name] = true
+		}
+	}
+	m.reloadEntries()
+	for i := range m.Entries {
+		m.Entries[i].Selected = marked[m.Entries[i].Name]
+	}
+	if !m.selectByName(selected) {
+		m.Cursor = max(min(m.Cursor, len(m.Entries)-1), 0)
+	}
+}
+
+// refresh re-reads the current directory. If it was removed from under us,
+// it moves up to the nearest ancestor that still exists.
+func (m *FileManager) refresh() {
+	if info, err := m.filesystem().Stat(m.CurrentPath); err == nil && info.IsDir() {
+		m.reloadKeepingSelection()
+		return
+	}
+
+	missing := m.CurrentPath
+	dir := m.CurrentPath
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break // Even the root is gone; nothing better to do
+		}
+		dir = parent
+		if info, err := m.filesystem().Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+	}
+
+	m.CurrentPath = dir
+	m.reloadEntries()
+	m.Cursor = 0
+	if !m.statusIsError {
+		m.setStatus("%s no longer exists, moved to %s", missing, dir)
+	}
+}
+
+func (m *FileManager) Init() tea.Cmd {
+	var watch tea.Cmd
+	if m.watcher != nil {
+		watch = waitForChange(m.watcher)
+	}
+	if m.inline {
+		return watch
+	}
+	return tea.Batch(tea.EnterAltScreen, watch)
+}
+
+// goUp loads the directory levels up, to select the child we came from
+func (m *FileManager) goUp(levels int) tea.Cmd {
+	path, child := m.CurrentPath, ""
+	for ; levels > 0; levels-- {
+		parent := filepath.Dir(path)
+		if parent == path {
+			break // Already at the root
+		}
+		path, child = parent, filepath.Base(path)
+	}
+	if path == m.CurrentPath {
+		return nil
+	}
+	return m.loadDirectory(path, child, 0)
+}
+
+// tryEnterDirectory tries to enter the selected directory or opens the file
+func (m *FileManager) tryEnterDirectory() tea.Cmd {
+	if m.Cursor < len(m.Entries) {
+		entry := m.Entries[m.Cursor]
+		if entry.IsDir {
+			return m.loadDirectory(entry.Path, "", m.largeDirThreshold)
+		} else if m.enterFiles == enterFilesPreview && !isArchive(entry.Name) {
+			m.openQuickLook()
+		} else if m.archive != nil {
+			// Files inside an archive only exist once extracted
+			m.setStatus("Extract %s with U to open it", entry.Name)
+		} else if isArchive(entry.Name) {
+			return m.enterArchive(entry)
+		} else if m.runExecutables == executablesAsk && m.isExecutable(entry) {
+			m.confirmRun(entry)
+		} else {
+			// If it's a file, open it with its opener or the default program
+			return m.openFile(entry)
+		}
+	}
+	return nil
+}
+
+func (m *FileManager) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	seq := m.statusSeq
+	path := m.CurrentPath
+	selected := m.selectedPath()
+	model, cmd := m.update(msg)
+
+	// A preview scrolled down starts at the top for the next entry
+	if m.selectedPath() != selected {
+		m.previewScroll = 0
+	}
+	// The list scrolls only as far as it takes to keep the cursor's margin
+	if m.marginScroll {
+		m.scrollTop = m.listWindow(m.listHeight())
+	}
+
+	// A new confirmation message fades after a while
+	if m.statusSeq != seq && !m.statusIsError {
+		cmd = tea.Batch(cmd, clearStatusAfter(m.statusSeq))
+	}
+
+	// The directory we just left may have changed while we were in it
+	if m.CurrentPath != path {
+		delete(m.dirCounts, path)
+		delete(m.dirSizes, path)
+		m.cutNavigated = true
+		m.leaveArchiveIfOutside()
+		m.watchCurrentDir()
+		m.visitRecent(m.CurrentPath)
+	}
+	if countCmd := m.startDirCounts(); countCmd != nil {
+		cmd = tea.Batch(cmd, countCmd)
+	}
+	if gitCmd := m.startGitStatus(); gitCmd != nil {
+		cmd = tea.Batch(cmd, gitCmd)
+	}
+	if previewCmd := m.startPreview(); previewCmd != nil {
+		cmd = tea.Batch(cmd, previewCmd)
+	}
+	if listingCmd := m.startListings(); listingCmd != nil {
+		cmd = tea.Batch(cmd, listingCmd)
+	}
+	if hookCmd := m.startHooks(); hookCmd != nil {
+		cmd = tea.Batch(cmd, hookCmd)
+	}
+	if activityCmd := m.startActivity(); activityCmd != nil {
+		cmd = tea.Batch(cmd, activityCmd)
+	}
+	// Each key typed restarts the wait before an idle input is cancelled
+	if _, ok := msg.(tea.KeyMsg); ok {
+		if idleCmd := m.scheduleInputIdle(); idleCmd != nil {
+			cmd = tea.Batch(cmd, idleCmd)
+		}
+	}
+	return model, cmd
+}
+
+func (m *FileManager) update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case reloadDirectoryMsg:
+		// Reload directory after returning from terminal
+		m.refresh()
+		if msg.err != nil {
+			m.setError(msg.err)
+		}
+		return m, nil
+	case dirCountsMsg:
+		return m, m.handleDirCounts(msg)
+	case dirSizeMsg:
+		m.handleDirSize(msg)
+		return m, nil
+	case previewLoadedMsg:
+		m.handlePreviewLoaded(msg)
+		return m, nil
+	case gitStatusMsg:
+		m.handleGitStatus(msg)
+		return m, nil
+	case remoteCdMsg:
+		m.handleRemoteCd(msg)
+		return m, nil
+	case remoteSelectMsg:
+		m.handleRemoteSelect(msg)
+		return m, nil
+	case dirLoadedMsg:
+		m.handleDirLoaded(msg)
+		return m, nil
+	case archiveOpenedMsg:
+		m.handleArchiveOpened(msg)
+		return m, nil
+	case finderFilesMsg:
+		return m, m.handleFinderFiles(msg)
+	case fzfDoneMsg:
+		m.handleFzfDone(msg)
+		return m, nil
+	case bulkRenameMsg:
+		m.handleBulkRename(msg)
+		return m, nil
+	case dirChangedMsg:
+		return m, m.handleDirChanged(msg)
+	case hookDoneMsg:
+		m.handleHookDone(msg)
+		return m, nil
+	case activityTickMsg:
+		return m, m.handleActivityTick()
+	case inputIdleMsg:
+		m.handleInputIdle(msg)
+		return m, nil
+	case renameCheckMsg:
+		m.handleRenameCheck(msg)
+		return m, nil
+	case deleteCountedMsg:
+		m.handleDeleteCounted(msg)
+		return m, nil
+	case spaceCheckedMsg:
+		return m, m.handleSpaceChecked(msg)
+	case trashCountedMsg:
+		m.handleTrashCounted(msg)
+		return m, nil
+	case trashEmptiedMsg:
+		m.handleTrashEmptied(msg)
+		return m, nil
+	case deleteProgressMsg:
+		return m, m.handleDeleteProgress(msg)
+	case deleteDoneMsg:
+		m.handleDeleteDone(msg)
+		return m, nil
+	case extractProgressMsg:
+		return m, m.handleExtractProgress(msg)
+	case extractDoneMsg:
+		m.handleExtractDone(msg)
+		return m, nil
+	case clearStatusMsg:
+		// Only clear the message this timer was started for
+		if msg.seq == m.statusSeq && !m.statusIsError {
+			m.statusMsg = ""
+		}
+		return m, nil
+	case tea.KeyMsg:
+		// Errors stay visible until the next key
+		if m.statusIsError {
+			m.statusMsg = ""
+			m.statusIsError = false
+		}
+
+		// If a confirmation is pending, only its keys (or esc) are accepted
+		if m.confirm != nil {
+			if msg.Type == tea.KeyEsc {
+				m.confirm = nil
+			} else if action, ok := m.confirm.actions[msg.String()]; ok {
+				m.confirm = nil
+				return m, action()
+			}
+			return m, nil
+		}
+
+		// While a directory is read, esc gives up on it and other keys wait
+		if m.loadingDir != "" {
+			switch msg.String() {
+			case "ctrl+c", "q":
+				return m, m.quit()
+			case "esc":
+				m.loadingDir = ""
+			}
+			return m, nil
+		}
+
+		// If a text prompt is open
+		if m.prompt != nil {
+			switch msg.Type {
+			case tea.KeyEnter:
+				prompt := m.prompt
+				m.prompt = nil
+				return m, prompt.submit(prompt.text)
+			case tea.KeyEsc:
+				m.prompt = nil
+			case tea.KeyBackspace:
+				if len(m.prompt.text) > 0 {
+					m.prompt.text = m.prompt.text[:len(m.prompt.text)-1]
+				}
+			default:
+				m.prompt.text += msg.String()
+			}
+			return m, nil
+		}
+
+		// If in search mode
+		if m.searchMode {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.searchMode = false
+				m.filteredEntries = nil
+				m.searchFiles(m.searchQuery)
+				m.searchQuery = ""
+			case tea.KeyEsc:
+				m.cancelSearch()
+			case tea.KeyBackspace:
+				if len(m.searchQuery) > 0 {
+					m.searchQuery = m.searchQuery[:len(m.searchQuery)-1]
+				}
+				m.filterEntries()
+			default:
+				m.searchQuery += msg.String()
+				m.filterEntries()
+			}
+			return m, nil
+		}
+
+		// If in rename mode
+		if m.renameMode {
+			return m, m.handleRenameKey(msg)
+		}
+
+		// If in zoxide mode
+		if m.zoxideMode {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.zoxideMode = false
+				m.navigateWithZoxide(m.zoxideQuery)
+				m.zoxideQuery = ""
+			case tea.KeyEsc:
+				m.zoxideMode = false
+				m.zoxideQuery = ""
+			case tea.KeyBackspace:
+				if len(m.zoxideQuery) > 0 {
+					m.zoxideQuery = m.zoxideQuery[:len(m.zoxideQuery)-1]
+				}
+			default:
+				m.zoxideQuery += msg.String()
+			}
+			return m, nil
+		}
+
+		// If prompting for a relative path base
+		if m.relBaseMode {
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.relBaseMode = false
+				m.copyRelativePath(m.relBaseText)
+				m.relBaseText = ""
+			case tea.KeyEsc:
+				m.relBaseMode = false
+				m.relBaseText = ""
+			case tea.KeyBackspace:
+				if len(m.relBaseText) > 0 {
+					m.relBaseText = m.relBaseText[:len(m.relBaseText)-1]
+				}
+			default:
+				m.relBaseText += msg.String()
+			}
+			return m, nil
+		}
+
+		// If the parent column has focus
+		if m.parentMode {
+			return m, m.handleParentKey(msg)
+		}
+
+		// If waiting for a mark's letter
+		if m.markMode != "" {
+			return m, m.handleMarkKey(msg)
+		}
+
+		// If moving through the preview's lines
+		if m.previewLines != nil {
+			return m, m.handlePreviewLinesKey(msg)
+		}
+
+		if m.quickLook != nil {
+			return m, m.handleQuickLookKey(msg)
+		}
+
+		if m.reorderMode {
+			return m, m.handleReorderKey(msg)
+		}
+
+		// If picking a template
+		if m.templates != nil {
+			return m, m.handleTemplateKey(msg)
+		}
+
+		// If browsing the trash
+		if m.trashItems != nil {
+			return m, m.handleTrashKey(msg)
+		}
+
+		// If picking a recent directory
+		if m.recentPicks != nil {
+			return m, m.handleRecentKey(msg)
+		}
+
+		// If finding a file without fzf
+		if m.finder != nil {
+			return m, m.handleFinderKey(msg)
+		}
+
+		// If picking an application to open with
+		if m.openWithMenu != nil {
+			return m, m.handleOpenWithKey(msg)
+		}
+
+		// If picking a sort mode
+		if m.showSortMenu {
+			return m, m.handleSortMenuKey(msg)
+		}
+
+		// Normal mode, with user key mappings applied
+		key := msg.String()
+		if mapped, ok := m.keymap[key]; ok {
+			key = mapped
+		}
+
+		// While typing ahead, text keys go to the name rather than commands
+		if m.typingAhead() && m.handleTypeAhead(msg) {
+			return m, nil
+		}
+
+		// Digits build a count for the next motion
+		if len(key) == 1 && (key >= "1" && key <= "9" || key == "0" && m.count > 0) {
+			m.count = min(m.count*10+int(key[0]-'0'), maxCount)
+			return m, nil
+		}
+		count := max(m.count, 1)
+		m.count = 0
+
+		switch key {
+		case "ctrl+c", "q":
+			return m, m.quit()
+		case "up", "k":
+			m.Cursor = max(m.Cursor-count, 0)
+		case "down", "j":
+			m.Cursor = max(min(m.Cursor+count, len(m.Entries)-1), 0)
+		case "l", "enter", "right":
+			return m, m.tryEnterDirectory()
+		case "h", "left":
+			// Go back to parent directory, or count levels up
+			return m, m.goUp(count)
+		case "d":
+			// If last command was "y", then it's yd (copy directory path)
+			if m.lastCommand == "y" && time.Since(m.commandTime) < 500*time.Millisecond {
+				m.copyPathToClipboard(m.CurrentPath)
+				m.lastCommand = ""
+			} else if m.handleDoubleCommand("d") {
+				m.cutFile()
+			}
+		case "X":
+			// dX deletes permanently, bypassing the trash
+			if m.lastCommand == "d" && time.Since(m.commandTime) < 500*time.Millisecond {
+				m.lastCommand = ""
+				return m, m.confirmPermanentDelete()
+			}
+		case "esc":
+			// While a delete runs, esc only stops it
+			if m.deleting != nil {
+				m.cancelDelete()
+			} else {
+				m.clearSelection()
+			}
+		case " ":
+			m.toggleSelected()
+		case "v":
+			m.enterPreviewLines()
+		case "V":
+			m.openQuickLook()
+		case "ctrl+y":
+			return m, m.pickPath()
+		case "%":
+			m.promptCreate(false)
+		case "+":
+			m.promptCreate(true)
+		case "ctrl+o":
+			// Open externally, also when enter shows the quick look
+			if m.Cursor < len(m.Entries) && !m.Entries[m.Cursor].IsDir {
+				return m, m.openFile(m.Entries[m.Cursor])
+			}
+		case "M":
+			m.startMark(markSet)
+		case "`":
+			m.startMark(markJump)
+		case "D":
+			// If last command was "d", then it's dD (delete)
+			if m.lastCommand == "d" && time.Since(m.commandTime) < 500*time.Millisecond {
+				m.deleteFile()
+				m.lastCommand = ""
+			} else if m.handleDoubleCommand("D") {
+				// DD also deletes (alternative command)
+				m.deleteFile()
+			}
+		case "y":
+			if m.handleDoubleCommand("y") {
+				m.copyFile()
+			}
+		case "Y":
+			if m.Cursor < len(m.Entries) {
+				m.copyPathToClipboard(m.Entries[m.Cursor].Path)
+			}
+		case "p":
+			if m.handleDoubleCommand("p") {
+				return m, m.pasteFile()
+			}
+		case "c":
+			m.queueSelected("copy")
+		case "x":
+			m.queueSelected("cut")
+		case "ctrl+v":
+			return m, m.pasteQueued()
+		case "Q":
+			m.showQueue = !m.showQueue
+		case "W":
+			m.promptMoveIntoNewDir()
+		case "T":
+			m.openTemplatePicker()
+		case "U":
+			// Inside an archive U takes out a member, outside it extracts a whole archive
+			if m.archive != nil {
+				m.extractSelected()
+			} else {
+				return m, m.startExtract()
+			}
+		case "s":
+			m.handleDoubleCommand("s")
+		case "o":
+			// so opens the sort menu, a lone o sorts by the next mode
+			if m.lastCommand == "s" && time.Since(m.commandTime) < 500*time.Millisecond {
+				m.showSortMenu = true
+				m.lastCommand = ""
+			} else {
+				m.cycleSort()
+			}
+		case "O":
+			m.toggleSortReverse()
+		case "!":
+			if m.Cursor < len(m.Entries) && m.archive == nil {
+				entry := m.Entries[m.Cursor]
+				if !m.isExecutable(entry) {
+					m.setStatus("%s is not executable", entry.Name)
+				} else {
+					m.confirmRun(entry)
+				}
+			}
+		case "C":
+			m.pasteQueue = nil
+			m.setStatus("Cleared the paste queue")
+		case "/":
+			m.startSearch()
+		case "n":
+			// yn copies the selected name, a lone n goes to the next match
+			if m.lastCommand == "y" && time.Since(m.commandTime) < 500*time.Millisecond {
+				if m.Cursor < len(m.Entries) {
+					m.copyPathToClipboard(m.Entries[m.Cursor].Name)
+				}
+				m.lastCommand = ""
+			} else {
+				m.nextSearchHit(1)
+			}
+		case "N":
+			m.nextSearchHit(-1)
+		case "a":
+			m.startRename()
+		case "A":
+			return m, m.bulkRename()
+		case "L":
+			return m, m.startOpenWith()
+		case "z":
+			m.zoxideMode = true
+			m.zoxideQuery = ""
+		case "f":
+			return m, m.findFile()
+		case "m":
+			m.startMark(bookmarkSet)
+		case "'":
+			m.startMark(bookmarkJump)
+		case "g":
+			if m.handleDoubleCommand("g") {
+				m.Cursor = 0
+			}
+		case "t":
+			// gt opens the trash view
+			if m.lastCommand == "g" && time.Since(m.commandTime) < 500*time.Millisecond {
+				m.openTrashView()
+				m.lastCommand = ""
+			}
+		case "G":
+			m.Cursor = len(m.Entries) - 1
+		case "u":
+			m.undoLastAction()
+		case "ctrl+r":
+			return m, m.redoLastAction()
+		case "E":
+			return m, m.confirmEmptyTrash()
+		case "S":
+			// Shift+S: Open terminal in current directory
+			return m, m.openTerminal()
+		case "e":
+			return m, m.openInEditor()
+		case "P":
+			m.cyclePathDisplay()
+		case "H":
+			m.focusParent()
+		case "r":
+			// If last command was "y", then it's yr (copy relative path)
+			if m.lastCommand == "y" && time.Since(m.commandTime) < 500*time.Millisecond {
+				m.copyRelativePath(m.workDir)
+				m.lastCommand = ""
+			} else if m.lastCommand == "g" && time.Since(m.commandTime) < 500*time.Millisecond {
+				// gr moves entries into a manual order
+				m.startReorder()
+				m.lastCommand = ""
+			}
+		case "R":
+			// yR asks for the base, a lone R refreshes
+			if m.lastCommand == "y" && time.Since(m.commandTime) < 500*time.Millisecond {
+				m.relBaseMode = true
+				m.relBaseText = m.workDir
+				m.lastCommand = ""
+			} else {
+				m.refresh()
+			}
+		case "i":
+			m.showSizes = !m.showSizes
+			if m.showSizes {
+				m.setStatus("Showing sizes")
+			} else {
+				m.setStatus("Hiding sizes")
+			}
+		case "F":
+			m.dirsOnly = !m.dirsOnly
+			m.reloadKeepingSelection()
+			if m.dirsOnly {
+				m.setStatus("Showing directories only")
+			} else {
+				m.setStatus("Showing all entries")
+			}
+		case ".":
+			m.listOptions.ShowHidden = !m.listOptions.ShowHidden
+			m.reloadKeepingSelection()
+			if m.listOptions.ShowHidden {
+				m.setStatus("Showing hidden files")
+			} else {
+				m.setStatus("Hiding hidden files")
+			}
+		case "w":
+			return m, m.toggleWatching()
+		case "ctrl+g":
+			m.diffPreview = !m.diffPreview
+			if m.diffPreview {
+				m.setStatus("Previewing changes against HEAD")
+			} else {
+				m.setStatus("Previewing file contents")
+			}
+		case "J":
+			m.scrollPreview(1)
+		case "K":
+			m.scrollPreview(-1)
+		case "ctrl+d":
+			m.scrollPreview(m.previewHeight() / 2)
+		case "ctrl+u":
+			m.scrollPreview(-m.previewHeight() / 2)
+		case "ctrl+s":
+			return m, m.computeDirSize()
+		case "ctrl+p":
+			m.autoPreview = !m.autoPreview
+			if m.autoPreview {
+				m.setStatus("Previews on")
+			} else {
+				m.setStatus("Previews off")
+			}
+		case "?":
+			m.showWhichKey = !m.showWhichKey
+		default:
+			// Keys bound to nothing start typing a name to jump to
+			if _, mapped := m.keymap[msg.String()]; !mapped {
+				m.handleTypeAhead(msg)
+			}
+		}
+	case tea.WindowSizeMsg:
+		m.Width = msg.Width
+		m.Height = msg.Height
+		if m.previewLines != nil {
+			m.previewLines.follow(m.previewHeight())
+		}
+	}
+	return m, nil
+}
+
+// nameWidth returns the cells left for an entry name in a column of
+// colWidth, after the padding, the cursor marker, the icon and a trailing "/"
+func (m *FileManager) nameWidth(colWidth int) int {
+	width := colWidth - 4 - 2 - 1
+	if m.icons != nil {
+		width -= 2
+	}
+	return width
+}
+
+// renderParentColumn renders the parent directory column, from its listing
+// read in the background
+func (m *FileManager) renderParentColumn(colWidth int) string {
+	parent := filepath.Dir(m.CurrentPath)
+	if parent == m.CurrentPath {
+		return columnStyle.Width(colWidth).Render("System root")
+	}
+
+	var parentCol strings.Builder
+	listing, ok := m.listings[parent]
+	if !ok {
+		return columnStyle.Width(colWidth).Render(loadingMsg)
+	}
+	if listing.err != nil {
+		return columnStyle.Width(colWidth).Render(unreadableMsg)
+	}
+	parentEntries := listing.entries
+	currentBase := filepath.Base(m.CurrentPath)
+
+	nameWidth := m.nameWidth(colWidth)
+	for i, entry := range parentEntries {
+		line := truncateRight(entry.Name, nameWidth, "…")
+		if entry.IsDir {
+			line = dirStyle.Render(line + "/")
+		}
+		line = iconFor(m.icons, entry) + line
+		// With focus, the parent column has its own cursor
+		selected := entry.Name == currentBase
+		if m.parentMode {
+			selected = i == m.parentCursor
+		}
+		if selected {
+			line = selectedStyle.Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+		parentCol.WriteString(line + "\n")
+	}
+
+	return columnStyle.Width(colWidth).Render(parentCol.String())
+}
+
+// focusParent moves focus to the parent column, starting on the current directory
+func (m *FileManager) focusParent() {
+	parent := filepath.Dir(m.CurrentPath)
+	if parent == m.CurrentPath {
+		return
+	}
+
+	listing, ok := m.listings[parent]
+	if !ok {
+		m.setStatus("Still reading %s", parent)
+		return
+	}
+	if listing.err != nil {
+		m.setError(listing.err)
+		return
+	}
+	parentEntries := listing.entries
+	m.parentMode = true
+	m.parentCursor = 0
+	currentBase := filepath.Base(m.CurrentPath)
+	for i, entry := range parentEntries {
+		if entry.Name == currentBase {
+			m.parentCursor = i
+			break
+		}
+	}
+}
+
+// handleParentKey handles keys while the parent column has focus
+func (m *FileManager) handleParentKey(msg tea.KeyMsg) tea.Cmd {
+	// The parent stops being readable at times; it then lists nothing
+	parentEntries := m.listings[filepath.Dir(m.CurrentPath)].entries
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc", "H":
+		m.parentMode = false
+	case "up", "k":
+		if m.parentCursor > 0 {
+			m.parentCursor--
+		}
+	case "down", "j":
+		if m.parentCursor < len(parentEntries)-1 {
+			m.parentCursor++
+		}
+	case "l", "enter", "right":
+		if m.parentCursor >= len(parentEntries) {
+			return nil
+		}
+		entry := parentEntries[m.parentCursor]
+		if !entry.IsDir {
+			m.setStatus("%s is not a directory", entry.Name)
+			return nil
+		}
+		// Re-root the listing on the chosen sibling
+		m.parentMode = false
+		m.openDir(entry.Path, "")
+	}
+	return nil
+}
+
+// renderDirPreview renders the preview of a directory, from its listing read
+// in the background
+func (m *FileManager) renderDirPreview(path string, colWidth int) string {
+	var preview strings.Builder
+	listing, ok := m.listings[path]
+	if !ok {
+		return loadingMsg
+	}
+	if listing.err != nil {
+		return unreadableMsg
+	}
+	entries := listing.entries
+
+	if len(entries) == 0 {
+		return emptyDirMsg
+	}
+
+	nameWidth := m.nameWidth(colWidth)
+	for i, entry := range entries {
+		if i > contentLimit {
+			preview.WriteString("...\n")
+			break
+		}
+
+		line := truncateRight(entry.Name, nameWidth, "…")
+		if entry.IsDir {
+			line = dirStyle.Render(line + "/")
+		}
+		preview.WriteString("  " + iconFor(m.icons, entry) + line + "\n")
+	}
+	return preview.String()
+}
+
+// renderMarkdownPreview renders a markdown file from line scroll on
+func renderMarkdownPreview(content []byte, maxHeight, scroll int) string {
+	rendered, err := markdownRenderer.Render(string(content))
+	if err != nil {
+		return "Error rendering markdown"
+	}
+
+	// Limit number of lines to maximum height
+	lines := scrollLines(strings.Split(rendered, "\n"), maxHeight, scroll)
+	return strings.Join(lines, "\n")
+}
+
+// renderTextPreview renders a text file from line scroll on
+func renderTextPreview(content []byte, colWidth, maxHeight, scroll int) string {
+	var preview strings.Builder
+
+	// Limit number of lines to maximum height
+	lines := scrollLines(strings.Split(string(content), "\n"), maxHeight, scroll)
+
+	limit := max(colWidth-4, 0)
+	for _, line := range lines {
+		if limit > 3 {
+			line = truncateRight(line, limit, "...")
+		} else {
+			line = truncateRight(line, limit, "")
+		}
+		preview.WriteString(line + "\n")
+	}
+
+	return preview.String()
+}
+
+// renderFilePreview renders the preview of a file, text scrolled down to
+// line scroll
+func renderFilePreview(fsys FS, file FileEntry, theme string, colWidth, maxHeight, scroll int) string {
+	content, err := readFile(fsys, file.Path)
+	if err != nil {
+		return "Error reading file"
+	}
+
+	// If it's a markdown file, use glamour
+	if strings.HasSuffix(strings.ToLower(file.Name), ".md") {
+		return renderMarkdownPreview(content, maxHeight, scroll)
+	}
+
+	// For other text files, highlighted when they are source code
+	if len(content) > 0 && !containsNullByte(content) {
+		if preview, ok := renderHighlightedPreview(file.Name, content, theme, colWidth, maxHeight, scroll); ok {
+			return preview
+		}
+		return renderTextPreview(content, colWidth, maxHeight, scroll)
+	}
+
+	// For binary files
+	return "[Binary file]"
+}
+
+// renderPreviewColumn renders the preview column
+func (m *FileManager) renderPreviewColumn(colWidth int) string {
+	// Nothing to preview until the directory being entered is read
+	if m.loadingDir != "" {
+		return columnStyle.Width(colWidth).Render("")
+	}
+	if len(m.Entries) == 0 {
+		return columnStyle.Width(colWidth).Render(emptyDirMsg)
+	}
+	if m.Cursor >= len(m.Entries) {
+		return columnStyle.Width(colWidth).Render(noSelectionMsg)
+	}
+
+	selected := m.Entries[m.Cursor]
+	var content string
+
+	// With previews off, show only the file info without reading the file
+	if !m.autoPreview {
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			getFileInfo(m.filesystem(), selected.Path, m.relativeTimes, m.dirCounts),
+			"",
+			emptyStateStyle.Render("Previews are off (ctrl+p)"),
+		)
+		return columnStyle.Width(colWidth).Render(content)
+	}
+
+	maxPreviewHeight := m.previewHeight()
+	if m.previewLines != nil {
+		content = m.renderPreviewLines(colWidth, maxPreviewHeight)
+	} else if link, err := readSymlink(m.filesystem(), selected.Path); err == nil && link.broken {
+		// Nothing to preview past the target that's missing
+		content = "Symlink " + link.String()
+	} else if selected.IsDir {
+		content = m.renderDirPreview(selected.Path, colWidth)
+	} else {
+		content = m.renderEntryPreview(selected, colWidth, maxPreviewHeight)
+	}
+
+	return columnStyle.Width(colWidth).Render(content)
+}
+
+// renderEntryPreview renders the preview of a file: its diff, its preview
+// command's output, its video metadata, or its contents
+func (m *FileManager) renderEntryPreview(file FileEntry, colWidth, maxHeight int) string {
+	if m.diffPreview {
+		return m.renderDiffPreview(file, colWidth, maxHeight)
+	}
+	if _, ok := previewCommandFor(m.previewCommands, file.Name); ok {
+		return m.renderCommandPreview(file, colWidth, maxHeight)
+	}
+	// Videos show metadata instead of being read whole
+	if isVideoFile(file.Name) {
+		preview, ok := m.loadedPreviewOf("video", file.Path)
+		if !ok && m.archive == nil {
+			return loadingMsg
+		}
+		if preview.text == "" {
+			return "[Binary file]"
+		}
+		return preview.text
+	}
+	// Archives list what they hold
+	if isArchive(file.Name) {
+		preview, ok := m.loadedPreviewOf("archive", file.Path)
+		if !ok {
+			return loadingMsg
+		}
+		return renderArchivePreview(preview, colWidth, maxHeight)
+	}
+	return renderFilePreview(m.filesystem(), file, m.previewTheme, colWidth, maxHeight, m.previewScroll)
+}
+
+// listHeight returns how many entries the columns have room for
+func (m *FileManager) listHeight() int {
+	headerHeight := 1  // Path height
+	statusHeight := 1  // Status bar height
+	commandHeight := 1 // Command/search line height
+	// whichKeyHeight doesn't factor into it, the overlay covers the columns
+	return max(m.Height-headerHeight-statusHeight-commandHeight-1, 0) // -1 for content margin top
+}
+
+// previewHeight returns how many lines the preview column has room for
+func (m *FileManager) previewHeight() int {
+	headerHeight := 2 // 1 content line + 1 padding
+	statusHeight := 1 // 1 content line
+	whichKeyHeight := 0
+	if m.showWhichKey {
+		if m.searchMode {
+			whichKeyHeight = 3
+		} else {
+			whichKeyHeight = 5
+		}
+	}
+	return max(m.Height-headerHeight-statusHeight-whichKeyHeight-2, 0) // -2 for margins
+}
+
+// getFileInfo returns detailed file information, with the modification time
+// relative to now if relativeTimes is set. A directory's items are taken
+// from dirCounts rather than read.
+func getFileInfo(fsys FS, path string, relativeTimes bool, dirCounts map[string]int) string {
+	// Symlinks show where they lead, even when nothing is there
+	link, linkErr := readSymlink(fsys, path)
+	info, err := fsys.Stat(path)
+	if err != nil && linkErr == nil {
+		return "Symlink " + link.String()
+	} else if err != nil {
+		return "Error getting file information"
+	}
+
+	// Format permissions
+	mode := info.Mode().String()
+
+	// Format size
+	size := ""
+	if info.IsDir() {
+		// Counted in the background, as this is drawn on every frame
+		if count, ok := dirCounts[path]; !ok {
+			size = "counting…"
+		} else if count < 0 {
+			size = "unreadable"
+		} else {
+			size = pluralize(count, "item")
+		}
+	} else {
+		size = HumanSize(info.Size())
+	}
+
+	// Format modification date
+	modTime := info.ModTime().Format(timeLayout)
+	if relativeTimes {
+		modTime = relativeTime(info.ModTime(), time.Now())
+	}
+
+	// Ownership is left out where the platform has none to show
+	fileInfo := fmt.Sprintf("%s  %s  %s", mode, size, modTime)
+	if owner, group, ok := fileOwner(info); ok {
+		fileInfo = fmt.Sprintf("%s  %s  %s  %s  %s", mode, owner, group, size, modTime)
+	}
+	if linkErr == nil {
+		fileInfo += "  " + link.String()
+	}
+	return fileInfo
+}
+
+// How modification times are shown when not relative
+const timeLayout = "02 Jan 2006 15:04"
+
+// relativeTime describes t from now, like "3 minutes ago" or "yesterday",
+// falling back to the date for anything older than a week
+func relativeTime(t, now time.Time) string {
+	age := now.Sub(t)
+	if age < time.Minute {
+		return "just now"
+	}
+	if age < time.Hour {
+		return pluralize(int(age/time.Minute), "minute") + " ago"
+	}
+
+	// Older times count calendar days, so last night is yesterday
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	days := 0
+	for t.Before(today.AddDate(0, 0, -days)) {
+		days++
+		if days > 7 {
+			return t.Format(timeLayout)
+		}
+	}
+	switch days {
+	case 0:
+		return pluralize(int(age/time.Hour), "hour") + " ago"
+	case 1:
+		return "yesterday"
+	}
+	return pluralize(days, "day") + " ago"
+}
+
+// HumanSize formats a byte count like 512B, 2.3K or 1.1G
+func HumanSize(bytes int64) string {
+	switch {
+	case bytes < 1024:
+		return fmt.Sprintf("%dB", bytes)
+	case bytes < 1024*1024:
+		return fmt.Sprintf("%.1fK", float64(bytes)/1024)
+	case bytes < 1024*1024*1024:
+		return fmt.Sprintf("%.1fM", float64(bytes)/1024/1024)
+	default:
+		return fmt.Sprintf("%.1fG", float64(bytes)/1024/1024/1024)
+	}
+}
+
+// pluralize formats a count with a noun, like "1 item" or "3 items"
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// openWithDefaultApp opens a file with the system's default program
+func openWithDefaultApp(path string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", path)
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", path)
+	default: // Linux and others
+		cmd = exec.Command("xdg-open", path)
+	}
+
+	return cmd.Start()
+}
+
+// containsNullByte checks if content appears to be binary
+func containsNullByte(data []byte) bool {
+	for _, b := range data {
+		if b == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Methods for file manipulation
+
+// quit exits, first asking when a cut hasn't been pasted: the cut is only
+// dropped, its entries stay where they are
+func (m *FileManager) quit() tea.Cmd {
+	if m.clipboardOp != "cut" || len(m.clipboard) == 0 {
+		return tea.Quit
+	}
+	m.confirm = &confirmation{
+		prompt: fmt.Sprintf("Cut of %s isn't pasted, quit and leave it in place? [y]es  [n]o", entriesName(m.clipboard)),
+		actions: map[string]func() tea.Cmd{
+			"y": func() tea.Cmd { return tea.Quit },
+			"n": func() tea.Cmd { return nil },
+		},
+	}
+	return nil
+}
+
+func (m *FileManager) cutFile() {
+	entries := m.selectedEntries()
+	if len(entries) == 0 {
+		return
+	}
+	m.clipboard = entries
+	m.clipboardOp = "cut"
+	m.cutFrom = m.CurrentPath
+	m.cutNavigated = false
+	if m.keepCutVisible {
+		m.clearSelection()
+		m.setStatus("Cut %s", entriesName(entries))
+		return
+	}
+
+	// Add to undo stack to restore visually if necessary
+	from := len(m.undoStack)
+	for _, entry := range entries {
+		m.pushUndo(UndoAction{
+			Type:    "cut",
+			OldPath: entry.Path,
+			Entry:   entry,
+		})
+	}
+	m.groupUndo(from, "cutting "+pluralize(len(entries), "item"))
+
+	// Remove the files from visual list (will be moved when pasted)
+	kept := m.Entries[:0]
+	for _, entry := range m.Entries {
+		if !m.isCut(entry.Path) {
+			kept = append(kept, entry)
+		}
+	}
+	m.Entries = kept
+	m.Cursor = max(min(m.Cursor, len(m.Entries)-1), 0)
+}
+
+// selectedEntries returns the entries marked with space, or the one under
+// the cursor when none are
+func (m *FileManager) selectedEntries() []FileEntry {
+	var selected []FileEntry
+	for _, entry := range m.Entries {
+		if entry.Selected {
+			selected = append(selected, entry)
+		}
+	}
+	if len(selected) == 0 && m.Cursor < len(m.Entries) {
+		selected = append(selected, m.Entries[m.Cursor])
+	}
+	return selected
+}
+
+// toggleSelected marks or unmarks the entry under the cursor and moves on
+// to the next one
+func (m *FileManager) toggleSelected() {
+	if m.Cursor >= len(m.Entries) {
+		return
+	}
+	m.Entries[m.Cursor].Selected = !m.Entries[m.Cursor].Selected
+	m.Cursor = min(m.Cursor+1, len(m.Entries)-1)
+
+	count := 0
+	for _, entry := range m.Entries {
+		if entry.Selected {
+			count++
+		}
+	}
+	m.setStatus("%d selected", count)
+}
+
+// clearSelection unmarks all entries
+func (m *FileManager) clearSelection() {
+	for i := range m.Entries {
+		m.Entries[i].Selected = false
+	}
+}
+
+// entriesName names a single entry, or says how many there are
+func entriesName(entries []FileEntry) string {
+	if len(entries) == 1 {
+		return entries[0].Name
+	}
+	return pluralize(len(entries), "item")
+}
+
+// isCut reports whether path is waiting in the clipboard to be moved
+func (m *FileManager) isCut(path string) bool {
+	if m.clipboardOp != "cut" {
+		return false
+	}
+	for _, entry := range m.clipboard {
+		if entry.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteFile moves the selected entries to the trash; they are restored
+// together by undo
+func (m *FileManager) deleteFile() {
+	entries := m.selectedEntries()
+	if len(entries) == 0 {
+		return
+	}
+
+	// Each entry's place in the trash is reserved in turn, then the moves
+	// run as a batch
+	errs := make([]error, len(entries))
+	trashPaths := make([]string, len(entries))
+	reserved := make(map[string]bool)
+	var pending []int
+	var paths []string
+	for i, entry := range entries {
+		if m.batchStop && batchError(errs[:i]) != nil {
+			errs[i] = errBatchStopped
+			continue
+		}
+		m.runHook("pre_delete", entry.Path, "")
+		if trashPaths[i], errs[i] = m.reserveTrash(entry.Path, reserved); errs[i] == nil {
+			pending = append(pending, i)
+			paths = append(paths, entry.Path)
+		}
+	}
+	fsys := m.filesystem()
+	moveErrs := runBatch(len(pending), m.batchWorkersFor(paths), m.batchStop, func(j int) error {
+		i := pending[j]
+		return moveFileOrDir(fsys, entries[i].Path, trashPaths[i])
+	})
+	for j, i := range pending {
+		errs[i] = moveErrs[j]
+	}
+
+	// What was trashed is recorded in the order it was selected
+	from := len(m.undoStack)
+	var trashed []FileEntry
+	for i, entry := range entries {
+		if errs[i] != nil {
+			continue
+		}
+		m.runHook("post_delete", entry.Path, "")
+		trashed = append(trashed, entry)
+
+		// Add to undo stack
+		undoAction := UndoAction{
+			Type:    "delete",
+			OldPath: entry.Path,
+			NewPath: trashPaths[i], // Save where it is in trash
+			Entry:   entry,
+		}
+		m.pushUndo(undoAction)
+	}
+	if err := batchError(errs); err != nil {
+		m.setError(err)
+	}
+	if len(trashed) == 0 {
+		return
+	}
+	m.groupUndo(from, "deleting "+pluralize(len(trashed), "item"))
+	if !m.statusIsError {
+		m.setStatus("Moved %s to trash", entriesName(trashed))
+	}
+
+	// Update list
+	m.reloadEntries()
+	m.Cursor = max(min(m.Cursor, len(m.Entries)-1), 0)
+}
+
+// uniquePath returns path, or path with a _1, _2... suffix before the
+// extension if it already exists
+func uniquePath(fsys FS, path string) string {
+	return unreservedPath(fsys, path, nil)
+}
+
+// unreservedPath is uniquePath also passing over the paths reserved by a
+// batch whose entries haven't been moved into place yet
+func unreservedPath(fsys FS, path string, reserved map[string]bool) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	candidate := path
+	for counter := 1; ; counter++ {
+		if _, err := fsys.Stat(candidate); os.IsNotExist(err) && !reserved[candidate] {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d%s", base, counter, ext)
+	}
+}
+
+// moveToTrash moves a file or directory into the trash and returns its new path
+func (m *FileManager) moveToTrash(path string) (string, error) {
+	trashPath, err := m.reserveTrash(path, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := moveFileOrDir(m.filesystem(), path, trashPath); err != nil {
+		return "", err
+	}
+	return trashPath, nil
+}
+
+// reserveTrash records path as trashed and returns where in the trash it
+// is to be moved. Bulk deletes reserve each entry's place in turn, adding
+// it to reserved, then move them all at once.
+func (m *FileManager) reserveTrash(path string, reserved map[string]bool) (string, error) {
+	// Create trash directory if it doesn't exist
+	if m.trashDir == "" {
+		tmpDir, err := os.MkdirTemp("", "tfm_trash_")
+		if err != nil {
+			return "", err
+		}
+		m.trashDir = tmpDir
+		addTempTrash(tmpDir)
+	}
+
+	// Move file to trash instead of permanently deleting it, with a suffix
+	// if a file with the same name already exists in trash
+	trashPath := unreservedPath(m.filesystem(), filepath.Join(m.trashDir, filepath.Base(path)), reserved)
+
+	if err := m.trash().record(trashPath, path); err != nil {
+		return "", err
+	}
+	if reserved != nil {
+		reserved[trashPath] = true
+	}
+	return trashPath, nil
+}
+
+func (m *FileManager) copyFile() {
+	entries := m.selectedEntries()
+	if len(entries) == 0 {
+		return
+	}
+	m.clipboard = entries
+	m.clipboardOp = "copy"
+	// Like a cut, the copy takes the selection with it
+	m.clearSelection()
+	m.setStatus("Copied %s", entriesName(entries))
+}
+
+// Ways to resolve a paste onto a name that already exists
+const (
+	collisionKeep      = "keep"      // Default behavior: suffix copies, moves replace
+	collisionOverwrite = "overwrite" // Send the existing entry to trash first
+	collisionSkip      = "skip"      // Leave the existing entry alone
+	collisionRename    = "rename"    // Paste under a free name with a suffix
+	collisionAsk       = "ask"       // Prompt for each collision
+)
+
+// What to do when a rename target already exists (rename_overwrite)
+const (
+	renameAsk    = "ask"    // Default: confirm before overwriting
+	renameRefuse = "refuse" // Never overwrite; report an error instead
+	renameSuffix = "suffix" // Keep both, adding a _1, _2... suffix to the new name
+)
+
+// copyPathToClipboard copies a path to the system clipboard
+func (m *FileManager) copyPathToClipboard(path string) {
+	if err := CopyToClipboard(path); err != nil {
+		m.setError(err)
+		return
+	}
+	m.setStatus("Copied %s to clipboard", path)
+}
+
+// copyRelativePath copies the selected entry's path relative to base.
+// A relative base is taken from the current directory.
+func (m *FileManager) copyRelativePath(base string) {
+	if len(m.Entries) == 0 || m.Cursor >= len(m.Entries) {
+		return
+	}
+	if !filepath.IsAbs(base) {
+		base = filepath.Join(m.CurrentPath, base)
+	}
+
+	rel, err := filepath.Rel(base, m.Entries[m.Cursor].Path)
+	if err != nil {
+		m.setError(err)
+		return
+	}
+	m.copyPathToClipboard(rel)
+}
+
+func (m *FileManager) pasteFile() tea.Cmd {
+	if len(m.clipboard) == 0 {
+		return nil
+	}
+	if m.checkingSpace {
+		m.setStatus("Still checking the space for the last paste")
+		return nil
+	}
+
+	// Pasting a cut right where it was made, without navigating away,
+	// cancels it. After leaving and coming back it is a move like any other.
+	if m.clipboardOp == "cut" && m.CurrentPath == m.cutFrom && !m.cutNavigated {
+		name := m.clipboard[0].Name
+		m.clipboard = nil
+		m.clipboardOp = ""
+		m.setStatus("Cancelled cut")
+		// Reload the list to show the file again, with the cursor on it
+		m.reloadEntries()
+		m.selectByName(name)
+		return nil
+	}
+	m.pastedName = ""
+
+	// Count how many destinations already exist
+	existing := 0
+	for _, entry := range m.clipboard {
+		if m.pasteCollides(entry) {
+			existing++
+		}
+	}
+
+	if existing == 0 {
+		return m.pasteEntries(m.clipboard, collisionKeep)
+	}
+	if len(m.clipboard) == 1 {
+		return m.pasteEntries(m.clipboard, collisionAsk)
+	}
+
+	// Bulk paste with collisions: ask once how to handle all of them
+	entries := m.clipboard
+	m.confirm = &confirmation{
+		prompt: fmt.Sprintf("%d of %d already exist — [o]verwrite all  [s]kip existing  [r]ename all  [d]ecide each  [c]ancel",
+			existing, len(entries)),
+		actions: map[string]func() tea.Cmd{
+			"o": func() tea.Cmd { return m.pasteEntries(entries, collisionOverwrite) },
+			"s": func() tea.Cmd { return m.pasteEntries(entries, collisionSkip) },
+			"r": func() tea.Cmd { return m.pasteEntries(entries, collisionRename) },
+			"d": func() tea.Cmd { return m.pasteEntries(entries, collisionAsk) },
+			"c": func() tea.Cmd { return nil },
+		},
+	}
+	return nil
+}
+
+// pasteEntries pastes entries into the current directory, resolving name
+// collisions with the given policy
+func (m *FileManager) pasteEntries(entries []FileEntry, policy string) tea.Cmd {
+	// Nothing is copied unless all of it fits. Moves only rename, unless
+	// across filesystems.
+	if m.clipboardOp != "copy" {
+		return m.pasteFitting(entries, policy)
+	}
+	paths := make([]string, len(entries))
+	for i, entry := range entries {
+		paths[i] = entry.Path
+	}
+	return m.checkSpace(paths, func() tea.Cmd { return m.pasteFitting(entries, policy) })
+}
+
+// pasteFitting is pasteEntries once the entries are known to fit
+func (m *FileManager) pasteFitting(entries []FileEntry, policy string) tea.Cmd {
+	pasted := 0
+	// What is pasted in one go is undone in one step
+	from := len(m.undoStack)
+	verb := "copying"
+	if m.clipboardOp == "cut" {
+		verb = "moving"
+	}
+	if policy != collisionAsk {
+		pasted = m.pasteBatch(entries, policy)
+	} else {
+		// Entries are pasted one by one, stopping to ask at each collision
+		for i, entry := range entries {
+			if m.pasteCollides(entry) {
+				// Show what was pasted so far and ask about this one
+				m.groupUndo(from, verb+" "+pluralize(pasted, "item"))
+				m.reportPaste(pasted, entries[:i])
+				m.reloadEntries()
+				m.askCollision(entry, entries[i+1:])
+				return nil
+			}
+			ok, err := m.pasteEntry(entry, m.clipboardOp, policy)
+			if err != nil {
+				m.setError(err)
+			} else if ok {
+				pasted++
+			}
+		}
+	}
+	m.groupUndo(from, verb+" "+pluralize(pasted, "item"))
+	m.reportPaste(pasted, entries)
+
+	if m.clipboardOp == "cut" {
+		// Clear clipboard after cut+paste
+		m.clipboard = nil
+		m.clipboardOp = ""
+	}
+	// For copy, don't clear clipboard to allow multiple copies
+
+	// Update list and go to what was pasted
+	m.reloadEntries()
+	if m.pastedName != "" {
+		m.selectByName(m.pastedName)
+	}
+	return nil
+}
+
+// reportPaste confirms how many entries were pasted and how many skipped,
+// unless an error is showing
+func (m *FileManager) reportPaste(pasted int, entries []FileEntry) {
+	if len(entries) == 0 || m.statusIsError {
+		return
+	}
+
+	verb := "Copied"
+	if m.clipboardOp == "cut" {
+		verb = "Moved"
+	}
+	switch skipped := len(entries) - pasted; {
+	case pasted == 0:
+		m.setStatus("Skipped %s", entriesName(entries))
+	case len(entries) == 1:
+		m.setStatus("%s %s", verb, entries[0].Name)
+	case skipped > 0:
+		m.setStatus("%s %d items, skipped %d", verb, pasted, skipped)
+	default:
+		m.setStatus("%s %d items", verb, pasted)
+	}
+}
+
+// pasteCollides reports whether pasting entry here would land on another
+// existing entry. Copying an entry into its own directory doesn't count, as
+// that pastes a _copy of it.
+func (m *FileManager) pasteCollides(entry FileEntry) bool {
+	dest := filepath.Join(m.CurrentPath, entry.Name)
+	if dest == entry.Path {
+		return false
+	}
+	_, err := m.filesystem().Stat(dest)
+	return err == nil
+}
+
+// askCollision prompts for a single existing destination, then continues
+// with the remaining entries. The uppercase keys apply to all of them.
+func (m *FileManager) askCollision(entry FileEntry, rest []FileEntry) {
+	decide := func(policy, restPolicy string) func() tea.Cmd {
+		return func() tea.Cmd {
+			// An overwrite is undone along with the paste
+			from := len(m.undoStack)
+			ok, err := m.pasteEntry(entry, m.clipboardOp, policy)
+			m.groupUndo(from, "pasting "+entry.Name)
+			if err != nil {
+				m.setError(err)
+			} else if ok {
+				m.reportPaste(1, []FileEntry{entry})
+			} else {
+				m.setStatus("Skipped %s", entry.Name)
+			}
+			return m.pasteEntries(rest, restPolicy)
+		}
+	}
+
+	prompt := "[o]verwrite  [s]kip  [r]ename  [O]verwrite all  [S]kip all  [R]ename all  [c]ancel"
+	if len(rest) == 0 {
+		prompt = "[o]verwrite  [s]kip  [r]ename  [c]ancel"
+	}
+	m.confirm = &confirmation{
+		prompt: fmt.Sprintf("%s already exists — %s", entry.Name, prompt),
+		actions: map[string]func() tea.Cmd{
+			"o": decide(collisionOverwrite, collisionAsk),
+			"s": decide(collisionSkip, collisionAsk),
+			"r": decide(collisionRename, collisionAsk),
+			"O": decide(collisionOverwrite, collisionOverwrite),
+			"S": decide(collisionSkip, collisionSkip),
+			"R": decide(collisionRename, collisionRename),
+			"c": func() tea.Cmd {
+				m.stopPaste(append([]FileEntry{entry}, rest...))
+				return nil
+			},
+		},
+	}
+}
+
+// stopPaste gives up on a paste at a collision. Whatever was pasted before
+// it stays; a cut keeps only the entries not moved yet, so pasting again
+// elsewhere moves just those.
+func (m *FileManager) stopPaste(remaining []FileEntry) {
+	if m.clipboardOp != "cut" {
+		m.setStatus("Stopped pasting")
+		return
+	}
+	m.clipboard = remaining
+	m.setStatus("Stopped pasting, %s still cut", pluralize(len(remaining), "item"))
+}
+
+// pasteEntry copies or moves (op "copy" or "cut") a single entry into the
+// current directory. It reports false when the entry was skipped.
+func (m *FileManager) pasteEntry(entry FileEntry, op, policy string) (bool, error) {
+	job, ok, err := m.planPaste(entry, op, policy, nil)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := job.run(m.filesystem()); err != nil {
+		return false, err
+	}
+	m.finishPaste(job)
+	return true, nil
+}
+
+// pasteJob is an entry to paste, its destination worked out
+type pasteJob struct {
+	entry FileEntry
+	dest  string
+	op    string // "copy" or "cut"
+}
+
+// planPaste works out where entry is pasted, sending an entry it replaces
+// to the trash, and runs the pre hook. A batch passes the destinations of
+// its entries planned so far in reserved, which are never pasted onto; the
+// entry's own is added. It reports false when the entry is skipped.
+func (m *FileManager) planPaste(entry FileEntry, op, policy string, reserved map[string]bool) (pasteJob, bool, error) {
+	fsys := m.filesystem()
+	destPath := filepath.Join(m.CurrentPath, entry.Name)
+
+	if _, statErr := fsys.Stat(destPath); statErr == nil || reserved[destPath] {
+		switch {
+		case policy == collisionSkip:
+			return pasteJob{}, false, nil
+		case policy == collisionOverwrite && !reserved[destPath]:
+			if destPath == entry.Path {
+				return pasteJob{}, false, nil // Pasting onto itself
+			}
+			// Keep the replaced entry recoverable
+			trashPath, err := m.moveToTrash(destPath)
+			if err != nil {
+				return pasteJob{}, false, err
+			}
+			m.pushUndo(UndoAction{
+				Type:    "delete",
+				OldPath: destPath,
+				NewPath: trashPath,
+				Entry:   FileEntry{Name: entry.Name, Path: destPath},
+			})
+		default:
+			if op == "copy" {
+				// For copy, add a suffix to keep both, and a number if
+				// that copy exists too
+				ext := filepath.Ext(entry.Name)
+				name := strings.TrimSuffix(entry.Name, ext)
+				destPath = unreservedPath(fsys, filepath.Join(m.CurrentPath, name+"_copy"+ext), reserved)
+			} else if (policy == collisionRename || reserved[destPath]) && destPath != entry.Path {
+				// Another entry of the batch is pasted here
+				destPath = unreservedPath(fsys, destPath, reserved)
+			}
+		}
+	}
+	if reserved != nil {
+		reserved[destPath] = true
+	}
+
+	job := pasteJob{entry: entry, dest: destPath, op: op}
+	if op == "cut" {
+		// The file may have been removed since it was cut
+		if _, err := fsys.Stat(entry.Path); err != nil {
+			return pasteJob{}, false, err
+		}
+		// Moving an entry onto itself leaves nothing to do or undo
+		if destPath == entry.Path {
+			return pasteJob{}, false, nil
+		}
+		m.runHook("pre_move", destPath, entry.Path)
+		return job, true, nil
+	}
+	m.runHook("pre_copy", destPath, entry.Path)
+	return job, true, nil
+}
+
+// run copies or moves the entry. Unlike the rest of a paste it only
+// touches the filesystem, so jobs can run at once.
+func (j pasteJob) run(fsys FS) error {
+	if j.op == "cut" {
+		if j.dest == j.entry.Path {
+			return nil
+		}
+		return moveFileOrDir(fsys, j.entry.Path, j.dest)
+	}
+	// Something put there since the paste was planned isn't ours to
+	// overwrite, or to remove if the copy fails
+	if _, err := fsys.Stat(j.dest); err == nil {
+		return &fs.PathError{Op: "copy", Path: j.dest, Err: fs.ErrExist}
+	}
+	if err := copyPath(fsys, j.entry.Path, j.dest); err != nil {
+		// Don't leave a partial copy behind
+		fsys.RemoveAll(j.dest)
+		return err
+	}
+	return nil
+}
+
+// finishPaste runs the post hook of a pasted entry and records its undo
+func (m *FileManager) finishPaste(j pasteJob) {
+	m.pastedName = filepath.Base(j.dest)
+	if j.op == "cut" {
+		if j.dest == j.entry.Path {
+			return
+		}
+		m.runHook("post_move", j.dest, j.entry.Path)
+		m.moveMarks(j.entry.Path, j.dest)
+		// Add to undo stack for the movement
+		m.pushUndo(UndoAction{
+			Type:    "move",
+			OldPath: j.entry.Path,
+			NewPath: j.dest,
+			Entry:   j.entry,
+		})
+		return
+	}
+
+	m.runHook("post_copy", j.dest, j.entry.Path)
+	// Add to undo stack for the copy
+	m.pushUndo(UndoAction{
+		Type:    "copy",
+		OldPath: j.entry.Path, // Copied from, for redo
+		NewPath: j.dest,       // File that was created
+		Entry:   j.entry,
+	})
+}
+
+// pasteBatch pastes entries, planned one by one and then copied or moved
+// as a batch, and returns how many were pasted. Their undo actions are
+// recorded in the order of entries whatever order they finish in.
+func (m *FileManager) pasteBatch(entries []FileEntry, policy string) int {
+	errs := make([]error, len(entries))
+	jobs := make([]pasteJob, len(entries))
+	reserved := make(map[string]bool)
+	var pending []int
+	var paths []string
+	for i, entry := range entries {
+		if m.batchStop && batchError(errs[:i]) != nil {
+			errs[i] = errBatchStopped
+			continue
+		}
+		var ok bool
+		if jobs[i], ok, errs[i] = m.planPaste(entry, m.clipboardOp, policy, reserved); ok {
+			pending = append(pending, i)
+			paths = append(paths, entry.Path, jobs[i].dest)
+		}
+	}
+	fsys := m.filesystem()
+	runErrs := runBatch(len(pending), m.batchWorkersFor(paths), m.batchStop, func(j int) error {
+		return jobs[pending[j]].run(fsys)
+	})
+
+	pasted := 0
+	for j, i := range pending {
+		if errs[i] = runErrs[j]; errs[i] == nil {
+			m.finishPaste(jobs[i])
+			pasted++
+		}
+	}
+	if err := batchError(errs); err != nil {
+		m.setError(err)
+	}
+	return pasted
+}
+
+// CopyFileOrDir copies a file or directory recursively
+func CopyFileOrDir(src, dst string) error {
+	return copyPath(OS, src, dst)
+}
+
+// copyPath copies a file or directory recursively within fsys
+func copyPath(fsys FS, src, dst string) error {
+	srcInfo, err := fsys.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if srcInfo.IsDir() {
+		return copyDir(fsys, src, dst)
+	}
+	return copyFile(fsys, src, dst)
+}
+
+// copyFile copies a single file with its permissions and modification time
+func copyFile(fsys FS, src, dst string) error {
+	srcInfo, err := fsys.Stat(src)
+	if err != nil {
+		return err
+	}
+	srcFile, err := fsys.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := fsys.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
+		return err
+	}
+	if err := dstFile.Close(); err != nil {
+		return err
+	}
+	copyMetadata(fsys, dst, srcInfo)
+	return nil
+}
+
+// copyDir copies a directory recursively, keeping the permissions and
+// modification times throughout
+func copyDir(fsys FS, src, dst string) error {
+	srcInfo, err := fsys.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	// Private until its contents are in; its own mode comes last
+	err = fsys.MkdirAll(dst, 0700)
+	if err != nil {
+		return err
+	}
+
+	entries, err := fsys.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+		if err := copyPath(fsys, srcPath, dstPath); err != nil {
+			return err
+		}
+	}
+
+	// Only once its contents are written, which would change the time and
+	// might be refused by a read-only mode
+	copyMetadata(fsys, dst, srcInfo)
+	return nil
+}
+
+// moveFileOrDir moves a file or directory, falling back to copy+delete
+// when the destination is on a different filesystem
+func moveFileOrDir(fsys FS, src, dst string) error {
+	err := fsys.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyPath(fsys, src, dst); err != nil {
+		// Don't leave a partial copy behind
+		fsys.RemoveAll(dst)
+		return err
+	}
+	return fsys.RemoveAll(src)
+}
+
+// moveBack moves the entry of a delete, move or rename back from NewPath to
+// OldPath. Its directory is created again if it was removed since; if that
+// fails, the entry goes to the current directory instead and OldPath is
+// updated for redo.
+func (m *FileManager) moveBack(action *UndoAction) error {
+	fsys := m.filesystem()
+	if err := fsys.MkdirAll(filepath.Dir(action.OldPath), 0755); err == nil {
+		return moveFileOrDir(fsys, action.NewPath, action.OldPath)
+	}
+	dest := uniquePath(fsys, filepath.Join(m.CurrentPath, filepath.Base(action.OldPath)))
+	if err := moveFileOrDir(fsys, action.NewPath, dest); err != nil {
+		return err
+	}
+	action.OldPath = dest
+	m.relocated = append(m.relocated, dest)
+	return nil
+}
+
+// undoLastAction undoes the last action
+func (m *FileManager) undoLastAction() {
+	if len(m.undoStack) == 0 {
+		return
+	}
+
+	// Get the last action
+	lastAction := m.undoStack[len(m.undoStack)-1]
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
+
+	m.relocated = nil
+	if err := m.undo(&lastAction); err != nil {
+		// If it fails, put back in undo stack
+		m.undoStack = append(m.undoStack, lastAction)
+		m.setError(err)
+		return
+	}
+	m.redoStack = append(m.redoStack, lastAction)
+	summary := lastAction.Summary
+	if lastAction.Type != "batch" {
+		summary = lastAction.Type + " of " + lastAction.Entry.Name
+	}
+	if len(m.relocated) == 1 {
+		m.setStatus("Undid %s; its directory is gone, so it is in %s", summary, m.relocated[0])
+	} else if len(m.relocated) > 1 {
+		m.setStatus("Undid %s; %s whose directory is gone are in %s", summary, pluralize(len(m.relocated), "item"), m.CurrentPath)
+	} else {
+		m.setStatus("Undid %s", summary)
+	}
+
+	// Update list
+	m.reloadEntries()
+}
+
+// groupUndo turns the actions recorded since the undo stack had from
+// entries into a single batch, so they are undone in one step
+func (m *FileManager) groupUndo(from int, summary string) {
+	if len(m.undoStack)-from < 2 {
+		return
+	}
+	batch := UndoAction{
+		Type:    "batch",
+		Batch:   slices.Clone(m.undoStack[from:]),
+		Summary: summary,
+	}
+	m.undoStack = append(m.undoStack[:from], batch)
+}
+
+// pushUndo records a new action. Like in an editor, it drops whatever could
+// still be redone.
+func (m *FileManager) pushUndo(action UndoAction) {
+	m.undoStack = append(m.undoStack, action)
+	m.redoStack = nil
+}
+
+// redoLastAction applies the last undone action again. An extraction runs
+// in the background like the first time.
+func (m *FileManager) redoLastAction() tea.Cmd {
+	if len(m.redoStack) == 0 {
+		return nil
+	}
+
+	lastAction := m.redoStack[len(m.redoStack)-1]
+	if lastAction.Type == "extract" {
+		cmd, err := m.redoExtract(lastAction)
+		if err != nil {
+			m.setError(err)
+			return nil
+		}
+		m.redoStack = m.redoStack[:len(m.redoStack)-1]
+		return cmd
+	}
+	m.redoStack = m.redoStack[:len(m.redoStack)-1]
+
+	if err := m.redo(&lastAction); err != nil {
+		// If it fails, keep it to retry
+		m.redoStack = append(m.redoStack, lastAction)
+		m.setError(err)
+		return nil
+	}
+	m.undoStack = append(m.undoStack, lastAction)
+	if lastAction.Type == "batch" {
+		m.setStatus("Redid %s", lastAction.Summary)
+	} else {
+		m.setStatus("Redid %s of %s", lastAction.Type, lastAction.Entry.Name)
+	}
+
+	m.reloadEntries()
+	return nil
+}
+
+// redo applies an undone action again, the inverse of undo. A batch that
+// fails partway keeps only the actions still to redo.
+func (m *FileManager) redo(action *UndoAction) error {
+	switch action.Type {
+	case "delete":
+		// Put the file back in the trash where undo took it from
+		if action.NewPath != "" {
+			if err := m.trash().record(action.NewPath, action.OldPath); err != nil {
+				return err
+			}
+			return moveFileOrDir(m.filesystem(), action.OldPath, action.NewPath)
+		}
+	case "cut":
+		// Put the file back in the clipboard, next to the others of a batch
+		if m.clipboardOp != "cut" {
+			m.clipboard = nil
+		}
+		m.clipboard = append(m.clipboard, action.Entry)
+		m.clipboardOp = "cut"
+		m.cutFrom = filepath.Dir(action.OldPath)
+		m.cutNavigated = false
+	case "copy", "create":
+		// Copy the file again from where it came from, or create it empty
+		if action.Archive != "" {
+			return m.reextract(action)
+		} else if action.OldPath != "" {
+			return copyPath(m.filesystem(), action.OldPath, action.NewPath)
+		} else if action.Type == "create" {
+			return createEmptyFile(m.filesystem(), action.NewPath)
+		}
+	case "move":
+		return moveFileOrDir(m.filesystem(), action.OldPath, action.NewPath)
+	case "rename":
+		return m.filesystem().Rename(action.OldPath, action.NewPath)
+	case "mkdir":
+		return m.filesystem().MkdirAll(action.NewPath, 0755)
+	case "batch":
+		for i := range action.Batch {
+			if err := m.redo(&action.Batch[i]); err != nil {
+				action.Batch = action.Batch[i:]
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// undo reverses a single action. A batch that fails partway keeps only the
+// actions still to undo, so retrying doesn't repeat the others.
+func (m *FileManager) undo(action *UndoAction) error {
+	switch action.Type {
+	case "delete":
+		// Restore file from trash to original location
+		if action.NewPath != "" {
+			return m.moveBack(action)
+		}
+	case "cut":
+		// Restore file in visual list (cancel the cut)
+		// Reinsert file in original position
+		m.clipboard = nil
+		m.clipboardOp = ""
+	case "copy", "create", "extract":
+		// Take away the file that was copied or created, or what was extracted
+		if action.NewPath != "" {
+			return m.removeCreated(action.NewPath)
+		}
+	case "move", "rename":
+		// Undo a movement (cut+paste) or a rename
+		return m.moveBack(action)
+	case "mkdir":
+		// Remove the created directory, unless something else was put in it
+		if entries, err := m.filesystem().ReadDir(action.NewPath); err == nil && len(entries) == 0 {
+			return m.filesystem().RemoveAll(action.NewPath)
+		}
+	case "batch":
+		for i := len(action.Batch) - 1; i >= 0; i-- {
+			if err := m.undo(&action.Batch[i]); err != nil {
+				action.Batch = action.Batch[:i+1]
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeCreated takes away what an action created at path. Anything but an
+// empty file goes to the trash, so what was written to it since isn't lost.
+func (m *FileManager) removeCreated(path string) error {
+	fsys := m.filesystem()
+	info, err := fsys.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() && info.Size() == 0 {
+		return fsys.RemoveAll(path)
+	}
+	_, err = m.moveToTrash(path)
+	return err
+}
+
+// promptMoveIntoNewDir asks for a directory name to move the selected
+// entries into
+func (m *FileManager) promptMoveIntoNewDir() {
+	entries := m.selectedEntries()
+	if len(entries) == 0 {
+		return
+	}
+	m.prompt = &textPrompt{
+		label: "Move into new directory",
+		submit: func(name string) tea.Cmd {
+			m.moveIntoNewDir(name, entries)
+			return nil
+		},
+	}
+}
+
+// moveIntoNewDir creates a directory in the current one and moves entries
+// into it. The whole operation is undone in one step.
+func (m *FileManager) moveIntoNewDir(name string, entries []FileEntry) {
+	if name == "" || strings.ContainsRune(name, filepath.Separator) {
+		m.setError(fmt.Errorf("invalid directory name %q", name))
+		return
+	}
+
+	fsys := m.filesystem()
+	dir := uniquePath(fsys, filepath.Join(m.CurrentPath, name))
+	m.runHook("pre_create", dir, "")
+	if err := fsys.MkdirAll(dir, 0755); err != nil {
+		m.setError(err)
+		return
+	}
+	m.runHook("post_create", dir, "")
+	batch := UndoAction{
+		Type:  "batch",
+		Entry: FileEntry{Name: filepath.Base(dir), Path: dir, IsDir: true},
+		Batch: []UndoAction{{Type: "mkdir", NewPath: dir}},
+	}
+
+	var moveErr error
+	for _, entry := range entries {
+		dest := filepath.Join(dir, entry.Name)
+		m.runHook("pre_move", dest, entry.Path)
+		if err := moveFileOrDir(fsys, entry.Path, dest); err != nil {
+			moveErr = err
+			break
+		}
+		m.runHook("post_move", dest, entry.Path)
+		m.moveMarks(entry.Path, dest)
+		batch.Batch = append(batch.Batch, UndoAction{Type: "move", OldPath: entry.Path, NewPath: dest, Entry: entry})
+	}
+	// Even a partial move is undone as one step
+	batch.Summary = fmt.Sprintf("moving %s into %s", pluralize(len(batch.Batch)-1, "item"), batch.Entry.Name)
+	m.pushUndo(batch)
+
+	m.reloadEntries()
+	m.selectByName(filepath.Base(dir))
+	if moveErr != nil {
+		m.setError(moveErr)
+		return
+	}
+	m.setStatus("Moved %s into %s", pluralize(len(entries), "item"), filepath.Base(dir))
+}
+
+// trash returns the trash deleted entries are moved to
+func (m *FileManager) trash() Trash {
+	return Trash{Dir: m.trashDir, FS: m.filesystem()}
+}
+
+// Custom message carrying the size of the trash for the prompt to empty it;
+// fill puts it in the prompt
+type trashCountedMsg struct {
+	fill func()
+}
+
+// Custom message sent when the trash has been emptied in the background
+type trashEmptiedMsg struct {
+	emptied int
+	err     error
+}
+
+// confirmEmptyTrash asks before permanently deleting everything in the
+// trash. The prompt shows right away; the size is filled in once the trash
+// has been walked in the background.
+func (m *FileManager) confirmEmptyTrash() tea.Cmd {
+	if m.emptyingTrash {
+		m.setStatus("Already emptying the trash")
+		return nil
+	}
+	var items []TrashItem
+	if m.trashDir != "" {
+		var err error
+		if items, err = m.trash().Items(); err != nil {
+			m.setError(fmt.Errorf("reading the trash: %w", err))
+			return nil
+		}
+	}
+	if len(items) == 0 {
+		m.setStatus("Trash is empty")
+		return nil
+	}
+
+	confirm := &confirmation{
+		actions: map[string]func() tea.Cmd{
+			"y": func() tea.Cmd { return m.emptyTrash() },
+			"n": func() tea.Cmd { return nil },
+		},
+	}
+	prompt := func(size string) {
+		confirm.prompt = fmt.Sprintf("Permanently delete %s (%s) from trash? [y]es  [n]o", pluralize(len(items), "item"), size)
+	}
+	prompt("counting…")
+	m.confirm = confirm
+
+	m.startOp()
+	dir := m.trashDir
+	fsys := m.filesystem()
+	return func() tea.Msg {
+		size, _, _ := dirSize(context.Background(), fsys, dir)
+		return trashCountedMsg{fill: func() { prompt(HumanSize(size)) }}
+	}
+}
+
+// handleTrashCounted fills in the size of the trash to empty
+func (m *FileManager) handleTrashCounted(msg trashCountedMsg) {
+	m.finishOp()
+	msg.fill()
+}
+
+// emptyTrash permanently deletes the trash contents in the background
+func (m *FileManager) emptyTrash() tea.Cmd {
+	m.emptyingTrash = true
+	m.startOp()
+	m.setStatus("Emptying the trash")
+	trash := m.trash()
+	return func() tea.Msg {
+		emptied, err := trash.Empty()
+		return trashEmptiedMsg{emptied: emptied, err: err}
+	}
+}
+
+// handleTrashEmptied reports how emptying the trash went and drops the undo
+// entries of the deletes that pointed into it
+func (m *FileManager) handleTrashEmptied(msg trashEmptiedMsg) {
+	m.emptyingTrash = false
+	m.finishOp()
+
+	// Deletes whose entry is gone from the trash can't be undone anymore;
+	// those made while it was being emptied still can
+	fsys := m.filesystem()
+	kept := m.undoStack[:0]
+	for _, action := range m.undoStack {
+		if action.Type == "delete" && strings.HasPrefix(action.NewPath, m.trashDir+string(filepath.Separator)) {
+			if _, err := fsys.Stat(action.NewPath); os.IsNotExist(err) {
+				continue
+			}
+		}
+		kept = append(kept, action)
+	}
+	m.undoStack = kept
+
+	if msg.err != nil {
+		m.setError(msg.err)
+		return
+	}
+	m.setStatus("Emptied trash (%s)", pluralize(msg.emptied, "item"))
+}
+
+// cleanupTrash removes the temporary trash directory, or from a kept trash
+// only the entries older than the retention period
+func (m *FileManager) cleanupTrash() {
+	if m.trashDir == "" {
+		return
+	}
+	if !m.keepTrash {
+		removeTempTrash(m.trashDir)
+	} else if m.trashRetention > 0 {
+		m.trash().Prune(m.trashRetention)
+	}
+}
+
+// searchFiles ranks entries against the query and jumps to the best match
+func (m *FileManager) searchFiles(query string) {
+	if query == "" {
+		return
+	}
+
+	names := make([]string, len(m.Entries))
+	for i, entry := range m.Entries {
+		names[i] = entry.Name
+	}
+
+	m.searchHits = nil
+	m.searchIndex = 0
+	for _, i := range rankMatches(query, names, !m.substring) {
+		m.searchHits = append(m.searchHits, names[i])
+	}
+
+	if len(m.searchHits) == 0 {
+		m.setStatus("No match for %q", query)
+		return
+	}
+	m.selectByName(m.searchHits[0])
+	if len(m.searchHits) > 1 {
+		m.setStatus("Match 1 of %d", len(m.searchHits))
+	}
+}
+
+// nextSearchHit moves to the next (or previous) match of the last search
+func (m *FileManager) nextSearchHit(step int) {
+	if len(m.searchHits) == 0 {
+		return
+	}
+	m.searchIndex = (m.searchIndex + step + len(m.searchHits)) % len(m.searchHits)
+	if !m.selectByName(m.searchHits[m.searchIndex]) {
+		m.setStatus("%s is no longer listed", m.searchHits[m.searchIndex])
+		return
+	}
+	m.setStatus("Match %d of %d", m.searchIndex+1, len(m.searchHits))
+}
+
+// selectByName moves the cursor to the entry with the given name
+func (m *FileManager) selectByName(name string) bool {
+	for i, entry := range m.Entries {
+		if entry.Name == name {
+			m.Cursor = i
+			return true
+		}
+	}
+	return false
+}
+
+func (m *FileManager) renameFile(newName string) {
+	if newName == "" || len(m.Entries) == 0 || m.Cursor >= len(m.Entries) {
+		return
+	}
+
+	entry := m.Entries[m.Cursor]
+	newPath := filepath.Join(m.CurrentPath, newName)
+
+	// Only rename if the name is different
+	if newName == entry.Name {
+		return
+	}
+
+	// os.Rename silently replaces an existing file, so check first.
+	// A case-only rename on a case-insensitive filesystem finds the entry itself.
+	if target, err := m.filesystem().Stat(newPath); err == nil {
+		if source, err := m.filesystem().Stat(entry.Path); err == nil && os.SameFile(source, target) {
+			m.renameEntry(entry, newPath)
+			return
+		}
+		switch m.renameOverwrite {
+		case renameRefuse:
+			m.setError(fmt.Errorf("%s already exists", newName))
+			return
+		case renameSuffix:
+			m.renameEntry(entry, uniquePath(m.filesystem(), newPath))
+			return
+		}
+		m.confirm = &confirmation{
+			prompt: fmt.Sprintf("%s already exists — [o]verwrite  [c]ancel", newName),
+			actions: map[string]func() tea.Cmd{
+				"o": func() tea.Cmd {
+					m.overwriteEntry(entry, newPath)
+					return nil
+				},
+				"c": func() tea.Cmd { return nil },
+			},
+		}
+		return
+	}
+
+	m.renameEntry(entry, newPath)
+}
+
+// How long typing must pause before the rename target is checked
+const renameCheckDelay = 150 * time.Millisecond
+
+// Custom message to check whether a typed rename target exists
+type renameCheckMsg struct {
+	text string // Rename text when the check was scheduled
+}
+
+// scheduleRenameCheck checks the rename target once typing pauses
+func (m *FileManager) scheduleRenameCheck() tea.Cmd {
+	m.renameExists = false
+	text := m.renameText
+	return tea.Tick(renameCheckDelay, func(time.Time) tea.Msg {
+		return renameCheckMsg{text: text}
+	})
+}
+
+// handleRenameCheck flags the rename text if it names another existing
+// entry. Checks for text that has been typed over since are dropped.
+func (m *FileManager) handleRenameCheck(msg renameCheckMsg) {
+	if !m.renameMode || msg.text != m.renameText || msg.text == "" {
+		return
+	}
+	if len(m.Entries) == 0 || m.Cursor >= len(m.Entries) {
+		return
+	}
+
+	// The entry being renamed doesn't count, even under another case
+	target, err := m.filesystem().Stat(filepath.Join(m.CurrentPath, msg.text))
+	if err != nil {
+		return
+	}
+	source, err := m.filesystem().Stat(m.Entries[m.Cursor].Path)
+	m.renameExists = err != nil || !os.SameFile(source, target)
+}
+
+// overwriteEntry renames entry onto an existing path, trashing what was there
+// so undo can bring it back
+func (m *FileManager) overwriteEntry(entry FileEntry, newPath string) {
+	trashPath, err := m.moveToTrash(newPath)
+	if err != nil {
+		m.setError(err)
+		return
+	}
+	m.pushUndo(UndoAction{
+		Type:    "delete",
+		OldPath: newPath,
+		NewPath: trashPath,
+		Entry:   FileEntry{Name: filepath.Base(newPath), Path: newPath},
+	})
+	m.renameEntry(entry, newPath)
+}
+
+// renameEntry renames entry to newPath and selects it under its new name
+func (m *FileManager) renameEntry(entry FileEntry, newPath string) {
+	newName := filepath.Base(newPath)
+	if err := m.renamePath(entry, newPath); err != nil {
+		m.setError(err)
+		return
+	}
+	m.setStatus("Renamed %s to %s", entry.Name, newName)
+
+	// Reload list to maintain sorting
+	m.reloadEntries()
+
+	// Find new position of renamed file
+	for i, e := range m.Entries {
+		if e.Name == newName {
+			m.Cursor = i
+			break
+		}
+	}
+}
+
+// renamePath renames entry to newPath with its hooks, moving its marks
+// along, and records the undo
+func (m *FileManager) renamePath(entry FileEntry, newPath string) error {
+	m.runHook("pre_rename", newPath, entry.Path)
+	if err := m.filesystem().Rename(entry.Path, newPath); err != nil {
+		return err
+	}
+	m.moveMarks(entry.Path, newPath)
+	m.runHook("post_rename", newPath, entry.Path)
+
+	// Add to undo stack
+	m.pushUndo(UndoAction{
+		Type:    "rename",
+		OldPath: entry.Path,
+		NewPath: newPath,
+		Entry:   entry,
+		OldName: entry.Name,
+	})
+	return nil
+}
+
+func (m *FileManager) navigateWithZoxide(query string) {
+	if query == "" {
+		return
+	}
+
+	// Execute zoxide command to find directory
+	cmd := exec.Command("zoxide", "query", query)
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// zoxide exits with an error when nothing matches
+		m.setError(fmt.Errorf("zoxide found no directory for %q", query))
+		return
+	} else if err != nil {
+		m.setError(fmt.Errorf("running zoxide: %w", err))
+		return
+	}
+
+	targetPath := strings.TrimSpace(string(output))
+	if targetPath == "" {
+		m.setError(fmt.Errorf("zoxide found no directory for %q", query))
+		return
+	}
+
+	// Check if directory exists
+	if info, err := os.Stat(targetPath); err != nil || !info.IsDir() {
+		m.setError(fmt.Errorf("%s from zoxide no longer exists", targetPath))
+		return
+	}
+	m.openDir(targetPath, "")
+}
+
+// openTerminal opens a terminal in current directory and suspends the TUI
+func (m *FileManager) openTerminal() tea.Cmd {
+	// Determine which shell to use
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		switch runtime.GOOS {
+		case "windows":
+			shell = "cmd"
+		default:
+			shell = "/bin/bash"
+		}
+	}
+
+	// Create terminal command
+	cmd := exec.Command(shell)
+	cmd.Dir = m.CurrentPath
+
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		// Callback executed after shell terminates
+		// Reload current directory (there may have been changes)
+		return reloadDirectoryMsg{}
+	})
+}
+
+// Custom message to reload directory
+type reloadDirectoryMsg struct {
+	err error // Error from the process we returned from, if any
+}
+
+// openInEditor opens the selected directory, or the current one when a file
+// is selected, in the editor and suspends the TUI
+func (m *FileManager) openInEditor() tea.Cmd {
+	dir := m.CurrentPath
+	if len(m.Entries) > 0 && m.Cursor < len(m.Entries) && m.Entries[m.Cursor].IsDir {
+		dir = m.Entries[m.Cursor].Path
+	}
+
+	// The editor may carry arguments, e.g. "code -w"
+	args := strings.Fields(m.editor)
+	if len(args) == 0 {
+		m.setError(fmt.Errorf("no editor configured: set $EDITOR or editor in the config"))
+		return nil
+	}
+	cmd := exec.Command(args[0], append(args[1:], dir)...)
+	cmd.Dir = dir
+
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return reloadDirectoryMsg{err: err}
+	})
+}
+
+// renderWhichKey renders the shortcuts screen
+func (m *FileManager) renderWhichKey() string {
+	if !m.showWhichKey {
+		return ""
+	}
+
+	// Determine which set of shortcuts to show
+	var currentShortcuts []shortcut
+	if m.searchMode {
+		currentShortcuts = shortcuts["search"]
+	} else if m.renameMode {
+		currentShortcuts = shortcuts["rename"]
+	} else if m.zoxideMode {
+		currentShortcuts = shortcuts["zoxide"]
+	} else if m.relBaseMode {
+		currentShortcuts = shortcuts["relbase"]
+	} else if m.prompt != nil {
+		currentShortcuts = shortcuts["prompt"]
+	} else if m.parentMode {
+		currentShortcuts = shortcuts["parent"]
+	} else if m.previewLines != nil {
+		currentShortcuts = shortcuts["lines"]
+	} else if m.quickLook != nil {
+		currentShortcuts = shortcuts["quicklook"]
+	} else if m.reorderMode {
+		currentShortcuts = shortcuts["reorder"]
+	} else if m.templates != nil {
+		currentShortcuts = shortcuts["templates"]
+	} else if m.recentPicks != nil {
+		currentShortcuts = shortcuts["recent"]
+	} else if m.finder != nil {
+		currentShortcuts = shortcuts["finder"]
+	} else if m.trashItems != nil {
+		currentShortcuts = shortcuts["trash"]
+	} else if m.openWithMenu != nil {
+		currentShortcuts = shortcuts["openwith"]
+	} else if m.showSortMenu {
+		currentShortcuts = shortcuts["sort"]
+	} else {
+		currentShortcuts = shortcuts["normal"]
+	}
+
+	// Prepare data for table
+	rows := make([]table.Row, 0, len(currentShortcuts))
+	for _, s := range currentShortcuts {
+		rows = append(rows, table.Row{s.key, s.description})
+	}
+
+	// Configure table
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "", Width: 10},
+			{Title: "", Width: 20},
+		}),
+		table.WithRows(rows),
+		table.WithFocused(false),
+		table.WithHeight(len(rows)),
+	)
+
+	// Style the table
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderBottom(false).
+		Bold(false).
+		Foreground(lipgloss.Color("234")).
+		Background(lipgloss.Color("252"))
+	s.Selected = s.Selected.
+		Foreground(lipgloss.Color("205")).
+		Background(lipgloss.Color("252")).
+		Bold(true)
+	s.Cell = s.Cell.
+		Foreground(lipgloss.Color("234")).
+		Background(lipgloss.Color("252"))
+
+	t.SetStyles(s)
+
+	// Render within which-key style
+	return whichKeyStyle.
+		Width(m.Width).
+		Render(t.View())
+}
+
+func (m *FileManager) handleDoubleCommand(cmd string) bool {
+	now := time.Now()
+	if cmd == m.lastCommand && now.Sub(m.commandTime) < 500*time.Millisecond {
+		m.lastCommand = ""
+		return true
+	}
+	m.lastCommand = cmd
+	m.commandTime = now
+	return false
+}
+
+// cyclePathDisplay switches between names, relative and absolute paths
+func (m *FileManager) cyclePathDisplay() {
+	m.pathDisplay = (m.pathDisplay + 1) % pathDisplayModes
+	switch m.pathDisplay {
+	case pathDisplayName:
+		m.setStatus("Showing names")
+	case pathDisplayRelative:
+		m.setStatus("Showing relative paths")
+	case pathDisplayAbsolute:
+		m.setStatus("Showing absolute paths")
+	}
+}
+
+// displayName returns how an entry is named in the current column. Paths
+// longer than width are cut from the left, keeping the name visible.
+func (m *FileManager) displayName(entry FileEntry, width int) string {
+	switch m.pathDisplay {
+	case pathDisplayRelative:
+		rel, err := filepath.Rel(m.CurrentPath, entry.Path)
+		if err != nil {
+			rel = entry.Path
+		}
+		return truncateLeft(rel, width)
+	case pathDisplayAbsolute:
+		return truncateLeft(entry.Path, width)
+	}
+	return truncateRight(entry.Name, width, "…")
+}
+
+// Width of the size column, enough for HumanSize's longest, e.g. 1023.9K
+const sizeColumnWidth = 7
+
+// entrySize returns what the size column shows for an entry: a file's
+// size, or a directory's item count once it has been counted ("-" until then)
+func (m *FileManager) entrySize(entry *FileEntry) string {
+	if entry.IsDir {
+		if count, ok := m.dirCounts[entry.Path]; ok && count >= 0 {
+			return fmt.Sprint(count)
+		}
+		// Not counted yet, or unreadable
+		return "-"
+	}
+	info, err := entry.Info()
+	if err != nil {
+		return "-"
+	}
+	return HumanSize(info.Size())
+}
+
+// setStatus shows a short confirmation in the status bar
+func (m *FileManager) setStatus(format string, args ...any) {
+	m.statusMsg = fmt.Sprintf(format, args...)
+	m.statusIsError = false
+	m.statusSeq++
+}
+
+// setError shows an error in the status bar until the next key
+func (m *FileManager) setError(err error) {
+	m.statusMsg = err.Error()
+	m.statusIsError = true
+	m.statusSeq++
+}
+
+// Custom message to clear a confirmation from the status bar
+type clearStatusMsg struct{ seq int }
+
+// clearStatusAfter clears the status message with the given sequence once it times out
+func clearStatusAfter(seq int) tea.Cmd {
+	return tea.Tick(statusTimeout, func(time.Time) tea.Msg {
+		return clearStatusMsg{seq: seq}
+	})
+}
+
+func (m *FileManager) View() string {
+	// Nothing to draw until the first WindowSizeMsg arrives
+	if m.Width == 0 && m.Height == 0 {
+		return ""
+	}
+	if m.Width < minWidth || m.Height < minHeight {
+		return fmt.Sprintf("Terminal too small\nNeed %dx%d, have %dx%d", minWidth, minHeight, m.Width, m.Height)
+	}
+
+	// 1. Height calculations - which-key doesn't affect main layout
+	headerHeight := 1 // Path height
+	availableHeight := m.listHeight()
+
+	// 2. Width calculations
+	contentWidth := max(m.Width-4, 0)
+	leftColWidth := contentWidth * 20 / 100  // 20% for left column
+	mainColWidth := contentWidth * 30 / 100  // 30% for center column
+	rightColWidth := contentWidth * 50 / 100 // 50% for right column
+	if m.minimal {
+		// Only the current column, at full width
+		mainColWidth = contentWidth
+	}
+
+	// 3. Calculate number of visible items
+	visibleCount := availableHeight // Use all available height
+
+	// 4. Build layout using strings.Builder
+	var view strings.Builder
+
+	// 5. Add header
+	headerStyle := pathStyle.
+		Width(m.Width).
+		MarginBottom(1)
+	// Keep the path to one line, dropping its start rather than wrapping
+	header := m.CurrentPath
+	if m.loadingDir != "" {
+		header = m.loadingDir
+	}
+	view.WriteString(headerStyle.Render(truncateLeft(header, m.Width-2)))
+
+	// 6. Render current column
+	var currentCol strings.Builder
+	if m.loadingDir != "" {
+		currentCol.WriteString(loadingMsg)
+	} else if m.searchMode && m.searchQuery != "" {
+		// Only what matches the search so far is listed
+		currentCol.WriteString(m.renderFiltered(m.nameWidth(mainColWidth), visibleCount))
+	} else if len(m.Entries) == 0 {
+		currentCol.WriteString(lipgloss.JoinVertical(lipgloss.Left,
+			emptyDirMsg,
+			"",
+			emptyStateStyle.Render("Use h to go back to parent directory"),
+		))
+	} else {
+		startIdx := m.listWindow(visibleCount)
+		endIdx := min(len(m.Entries), startIdx+visibleCount)
+
+		nameWidth := m.nameWidth(mainColWidth)
+		if m.showSizes {
+			// Leave room for the size column and a space before it
+			nameWidth -= sizeColumnWidth + 1
+		}
+
+		for i := startIdx; i < endIdx; i++ {
+			entry := m.Entries[i]
+			line := m.displayName(entry, nameWidth)
+			if m.isCut(entry.Path) {
+				// Still listed until pasted, but marked as going away
+				if entry.IsDir {
+					line += "/"
+				}
+				line = cutStyle.Render(line)
+			} else if entry.Selected {
+				if entry.IsDir {
+					line += "/"
+				}
+				line = markedStyle.Render(line)
+			} else if entry.IsSymlink {
+				line = renderLink(entry, line, nameWidth)
+			} else if entry.IsDir {
+				style := dirStyle
+				if gitStyle, ok := m.gitStyle(entry); ok {
+					style = gitStyle
+				}
+				line = style.Render(line + "/")
+				if count, ok := m.dirCounts[entry.Path]; ok && m.showDirCounts && !m.showSizes && count >= 0 {
+					line += countStyle.Render(fmt.Sprintf(" (%d)", count))
+				}
+			} else if style, ok := m.gitStyle(entry); ok {
+				line = style.Render(line)
+			}
+			if m.showSizes {
+				// Pad to the width of the name and its "/"
+				line += strings.Repeat(" ", max(nameWidth+1-lipgloss.Width(line), 0))
+				line += " " + countStyle.Render(fmt.Sprintf("%*s", sizeColumnWidth, m.entrySize(&m.Entries[i])))
+			}
+			line = iconFor(m.icons, entry) + line
+			if i == m.Cursor {
+				line = selectedStyle.Render("> " + line)
+			} else if entry.Selected {
+				line = markedStyle.Render("* ") + line
+			} else {
+				line = "  " + line
+			}
+			currentCol.WriteString(line + "\n")
+		}
+	}
+
+	// 7. Combine columns with limited height
+	var columns string
+	if m.quickLook != nil {
+		// The quick look takes the place of all columns
+		columns = columnStyle.Width(contentWidth).Render(m.renderQuickLook(contentWidth, availableHeight))
+	} else if m.minimal && m.parentMode {
+		// The parent column takes the current one's place while it has focus
+		columns = m.renderParentColumn(mainColWidth)
+	} else if m.minimal {
+		columns = columnStyle.Width(mainColWidth).Render(currentCol.String())
+	} else {
+		columns = lipgloss.JoinHorizontal(
+			lipgloss.Left,
+			m.renderParentColumn(leftColWidth),
+			columnStyle.Width(mainColWidth).Render(currentCol.String()),
+			m.renderPreviewColumn(rightColWidth),
+		)
+	}
+
+	// 8. Add main content with padding
+	mainStyle := lipgloss.NewStyle().
+		MaxHeight(availableHeight).
+		Height(availableHeight).
+		MarginTop(1)
+
+	view.WriteString(mainStyle.Render(columns))
+
+	// 9. Prepare status bar (always present)
+	var status string
+	if m.statusMsg != "" {
+		status = m.statusMsg
+	} else if m.deleting != nil {
+		status = fmt.Sprintf("Deleting %s: %s removed (esc to cancel)",
+			filepath.Base(m.deleting.path), pluralize(m.deleting.removed, "file"))
+	} else if m.extracting != nil {
+		status = fmt.Sprintf("Extracting %s: %s written",
+			filepath.Base(m.extracting.archive), pluralize(m.extracting.extracted, "file"))
+	} else if m.loadingDir != "" {
+		status = fmt.Sprintf("Loading %s (esc to cancel)", m.loadingDir)
+	} else if len(m.Entries) > 0 && m.Cursor < len(m.Entries) {
+		selected := m.Entries[m.Cursor]
+		status = getFileInfo(m.filesystem(), selected.Path, m.relativeTimes, m.dirCounts)
+		if size, ok := m.dirSizes[selected.Path]; ok && selected.IsDir {
+			status += "  " + HumanSize(size) + " in total"
+		}
+	} else {
+		status = noSelectionMsg
+	}
+
+	// 10. Render status bar, on one line after its padding, after the
+	// spinner while background operations run
+	if activity := m.activityIndicator(); activity != "" {
+		status = activity + "  " + status
+	}
+	// The sort in effect goes at the right, in the room the status leaves
+	status = truncateRight(status, m.Width-2, "…")
+	if room := m.Width - 2 - lipgloss.Width(status) - 2; room > 0 {
+		sortInfo := truncateRight("sort: "+m.sortLabel(), room, "…")
+		status += strings.Repeat(" ", m.Width-2-lipgloss.Width(status)-lipgloss.Width(sortInfo)) + sortInfo
+	}
+	view.WriteString("\n")
+	finalStatusStyle := statusStyle.Width(m.Width)
+	if m.statusIsError {
+		finalStatusStyle = errorStyle.Width(m.Width)
+	}
+	view.WriteString(finalStatusStyle.Render(status))
+
+	// 11. Prepare and render command/search/rename/zoxide line
+	view.WriteString("\n")
+	if m.searchMode {
+		// Search mode: show search bar
+		searchPrompt := fmt.Sprintf("Search: %s█", m.searchQuery)
+		finalSearchBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalSearchBarStyle.Render(searchPrompt))
+	} else if m.renameMode {
+		// Rename mode: show rename bar, warning when the name is taken
+		renamePrompt := "Rename: " + m.renderRenameText()
+		if m.renameExists && m.renameOverwrite == renameSuffix {
+			renamePrompt += "  " + warningStyle.Render("⚠ already exists, a suffix will be added")
+		} else if m.renameExists {
+			renamePrompt += "  " + warningStyle.Render("⚠ already exists")
+		}
+		finalRenameBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalRenameBarStyle.Render(renamePrompt))
+	} else if m.zoxideMode {
+		// Zoxide mode: show zoxide bar
+		zoxidePrompt := fmt.Sprintf("z %s█", m.zoxideQuery)
+		finalZoxideBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalZoxideBarStyle.Render(zoxidePrompt))
+	} else if m.relBaseMode {
+		// Relative copy: ask for the base directory
+		relBasePrompt := fmt.Sprintf("Relative to: %s█", m.relBaseText)
+		finalRelBaseBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalRelBaseBarStyle.Render(relBasePrompt))
+	} else if m.markMode == markSet {
+		// Waiting for the letter to mark the selected entry with
+		finalMarkBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalMarkBarStyle.Render("Mark: press a letter"))
+	} else if m.markMode == bookmarkSet {
+		// Waiting for the letter to bookmark the current directory as
+		finalBookmarkBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalBookmarkBarStyle.Render("Bookmark: press a letter"))
+	} else if m.parentMode {
+		// Parent focus: explain how to get back
+		finalParentBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalParentBarStyle.Render("Parent: enter to open, esc to return"))
+	} else if m.previewLines != nil {
+		// Line cursor in the preview: show where it is
+		first, last := m.previewLines.selection()
+		position := fmt.Sprintf("Line %d of %d", first+1, len(m.previewLines.lines))
+		if first != last {
+			position = fmt.Sprintf("Lines %d-%d of %d", first+1, last+1, len(m.previewLines.lines))
+		}
+		finalLinesBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalLinesBarStyle.Render(position + ": y to copy, v for a range, esc to return"))
+	} else if m.quickLook != nil {
+		// Quick look: show what's being looked at and how to leave
+		finalQuickLookBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalQuickLookBarStyle.Render("Quick look: j/k to scroll, ctrl+o to open externally, esc to return"))
+	} else if m.reorderMode {
+		// Reordering: explain the keys
+		finalReorderBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalReorderBarStyle.Render("Reorder: J/K to move the selected entry, esc when done"))
+	} else if m.prompt != nil {
+		// Text prompt: show its label and the text typed so far
+		finalPromptBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalPromptBarStyle.Render(fmt.Sprintf("%s: %s█", m.prompt.label, m.prompt.text)))
+	} else if m.confirm != nil {
+		// Confirmation: show the question and its options
+		finalConfirmBarStyle := searchBarStyle.Width(m.Width)
+		view.WriteString(finalConfirmBarStyle.Render(m.confirm.prompt))
+	} else {
+		// Normal mode: blank line, or the count being typed
+		pending := ""
+		if m.count > 0 {
+			pending = fmt.Sprint(m.count)
+		}
+		emptyCommandStyle := lipgloss.NewStyle().Width(m.Width)
+		view.WriteString(emptyCommandStyle.Render(pending))
+	}
+
+	// 12. If which-key, the paste queue, a picker or the sort menu is shown, overlay it on the content area
+	if m.showWhichKey {
+		return overlayBottom(view.String(), m.renderWhichKey(), headerHeight)
+	}
+	if m.showQueue {
+		return overlayBottom(view.String(), m.renderQueue(), headerHeight)
+	}
+	if m.templates != nil {
+		return overlayBottom(view.String(), m.renderTemplates(), headerHeight)
+	}
+	if m.recentPicks != nil {
+		return overlayBottom(view.String(), m.renderRecent(), headerHeight)
+	}
+	if m.finder != nil {
+		return overlayBottom(view.String(), m.renderFinder(), headerHeight)
+	}
+	if m.trashItems != nil {
+		return overlayBottom(view.String(), m.renderTrash(), headerHeight)
+	}
+	if m.markMode == markJump {
+		return overlayBottom(view.String(), m.renderMarks(), headerHeight)
+	}
+	if m.markMode == bookmarkJump {
+		return overlayBottom(view.String(), m.renderBookmarks(), headerHeight)
+	}
+	if m.openWithMenu != nil {
+		return overlayBottom(view.String(), m.renderOpenWith(), headerHeight)
+	}
+	if m.showSortMenu {
+		return overlayBottom(view.String(), m.renderSortMenu(), headerHeight)
+	}
+
+	return view.String()
+}
+
+// listWindow returns the first entry to show in the current column. By
+// default the cursor is kept centered; with scroll_off the window only moves
+// when the cursor comes within that many lines of its edge. Either way the
+// last entries fill the column rather than leaving blank lines below.
+func (m *FileManager) listWindow(visible int) int {
+	if !m.marginScroll {
+		return max(0, min(m.Cursor-visible/2, len(m.Entries)-visible))
+	}
+
+	margin := min(m.scrollOff, (visible-1)/2)
+	top := m.scrollTop
+	if m.Cursor-margin < top {
+		top = m.Cursor - margin
+	}
+	if m.Cursor+margin >= top+visible {
+		top = m.Cursor + margin - visible + 1
+	}
+	return max(0, min(top, len(m.Entries)-visible))
+}
+
+// overlayBottom replaces the lines just above the two bottom bars with
+// content, so the overlay doesn't add height
+func overlayBottom(baseView, content string, headerHeight int) string {
+	baseLines := strings.Split(baseView, "\n")
+	overlayLines := strings.Split(content, "\n")
+
+	// Calculate where to insert the overlay (above the two bottom bars)
+	bottomBarsCount := 2 // status bar + command line
+	insertPos := len(baseLines) - bottomBarsCount - len(overlayLines)
+	if insertPos < headerHeight+1 {
+		insertPos = headerHeight + 1
+	}
+
+	// Replace lines at calculated position
+	for i, line := range overlayLines {
+		if insertPos+i < len(baseLines)-bottomBarsCount {
+			baseLines[insertPos+i] = line
+		}
+	}
+
+	return strings.Join(baseLines, "\n")
+}
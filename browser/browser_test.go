@@ -0,0 +1,2371 @@
+package browser
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// send passes msg to m and finishes the directory load it starts, if any,
+// as the program would once the read completes. The listings shown beside
+// the directory are read too.
+func send(m *FileManager, msg tea.Msg) {
+	_, cmd := m.Update(msg)
+	if m.loadingDir != "" && cmd != nil {
+		m.Update(awaitLoad(cmd))
+	}
+	loadListings(m)
+}
+
+// awaitLoad runs cmd and returns the message of the directory or archive
+// it loads, leaving out the other commands batched with it
+func awaitLoad(cmd tea.Cmd) tea.Msg {
+	loaded := make(chan tea.Msg, 1)
+	var run func(tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			for _, cmd := range msg {
+				if cmd != nil {
+					go run(cmd)
+				}
+			}
+		case dirLoadedMsg:
+			if !msg.listing {
+				loaded <- msg
+			}
+		case archiveOpenedMsg:
+			loaded <- msg
+		}
+	}
+	go run(cmd)
+	return <-loaded
+}
+
+// awaitMsg runs cmd, and the commands it batches, and returns the first
+// message of type T they produce
+func awaitMsg[T tea.Msg](cmd tea.Cmd) T {
+	found := make(chan T, 1)
+	var run func(tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			for _, cmd := range msg {
+				if cmd != nil {
+					go run(cmd)
+				}
+			}
+		case T:
+			found <- msg
+		}
+	}
+	go run(cmd)
+	return <-found
+}
+
+// paste pastes the clipboard, waiting for a copy to be found to fit
+func paste(m *FileManager) {
+	runPaste(m, m.pasteFile())
+}
+
+// runPaste runs the space check cmd starts and the paste it goes on with
+func runPaste(m *FileManager, cmd tea.Cmd) {
+	for cmd != nil {
+		msg, ok := cmd().(spaceCheckedMsg)
+		if !ok {
+			return
+		}
+		cmd = m.handleSpaceChecked(msg)
+	}
+}
+
+// loadListings reads the listings shown beside the current directory, as
+// the program does in the background after each message
+func loadListings(m *FileManager) {
+	// Reads Update started for the test to drop are started again
+	m.listingsLoading = nil
+	cmd := m.startListings()
+	if cmd == nil {
+		return
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		for _, cmd := range batch {
+			m.handleDirLoaded(cmd().(dirLoadedMsg))
+		}
+		return
+	}
+	m.handleDirLoaded(msg.(dirLoadedMsg))
+}
+
+// mustReadDirectory lists a directory, failing the test if it can't
+func mustReadDirectory(tb testing.TB, path string, opts ListOptions) []FileEntry {
+	tb.Helper()
+	entries, err := ReadDirectory(path, opts)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return entries
+}
+
+func TestDeleteFileAcrossFilesystems(t *testing.T) {
+	dir := t.TempDir()
+	trash := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate the trash living on another mount
+	m := &FileManager{CurrentPath: dir, Entries: mustReadDirectory(t, dir, ListOptions{}), trashDir: trash, fs: exdevFS{OS}}
+	m.deleteFile()
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("source still exists after delete: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(trash, "notes.txt"))
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("file not copied into trash: %q, %v", content, err)
+	}
+	if len(m.undoStack) != 1 || m.undoStack[0].Type != "delete" {
+		t.Fatalf("expected a delete undo action, got %+v", m.undoStack)
+	}
+
+	m.undoLastAction()
+	if content, err := os.ReadFile(path); err != nil || string(content) != "hello" {
+		t.Fatalf("undo did not restore file: %q, %v", content, err)
+	}
+}
+
+func TestViewSmallTerminal(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("some text\nmore text"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sizes := []struct {
+		width, height int
+		tooSmall      bool
+	}{
+		{1, 1, true},
+		{10, 3, true},
+		{minWidth - 1, 40, true},
+		{120, minHeight - 1, true},
+		{minWidth, minHeight, false},
+		{120, 40, false},
+	}
+
+	for _, size := range sizes {
+		m := &FileManager{CurrentPath: dir, Entries: mustReadDirectory(t, dir, ListOptions{}), Width: size.width, Height: size.height}
+		view := m.View()
+		if got := strings.Contains(view, "Terminal too small"); got != size.tooSmall {
+			t.Errorf("%dx%d: too small message shown = %v, want %v", size.width, size.height, got, size.tooSmall)
+		}
+	}
+}
+
+func TestMinimalView(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "project")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "inner.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "sibling"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	newManager := func(minimal bool) *FileManager {
+		m := &FileManager{CurrentPath: dir, Width: 100, Height: 20, autoPreview: true, minimal: minimal}
+		m.Entries, _ = m.readDirectory(dir)
+		m.selectByName("sub")
+		loadListings(m)
+		return m
+	}
+
+	// Normally the parent and the selected directory are shown beside it
+	if view := newManager(false).View(); !strings.Contains(view, "sibling") || !strings.Contains(view, "inner.txt") {
+		t.Fatalf("expected the parent and preview columns:\n%s", view)
+	}
+
+	// Minimal shows only the current column, without reading the preview
+	m := newManager(true)
+	if view := m.View(); strings.Contains(view, "sibling") || strings.Contains(view, "inner.txt") {
+		t.Fatalf("expected only the current column:\n%s", view)
+	}
+	if _, ok := m.listings[filepath.Join(dir, "sub")]; ok {
+		t.Error("expected the hidden preview not read")
+	}
+	if !strings.Contains(m.View(), "sub") {
+		t.Errorf("expected the current column shown:\n%s", m.View())
+	}
+
+	// The parent column takes its place while it has focus
+	send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("H")})
+	if !m.parentMode || !strings.Contains(m.View(), "sibling") {
+		t.Errorf("expected the parent column in focus:\n%s", m.View())
+	}
+}
+
+func TestRefreshAfterCurrentDirectoryRemoved(t *testing.T) {
+	root := t.TempDir()
+	current := filepath.Join(root, "a", "b", "c")
+	if err := os.MkdirAll(current, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &FileManager{CurrentPath: current, Entries: mustReadDirectory(t, current, ListOptions{})}
+	if err := os.RemoveAll(filepath.Join(root, "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Update(reloadDirectoryMsg{})
+
+	want := filepath.Join(root, "a")
+	if m.CurrentPath != want {
+		t.Fatalf("CurrentPath = %q, want nearest existing ancestor %q", m.CurrentPath, want)
+	}
+	if m.Cursor != 0 || len(m.Entries) != 0 {
+		t.Fatalf("expected an empty listing with the cursor reset, got %d entries, cursor %d", len(m.Entries), m.Cursor)
+	}
+	if !strings.Contains(m.statusMsg, "no longer exists") {
+		t.Fatalf("expected a status message about the move, got %q", m.statusMsg)
+	}
+}
+
+func TestRenameOntoExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "a", "b.txt": "b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newModel := func(policy string) *FileManager {
+		m := &FileManager{CurrentPath: dir, trashDir: t.TempDir(), renameOverwrite: policy}
+		m.Entries, _ = m.readDirectory(dir)
+		m.selectByName("a.txt")
+		return m
+	}
+	read := func(name string) string {
+		content, _ := os.ReadFile(filepath.Join(dir, name))
+		return string(content)
+	}
+
+	// Refusing never touches the target
+	m := newModel(renameRefuse)
+	m.renameFile("b.txt")
+	if !m.statusIsError || m.confirm != nil || read("b.txt") != "b" {
+		t.Fatalf("refuse policy should report an error and keep b.txt, got %q", m.statusMsg)
+	}
+
+	// Asking and cancelling leaves both files alone
+	m = newModel(renameAsk)
+	m.renameFile("b.txt")
+	if m.confirm == nil {
+		t.Fatal("expected an overwrite confirmation")
+	}
+	m.confirm.actions["c"]()
+	if read("a.txt") != "a" || read("b.txt") != "b" {
+		t.Fatal("cancelled rename changed files")
+	}
+
+	// Overwriting replaces the target, and undo brings both back
+	m.renameFile("b.txt")
+	m.confirm.actions["o"]()
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) || read("b.txt") != "a" {
+		t.Fatalf("overwrite did not replace b.txt with a.txt")
+	}
+	m.undoLastAction()
+	m.undoLastAction()
+	if read("a.txt") != "a" || read("b.txt") != "b" {
+		t.Fatalf("undo did not restore both files: a=%q b=%q", read("a.txt"), read("b.txt"))
+	}
+
+	// Suffixing keeps both and says where the file went
+	m = newModel(renameSuffix)
+	m.renameFile("b.txt")
+	if read("b_1.txt") != "a" || read("b.txt") != "b" || m.statusMsg != "Renamed a.txt to b_1.txt" {
+		t.Fatalf("suffix policy should rename to b_1.txt, got status %q", m.statusMsg)
+	}
+}
+
+func TestRenameInput(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20}
+	m.Entries, _ = m.readDirectory(dir)
+	typeText := func(text string) {
+		for _, r := range text {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+	press := func(keys ...tea.KeyType) {
+		for _, key := range keys {
+			m.Update(tea.KeyMsg{Type: key})
+		}
+	}
+
+	// A file's stem is selected, so typing keeps the extension
+	m.Cursor = 1
+	typeText("a")
+	typeText("todo")
+	if m.renameText != "todo.txt" || m.renameCursor != 4 {
+		t.Fatalf("expected todo.txt with the caret after todo, got %q at %d", m.renameText, m.renameCursor)
+	}
+	press(tea.KeyHome)
+	typeText("my-")
+	press(tea.KeyEnd, tea.KeyBackspace, tea.KeyLeft, tea.KeyLeft, tea.KeyDelete)
+	if m.renameText != "my-todo.x" {
+		t.Fatalf("expected my-todo.x, got %q", m.renameText)
+	}
+	if !strings.Contains(m.View(), "Rename: my-todo.") {
+		t.Errorf("expected the text in the rename bar:\n%s", m.View())
+	}
+	press(tea.KeyEsc)
+
+	// Directories have no extension to keep
+	m.Cursor = 0
+	typeText("a")
+	typeText("2")
+	if m.renameText != "src2" {
+		t.Errorf("expected typing to append to a directory name, got %q", m.renameText)
+	}
+}
+
+func TestRenameWarnsOfExistingName(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20}
+	m.Entries, _ = m.readDirectory(dir)
+	typeText := func(text string) tea.Cmd {
+		var cmd tea.Cmd
+		for _, r := range text {
+			_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+		return cmd
+	}
+
+	// The check runs once typing pauses, on the text typed by then
+	m.selectByName("a.txt")
+	typeText("a")
+	check := typeText("b")
+	if m.renameText != "b.txt" || m.renameExists {
+		t.Fatalf("expected b.txt typed and not checked yet, got %q", m.renameText)
+	}
+	m.Update(awaitMsg[renameCheckMsg](check))
+	if !m.renameExists || !strings.Contains(m.View(), "already exists") {
+		t.Fatalf("expected a warning that b.txt exists:\n%s", m.View())
+	}
+
+	// Typing on clears it, and a check of older text is dropped
+	stale := renameCheckMsg{text: m.renameText}
+	check = typeText("c")
+	m.Update(stale)
+	if m.renameExists {
+		t.Error("expected the warning cleared while typing")
+	}
+	m.Update(awaitMsg[renameCheckMsg](check))
+	if m.renameExists {
+		t.Error("expected no warning for bc.txt")
+	}
+
+	// The entry's own name isn't taken
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	_, check = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if m.Update(awaitMsg[renameCheckMsg](check)); m.renameText != "a.txt" || m.renameExists {
+		t.Errorf("expected no warning for the entry's own name %q", m.renameText)
+	}
+}
+
+func TestBulkRename(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+
+	// Stands in for the editor saving the file with names
+	edited := func(names string) bulkRenameMsg {
+		file := filepath.Join(t.TempDir(), "names.txt")
+		if err := os.WriteFile(file, []byte(names), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return bulkRenameMsg{entries: slices.Clone(m.Entries), file: file}
+	}
+
+	m.handleBulkRename(edited("one.txt\nb.txt\n"))
+	if !m.statusIsError {
+		t.Fatalf("expected a line count mismatch to fail, got %q", m.statusMsg)
+	}
+	m.handleBulkRename(edited("one.txt\nc.txt\nthree.txt\n"))
+	if !m.statusIsError {
+		t.Fatalf("expected renaming onto an existing name to fail, got %q", m.statusMsg)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatalf("expected nothing renamed after a failed check: %v", err)
+	}
+
+	m.handleBulkRename(edited("one.txt\nb.txt\nthree.txt\n"))
+	names := func() []string {
+		var names []string
+		for _, entry := range mustReadDirectory(t, dir, ListOptions{}) {
+			names = append(names, entry.Name)
+		}
+		return names
+	}
+	if got := names(); !slices.Equal(got, []string{"b.txt", "one.txt", "three.txt"}) {
+		t.Fatalf("unexpected names after bulk rename: %v (%s)", got, m.statusMsg)
+	}
+	if len(m.undoStack) != 1 {
+		t.Fatalf("expected the renames undone in one step, got %+v", m.undoStack)
+	}
+	m.undoLastAction()
+	if got := names(); !slices.Equal(got, []string{"a.txt", "b.txt", "c.txt"}) {
+		t.Fatalf("unexpected names after undo: %v", got)
+	}
+
+	// A name with a trailing space stays unless its line is edited
+	if err := os.Rename(filepath.Join(dir, "c.txt"), filepath.Join(dir, "c.txt ")); err != nil {
+		t.Fatal(err)
+	}
+	m.Entries, _ = m.readDirectory(dir)
+	m.handleBulkRename(edited("a.txt\nb.txt\nc.txt \n"))
+	if m.statusMsg != "No names changed" {
+		t.Fatalf("expected nothing renamed, got %q", m.statusMsg)
+	}
+	m.handleBulkRename(edited("a.txt\nbee.txt  \nc.txt \n"))
+	if got := names(); !slices.Equal(got, []string{"a.txt", "bee.txt", "c.txt "}) {
+		t.Fatalf("expected only b.txt renamed, trimmed: %v (%s)", got, m.statusMsg)
+	}
+}
+
+func TestTypeAhead(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"alpha.txt", "bar.txt", "base.go", "zebra.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	typeText := func(text string) {
+		for _, r := range text {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+	selected := func() string { return m.Entries[m.Cursor].Name }
+
+	// b is bound to nothing, so it starts a name; the bound keys after it extend it
+	typeText("bas")
+	if selected() != "base.go" || m.renameMode {
+		t.Fatalf("expected base.go selected without renaming, got %s", selected())
+	}
+
+	// Once idle, the next name starts over and bound keys run again
+	m.typeAheadTime = time.Now().Add(-time.Second)
+	typeText("B")
+	if selected() != "bar.txt" {
+		t.Fatalf("expected bar.txt after starting over, got %s", selected())
+	}
+	m.typeAheadTime = time.Now().Add(-time.Second)
+	typeText("j")
+	if selected() != "base.go" {
+		t.Fatalf("expected j to move down once type-ahead is over, got %s", selected())
+	}
+}
+
+func TestRenameToSameName(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, renameOverwrite: renameAsk}
+	m.Entries, _ = m.readDirectory(dir)
+
+	m.renameFile("a.txt")
+	if m.confirm != nil || m.statusMsg != "" || len(m.undoStack) != 0 {
+		t.Fatalf("renaming to the same name should do nothing, got status %q", m.statusMsg)
+	}
+}
+
+func TestPasteCutInPlaceCancels(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+
+	m.cutFile()
+	paste(m)
+
+	if len(m.clipboard) != 0 || m.statusMsg != "Cancelled cut" {
+		t.Fatalf("expected the cut to be cancelled, got clipboard %v and status %q", m.clipboard, m.statusMsg)
+	}
+	if !m.selectByName("a.txt") {
+		t.Fatal("cancelled cut should show the file again")
+	}
+}
+
+func TestQuitWithPendingCut(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	if _, ok := m.quit()().(tea.QuitMsg); !ok {
+		t.Fatal("expected to quit right away without a cut")
+	}
+
+	m.cutFile()
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "a.txt") {
+		t.Fatalf("expected to be asked about the cut of a.txt, got %+v", m.confirm)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if m.confirm != nil || len(m.clipboard) != 1 {
+		t.Fatal("expected n to go back with the cut kept")
+	}
+
+	// Quitting from a menu asks the same
+	m.showSortMenu = true
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if m.confirm == nil {
+		t.Fatal("expected to be asked about the cut from the sort menu")
+	}
+	m.confirm = nil
+	m.showSortMenu = false
+	m.quit()
+	if cmd := m.confirm.actions["y"](); cmd == nil {
+		t.Fatal("expected y to quit")
+	} else if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Fatal("expected y to quit")
+	}
+}
+
+func TestPasteCutAfterNavigatingBack(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	key := func(k string) {
+		send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+
+	// Cut, go into sub and come back before pasting
+	m.selectByName("a.txt")
+	m.cutFile()
+	m.selectByName("sub")
+	key("l")
+	key("h")
+	if m.CurrentPath != dir {
+		t.Fatalf("expected to be back in %s, got %s", dir, m.CurrentPath)
+	}
+	paste(m)
+
+	if len(m.clipboard) != 0 || m.statusIsError || m.statusMsg != "Skipped a.txt" {
+		t.Fatalf("expected the cut to complete with a.txt skipped, got clipboard %v and status %q", m.clipboard, m.statusMsg)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "a.txt")); err != nil || string(content) != "a" {
+		t.Fatalf("file should stay in place: %q, %v", content, err)
+	}
+	for _, action := range m.undoStack {
+		if action.Type == "move" {
+			t.Fatalf("a move onto itself should not be undoable: %+v", action)
+		}
+	}
+}
+
+func TestKeepCutVisible(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20, keepCutVisible: true}
+	m.Entries, _ = m.readDirectory(dir)
+
+	// The cut entry stays listed, marked, until it is pasted
+	m.selectByName("a.txt")
+	m.cutFile()
+	if len(m.Entries) != 2 || !m.isCut(filepath.Join(dir, "a.txt")) {
+		t.Fatalf("expected a.txt still listed as cut, got %v", m.Entries)
+	}
+	if !strings.Contains(m.View(), "a.txt") {
+		t.Errorf("expected a.txt shown after the cut:\n%s", m.View())
+	}
+
+	m.selectByName("sub")
+	send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	paste(m)
+	if _, err := os.Stat(filepath.Join(dir, "sub", "a.txt")); err != nil {
+		t.Fatalf("file not moved into sub: %v", err)
+	}
+	send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("h")})
+	if m.selectByName("a.txt") {
+		t.Error("expected a.txt gone from the listing once moved")
+	}
+
+	// Without the option the entry is hidden right away
+	m.keepCutVisible = false
+	m.selectByName("sub")
+	m.cutFile()
+	if m.selectByName("sub") {
+		t.Errorf("expected the cut entry hidden, got %v", m.Entries)
+	}
+}
+
+func TestPasteSelectsPasted(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+
+	m.selectByName("a.txt")
+	m.copyFile()
+	m.selectByName("c.txt")
+	paste(m)
+	if got := m.Entries[m.Cursor].Name; got != "a_copy.txt" {
+		t.Fatalf("expected the cursor on a_copy.txt, got %s", got)
+	}
+
+	// A cancelled cut selects the entry again
+	m.selectByName("c.txt")
+	m.cutFile()
+	m.Cursor = 0
+	paste(m)
+	if got := m.Entries[m.Cursor].Name; got != "c.txt" {
+		t.Fatalf("expected the cursor back on c.txt, got %s", got)
+	}
+}
+
+func TestPasteCutIntoSubdirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+
+	m.selectByName("a.txt")
+	m.cutFile()
+	m.selectByName("sub")
+	send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	paste(m)
+
+	if _, err := os.Stat(filepath.Join(dir, "sub", "a.txt")); err != nil {
+		t.Fatalf("file not moved into sub: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("file still in the original directory: %v", err)
+	}
+}
+
+func TestPasteCollision(t *testing.T) {
+	dir, src := t.TempDir(), t.TempDir()
+	for _, d := range []string{dir, src} {
+		if err := os.WriteFile(filepath.Join(d, "a.txt"), []byte(d), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: src, trashDir: t.TempDir()}
+	m.Entries, _ = m.readDirectory(src)
+	m.cutFile()
+	m.CurrentPath = dir
+	m.Entries, _ = m.readDirectory(dir)
+
+	// A single entry asks instead of replacing what is there
+	paste(m)
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "a.txt already exists") {
+		t.Fatalf("expected to be asked about a.txt, got %+v", m.confirm)
+	}
+	m.confirm.actions["r"]()
+	if content, err := os.ReadFile(filepath.Join(dir, "a_1.txt")); err != nil || string(content) != src {
+		t.Fatalf("expected the moved file as a_1.txt: %q, %v", content, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(content) != dir {
+		t.Fatalf("existing file should be kept, got %q", content)
+	}
+
+	// Overwriting is undone in one step, bringing back the replaced file
+	m.Entries, _ = m.readDirectory(dir)
+	m.selectByName("a_1.txt")
+	m.copyFile()
+	m.CurrentPath = src
+	if err := os.WriteFile(filepath.Join(src, "a_1.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	paste(m)
+	runPaste(m, m.confirm.actions["o"]())
+	if content, _ := os.ReadFile(filepath.Join(src, "a_1.txt")); string(content) != src {
+		t.Fatalf("expected a_1.txt overwritten, got %q", content)
+	}
+	m.undoLastAction()
+	if content, _ := os.ReadFile(filepath.Join(src, "a_1.txt")); string(content) != "old" {
+		t.Fatalf("expected one undo to restore the overwritten file, got %q (%s)", content, m.statusMsg)
+	}
+}
+
+func TestPasteRenameAllKeepsBoth(t *testing.T) {
+	dir, src := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "a_1.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: src, trashDir: t.TempDir()}
+	m.Entries, _ = m.readDirectory(src)
+	for i := range m.Entries {
+		m.Entries[i].Selected = true
+	}
+	m.cutFile()
+	m.CurrentPath = dir
+	m.Entries, _ = m.readDirectory(dir)
+
+	// a.txt is renamed to a_1.txt, so the moved a_1.txt needs another name
+	paste(m)
+	if m.confirm == nil {
+		t.Fatal("expected to be asked about the collision")
+	}
+	m.confirm.actions["r"]()
+	contents := map[string]string{}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		content, _ := os.ReadFile(filepath.Join(dir, entry.Name()))
+		contents[string(content)] = entry.Name()
+	}
+	if len(contents) != 3 || contents["existing"] != "a.txt" || contents["a.txt"] == "" || contents["a_1.txt"] == "" {
+		t.Fatalf("expected all three files kept, got %v", contents)
+	}
+}
+
+func TestPasteCancelKeepsRemainingCut(t *testing.T) {
+	dir, src, other := t.TempDir(), t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: src}
+	m.Entries, _ = m.readDirectory(src)
+	for i := range m.Entries {
+		m.Entries[i].Selected = true
+	}
+	m.cutFile()
+	m.CurrentPath = dir
+	m.Entries, _ = m.readDirectory(dir)
+
+	// Deciding each, a.txt is moved before b.txt collides; cancelling
+	// there leaves b.txt cut
+	paste(m)
+	if m.confirm == nil {
+		t.Fatal("expected to be asked about the collision")
+	}
+	runPaste(m, m.confirm.actions["d"]())
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "b.txt already exists") {
+		t.Fatalf("expected to be asked about b.txt, got %+v", m.confirm)
+	}
+	m.confirm.actions["c"]()
+	if len(m.clipboard) != 1 || m.clipboard[0].Name != "b.txt" || m.clipboardOp != "cut" {
+		t.Fatalf("expected only b.txt left cut, got %v", m.clipboard)
+	}
+	if m.statusMsg != "Stopped pasting, 1 item still cut" {
+		t.Errorf("unexpected status %q", m.statusMsg)
+	}
+
+	// Pasting elsewhere moves just what is left
+	m.CurrentPath = other
+	m.Entries, _ = m.readDirectory(other)
+	paste(m)
+	if m.statusIsError || m.statusMsg != "Moved b.txt" {
+		t.Fatalf("expected b.txt moved, got status %q", m.statusMsg)
+	}
+	if content, err := os.ReadFile(filepath.Join(other, "b.txt")); err != nil || string(content) != "b.txt" {
+		t.Fatalf("b.txt not moved: %q, %v", content, err)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "a.txt")); err != nil || string(content) != "a.txt" {
+		t.Errorf("expected a.txt kept where it was moved first: %q, %v", content, err)
+	}
+}
+
+func TestMoveIntoNewDirUndo(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// An existing directory with the chosen name gets a suffix
+	if err := os.Mkdir(filepath.Join(dir, "new"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	m.moveIntoNewDir("new", []FileEntry{{Name: "a.txt", Path: filepath.Join(dir, "a.txt")}})
+	if _, err := os.Stat(filepath.Join(dir, "new_1", "a.txt")); err != nil {
+		t.Fatalf("entry not moved into new_1: %v", err)
+	}
+
+	m.undoLastAction()
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Errorf("entry not restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new_1")); !os.IsNotExist(err) {
+		t.Errorf("created directory still exists: %v", err)
+	}
+	if len(m.undoStack) != 0 {
+		t.Errorf("undo stack has %d actions left", len(m.undoStack))
+	}
+
+	// W moves every selected entry, undone in one step
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.Entries, _ = m.readDirectory(dir)
+	for i := range m.Entries {
+		m.Entries[i].Selected = strings.HasSuffix(m.Entries[i].Name, ".txt")
+	}
+	for _, key := range []string{"W", "t", "x", "t"} {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, "txt", name)); err != nil {
+			t.Errorf("%s not moved into txt: %v", name, err)
+		}
+	}
+	m.undoLastAction()
+	if len(m.undoStack) != 0 {
+		t.Errorf("expected one undo for the whole move, %d left", len(m.undoStack))
+	}
+}
+
+func TestRedo(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(a, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	m := &FileManager{CurrentPath: dir, trashDir: t.TempDir()}
+	m.Entries, _ = m.readDirectory(dir)
+
+	m.renameEntry(m.Entries[0], b)
+	m.undoLastAction()
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if exists(a) || !exists(b) {
+		t.Fatal("redo did not rename a.txt to b.txt again")
+	}
+
+	m.deleteFile()
+	m.undoLastAction()
+	m.redoLastAction()
+	if exists(b) {
+		t.Fatal("redo did not trash b.txt again")
+	}
+	m.undoLastAction()
+
+	m.copyFile()
+	paste(m)
+	copied := filepath.Join(dir, "b_copy.txt")
+	m.undoLastAction()
+	m.redoLastAction()
+	if !exists(copied) {
+		t.Fatal("redo did not copy b.txt again")
+	}
+
+	// Undone twice, then a new operation: nothing is left to redo
+	m.undoLastAction()
+	m.undoLastAction()
+	if len(m.redoStack) != 2 {
+		t.Fatalf("expected 2 actions to redo, got %d", len(m.redoStack))
+	}
+	m.Entries, _ = m.readDirectory(dir)
+	m.selectByName("a.txt")
+	m.deleteFile()
+	if len(m.redoStack) != 0 {
+		t.Errorf("redo stack kept %d actions after a new operation", len(m.redoStack))
+	}
+}
+
+// benchmarkReadDirectory lists a directory of 100k files with the given options
+func benchmarkReadDirectory(b *testing.B, opts ListOptions) {
+	dir := b.TempDir()
+	for i := 0; i < 100000; i++ {
+		file, err := os.Create(filepath.Join(dir, fmt.Sprintf("file%06d", i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		file.Close()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if entries := mustReadDirectory(b, dir, opts); len(entries) != 100000 {
+			b.Fatalf("listed %d entries", len(entries))
+		}
+	}
+}
+
+func BenchmarkReadDirectory(b *testing.B) {
+	benchmarkReadDirectory(b, ListOptions{})
+}
+
+// Sorting by size needs the info of every entry
+func BenchmarkReadDirectorySortSize(b *testing.B) {
+	benchmarkReadDirectory(b, ListOptions{FileSort: sortSize})
+}
+
+func TestWatchReloadKeepsSelection(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	m.selectByName("c.txt")
+
+	wait, err := m.startWatching()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.stopWatching()
+
+	// A new file sorting before the selection shouldn't move the cursor off it
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.Update(wait())
+
+	if len(m.Entries) != 3 || m.Entries[0].Name != "a.txt" {
+		t.Fatalf("expected a.txt listed first, got %v", m.Entries)
+	}
+	if m.Entries[m.Cursor].Name != "c.txt" {
+		t.Errorf("cursor moved to %s", m.Entries[m.Cursor].Name)
+	}
+
+	// A watcher error may have lost changes and the watch itself
+	m.watcher.Remove(dir)
+	if err := os.WriteFile(filepath.Join(dir, "d.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if m.Update(dirChangedMsg{watcher: m.watcher, failed: true}); len(m.Entries) != 4 {
+		t.Errorf("expected a reload after a watcher error, got %v", m.Entries)
+	}
+	if watched := m.watcher.WatchList(); len(watched) != 1 || watched[0] != dir {
+		t.Errorf("expected the watch added again, got %v", watched)
+	}
+}
+
+func TestCreateFromTemplateUndo(t *testing.T) {
+	dir := t.TempDir()
+	templates := t.TempDir()
+	if err := os.WriteFile(filepath.Join(templates, "LICENSE"), []byte("MIT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, templateDir: templates}
+
+	m.createFromTemplate("LICENSE", "LICENSE.txt")
+	if content, err := os.ReadFile(filepath.Join(dir, "LICENSE.txt")); err != nil || string(content) != "MIT" {
+		t.Fatalf("template not copied: %q, %v", content, err)
+	}
+
+	m.undoLastAction()
+	if _, err := os.Stat(filepath.Join(dir, "LICENSE.txt")); !os.IsNotExist(err) {
+		t.Errorf("created file still exists after undo: %v", err)
+	}
+}
+
+func TestCountedParent(t *testing.T) {
+	root := t.TempDir()
+	deep := filepath.Join(root, "a", "b", "c")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "0first"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: deep}
+	m.Entries, _ = m.readDirectory(deep)
+
+	for _, key := range "3h" {
+		send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+	}
+	if m.CurrentPath != root {
+		t.Fatalf("expected to go up to %s, at %s", root, m.CurrentPath)
+	}
+	if m.Entries[m.Cursor].Name != "a" {
+		t.Errorf("expected the child we came from selected, got %s", m.Entries[m.Cursor].Name)
+	}
+	if m.count != 0 {
+		t.Errorf("count left at %d", m.count)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(t.TempDir(), "hooks.log")
+	m := &FileManager{CurrentPath: dir, hooks: map[string]string{
+		"pre_rename":  `cp "$TFM_OLD_PATH" "$TFM_OLD_PATH.bak" && echo "$TFM_OLD_PATH" {} >> ` + shellQuote(logPath),
+		"post_rename": `cp {} {}.hooked && echo post >> ` + shellQuote(logPath),
+		"post_delete": "exit 3",
+	}}
+	m.Entries, _ = m.readDirectory(dir)
+	m.selectByName("a.txt")
+
+	// The pre hook sees the source before it moves; the post hook starts after the message
+	m.renameFile("b.txt")
+	if _, err := os.Stat(filepath.Join(dir, "a.txt.bak")); err != nil {
+		t.Fatalf("pre hook didn't run before the rename: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); err != nil {
+		t.Fatalf("rename didn't happen: %v", err)
+	}
+	if msg := m.startHooks()().(hookDoneMsg); msg.err != nil {
+		t.Fatalf("hooks failed: %v", msg.err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt.hooked")); err != nil {
+		t.Fatalf("post hook didn't get the new path: %v", err)
+	}
+	log, _ := os.ReadFile(logPath)
+	if string(log) != filepath.Join(dir, "a.txt")+" "+filepath.Join(dir, "b.txt")+"\npost\n" {
+		t.Errorf("expected the pre hook run before the post hook, got %q", log)
+	}
+
+	// A failing hook is reported, but the operation stands
+	m.runHook("post_delete", filepath.Join(dir, "b.txt"), "")
+	m.Update(m.startHooks()())
+	if !m.statusIsError || !strings.Contains(m.statusMsg, "post_delete hook failed") {
+		t.Errorf("expected the hook failure reported, got %q", m.statusMsg)
+	}
+}
+
+func TestRecentDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "b", "gone"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gone := filepath.Join(root, "gone")
+	m, err := New(Options{StartPath: root, RecentDirs: []string{gone}, RecentLimit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	press := func(keys string) {
+		for _, key := range keys {
+			send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		}
+	}
+
+	// Visit a, then b, by way of the root
+	press("lhjl")
+	want := []string{filepath.Join(root, "b"), root, filepath.Join(root, "a")}
+	if got := m.RecentDirs(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected %v, got %v (oldest dropped beyond the limit)", want, got)
+	}
+
+	// The picker leaves out the current directory; the second pick is a
+	press("''j")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.CurrentPath != filepath.Join(root, "a") || m.recentPicks != nil {
+		t.Fatalf("expected to jump to a, at %s", m.CurrentPath)
+	}
+	if m.RecentDirs()[0] != m.CurrentPath {
+		t.Errorf("expected a to become the most recent, got %v", m.RecentDirs())
+	}
+
+	// A directory removed since is dropped when picked
+	m.recentDirs = append(m.recentDirs, gone)
+	os.Remove(gone)
+	press("''")
+	press(strings.Repeat("j", len(m.recentPicks)))
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.statusIsError || slices.Contains(m.RecentDirs(), gone) {
+		t.Errorf("expected %s reported and forgotten, got %q and %v", gone, m.statusMsg, m.RecentDirs())
+	}
+}
+
+func TestBatchSelection(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	exists := func(path ...string) bool {
+		_, err := os.Stat(filepath.Join(append([]string{dir}, path...)...))
+		return err == nil
+	}
+	m := &FileManager{CurrentPath: dir, trashDir: t.TempDir()}
+	m.Entries, _ = m.readDirectory(dir)
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+
+	// Select a and c, skipping b
+	m.selectByName("a.txt")
+	m.Update(space)
+	m.Cursor++
+	m.Update(space)
+	m.deleteFile()
+	if exists("a.txt") || !exists("b.txt") || exists("c.txt") {
+		t.Fatal("expected only the selected files deleted")
+	}
+	m.undoLastAction()
+	if !exists("a.txt") || !exists("c.txt") {
+		t.Fatal("expected one undo to restore both files")
+	}
+
+	// Copy a and b into sub, then undo the paste in one step
+	m.selectByName("a.txt")
+	m.Update(space)
+	m.Update(space)
+	m.copyFile()
+	m.CurrentPath = filepath.Join(dir, "sub")
+	m.Entries, _ = m.readDirectory(m.CurrentPath)
+	paste(m)
+	if !exists("sub", "a.txt") || !exists("sub", "b.txt") || exists("sub", "c.txt") {
+		t.Fatal("expected the two selected files pasted")
+	}
+	m.undoLastAction()
+	if exists("sub", "a.txt") || exists("sub", "b.txt") {
+		t.Fatal("expected one undo to remove both copies")
+	}
+
+	// A copy takes the selection with it, even of a single entry
+	m.CurrentPath = dir
+	m.Entries, _ = m.readDirectory(dir)
+	m.selectByName("b.txt")
+	m.Update(space)
+	m.copyFile()
+	for _, entry := range m.Entries {
+		if entry.Selected {
+			t.Errorf("expected the copy to clear the selection, %s is still selected", entry.Name)
+		}
+	}
+	if len(m.clipboard) != 1 || m.statusMsg != "Copied b.txt" {
+		t.Errorf("expected b.txt copied, got %v and status %q", m.clipboard, m.statusMsg)
+	}
+
+	// esc clears the selection
+	m.Update(space)
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if len(m.selectedEntries()) != 1 || m.selectedEntries()[0].Name != m.Entries[m.Cursor].Name {
+		t.Errorf("expected the selection cleared, got %v", m.selectedEntries())
+	}
+}
+
+func TestPreviewLines(t *testing.T) {
+	dir := t.TempDir()
+	var content strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20, autoPreview: true}
+	m.Entries, _ = m.readDirectory(dir)
+	press := func(keys string) {
+		for _, key := range keys {
+			send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		}
+	}
+
+	press("v")
+	if m.previewLines == nil || m.previewLines.text() != "line 1" {
+		t.Fatalf("expected the cursor on the first line, got %+v", m.previewLines)
+	}
+
+	// Past the bottom of the preview, which scrolls to keep the cursor shown
+	press("G")
+	if !strings.Contains(m.View(), "> line 30") {
+		t.Errorf("expected the last line shown with the cursor:\n%s", m.View())
+	}
+
+	// A range from line 2 to 4
+	press("gjvjj")
+	if got := m.previewLines.text(); got != "line 2\nline 3\nline 4" {
+		t.Errorf("expected lines 2-4, got %q", got)
+	}
+	if !strings.Contains(m.View(), "Lines 2-4 of 30") {
+		t.Error("expected the range in the command line")
+	}
+
+	// esc drops the range, then leaves
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.previewLines == nil || m.previewLines.text() != "line 4" {
+		t.Fatalf("expected only the range dropped, got %+v", m.previewLines)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.previewLines != nil {
+		t.Error("expected to leave the line cursor")
+	}
+
+	// The cursor starts on the first line the preview is scrolled to, and
+	// drawing it leaves the scroll alone
+	m.previewScroll = 10
+	press("v")
+	if m.previewLines.text() != "line 11" {
+		t.Fatalf("expected the cursor on line 11, got %q", m.previewLines.text())
+	}
+	top := m.previewLines.top
+	m.Height = 5
+	m.View()
+	if m.previewLines.top != top {
+		t.Errorf("expected View to leave the scroll at %d, got %d", top, m.previewLines.top)
+	}
+}
+
+func TestPreviewScroll(t *testing.T) {
+	dir := t.TempDir()
+	var content strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	for _, name := range []string{"notes.txt", "other.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20, autoPreview: true}
+	m.Entries, _ = m.readDirectory(dir)
+	press := func(keys string) {
+		for _, key := range keys {
+			send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		}
+	}
+
+	press("JJJ")
+	if m.previewScroll != 3 {
+		t.Fatalf("expected the preview scrolled 3 lines, got %d", m.previewScroll)
+	}
+	if len(m.lineCounts) != 1 {
+		t.Errorf("expected the lines counted once, got %v", m.lineCounts)
+	}
+	if view := m.View(); strings.Contains(view, "line 3\n") || !strings.Contains(view, "line 4") {
+		t.Errorf("expected the preview to start at line 4:\n%s", view)
+	}
+	press("K")
+	if m.previewScroll != 2 {
+		t.Errorf("expected K to scroll back up, got %d", m.previewScroll)
+	}
+
+	// Scrolling stops with the last line in view
+	for range 10 {
+		send(m, tea.KeyMsg{Type: tea.KeyCtrlD})
+	}
+	if want := 31 - m.previewHeight(); m.previewScroll != want {
+		t.Errorf("expected the scroll to stop at %d, got %d", want, m.previewScroll)
+	}
+	if !strings.Contains(m.View(), "line 30") {
+		t.Error("expected the last line shown")
+	}
+	send(m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	if want := 31 - m.previewHeight() - m.previewHeight()/2; m.previewScroll != want {
+		t.Errorf("expected ctrl+u to scroll half a page up to %d, got %d", want, m.previewScroll)
+	}
+
+	// Another entry starts at the top
+	press("j")
+	if m.previewScroll != 0 {
+		t.Errorf("expected the scroll reset on moving, got %d", m.previewScroll)
+	}
+}
+
+func TestMarks(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"src", "docs"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"src/main.go", "src/util.go", "docs/notes.md"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: filepath.Join(root, "src"), Width: 80, Height: 20}
+	m.Entries, _ = m.readDirectory(m.CurrentPath)
+	press := func(keys string) {
+		for _, key := range keys {
+			send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		}
+	}
+
+	// Mark util.go, then jump back to it from another directory
+	m.selectByName("util.go")
+	press("Ma")
+	m.openDir(filepath.Join(root, "docs"), "")
+	press("`")
+	if !strings.Contains(m.View(), "a  "+filepath.Join(root, "src", "util.go")) {
+		t.Errorf("expected the marks overlay to list a:\n%s", m.View())
+	}
+	press("a")
+	if m.CurrentPath != filepath.Join(root, "src") || m.Entries[m.Cursor].Name != "util.go" {
+		t.Fatalf("expected util.go selected in src, at %s", m.CurrentPath)
+	}
+
+	// Renaming in tfm keeps the mark on the file
+	m.renameEntry(m.Entries[m.Cursor], filepath.Join(root, "src", "helpers.go"))
+	m.openDir(root, "")
+	press("`a")
+	if m.Entries[m.Cursor].Name != "helpers.go" {
+		t.Fatalf("expected the mark to follow the rename, on %s", m.Entries[m.Cursor].Name)
+	}
+
+	// A file removed behind tfm's back loses its mark
+	os.Remove(filepath.Join(root, "src", "helpers.go"))
+	m.openDir(root, "")
+	press("`a")
+	if !m.statusIsError || m.CurrentPath != filepath.Join(root, "src") || len(m.marks) != 0 {
+		t.Errorf("expected an error in src and the mark removed, got %q at %s", m.statusMsg, m.CurrentPath)
+	}
+}
+
+func TestLoadDirectoryInBackground(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "b", filepath.Join("b", "inner")} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: root, Width: 80, Height: 20}
+	m.Entries, _ = m.readDirectory(root)
+	m.selectByName("b")
+
+	// Entering b shows a placeholder until the listing arrives
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	if m.CurrentPath != root || !strings.Contains(m.View(), loadingMsg) {
+		t.Fatalf("expected b to load in the background, at %s", m.CurrentPath)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	m.Update(awaitLoad(cmd))
+	if m.CurrentPath != filepath.Join(root, "b") || m.Cursor != 0 || m.Entries[0].Name != "inner" {
+		t.Fatalf("expected to enter b on its first entry, at %s on %d", m.CurrentPath, m.Cursor)
+	}
+
+	// Going back selects the directory we came from
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("h")})
+	m.Update(awaitLoad(cmd))
+	if m.CurrentPath != root || m.Entries[m.Cursor].Name != "b" {
+		t.Fatalf("expected b selected in %s, at %s", root, m.CurrentPath)
+	}
+
+	// A load given up with esc is dropped when it arrives
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m.Update(awaitLoad(cmd))
+	loadListings(m)
+	if m.CurrentPath != root || strings.Contains(m.View(), loadingMsg) {
+		t.Errorf("expected the cancelled load dropped, at %s", m.CurrentPath)
+	}
+
+	// Whether a directory is too large to enter without asking is found out
+	// in the background too
+	if err := os.Mkdir(filepath.Join(root, "b", "other"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m.largeDirThreshold = 1
+	m.selectByName("b")
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	if m.confirm != nil {
+		t.Fatal("expected the entries counted before asking")
+	}
+	m.Update(awaitLoad(cmd))
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "more than 1 entries") {
+		t.Fatalf("expected to be asked before entering b, got %+v", m.confirm)
+	}
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m.Update(awaitLoad(cmd))
+	if m.CurrentPath != filepath.Join(root, "b") {
+		t.Errorf("expected to enter b once confirmed, at %s", m.CurrentPath)
+	}
+}
+
+func TestQuickLookOnEnter(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(Options{StartPath: dir, EnterFiles: "preview"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.Width, m.Height = 80, 20
+	// shown reports whether a line of the file is on screen
+	shown := func(line string) bool {
+		for _, l := range strings.Split(m.View(), "\n") {
+			if strings.TrimSpace(l) == line {
+				return true
+			}
+		}
+		return false
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.quickLook == nil || !shown("line 1") {
+		t.Fatalf("expected enter to show notes.txt in the quick look")
+	}
+	for range 3 {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	}
+	if shown("line 3") || !shown("line 4") {
+		t.Errorf("expected the quick look scrolled to line 4:\n%s", m.View())
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.quickLook != nil {
+		t.Errorf("expected esc to close the quick look")
+	}
+}
+
+func TestReorderEntries(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	names := func(entries []FileEntry) string {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		return strings.Join(names, " ")
+	}
+
+	// Move c to the top with gr and K twice; the cursor follows it
+	m.selectByName("c.txt")
+	for _, key := range "grKK" {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if got := names(m.Entries); got != "c.txt a.txt b.txt" || m.Cursor != 0 || m.reorderMode {
+		t.Fatalf("expected c moved to the top and reordering done, got %s on %d", got, m.Cursor)
+	}
+
+	// The order is kept for the next listing; stale and new names are
+	// sorted as usual, after the ordered ones
+	os.Remove(filepath.Join(dir, "a.txt"))
+	os.WriteFile(filepath.Join(dir, "0new.txt"), nil, 0644)
+	if got := names(mustReadDirectory(t, dir, m.listOptions)); got != "c.txt b.txt 0new.txt" {
+		t.Errorf("expected the saved order followed by new entries, got %s", got)
+	}
+}
+
+func TestActivityIndicator(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20, showDirCounts: true}
+	m.Entries, _ = m.readDirectory(dir)
+
+	// Counting the directories and reading the parent column show in the
+	// status bar while they run
+	_, cmd := m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	if cmd == nil || !strings.Contains(m.View(), activityFrames[0]+" 2") {
+		t.Fatalf("expected two operations shown as running, got %d", m.activeOps)
+	}
+	m.Update(countDirsCmd(OS, dir, []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")})())
+	if m.activeOps != 1 {
+		t.Errorf("expected the parent listing still running, got %d running", m.activeOps)
+	}
+	m.Update(dirLoadedMsg{path: filepath.Dir(dir), listing: true})
+	if m.activeOps != 0 || strings.Contains(m.View(), activityFrames[0]) {
+		t.Errorf("expected the indicator cleared once counts arrived, got %d running", m.activeOps)
+	}
+
+	// The spinner stops turning with nothing left to run
+	if _, cmd := m.Update(activityTickMsg{}); cmd != nil || m.activityTicking {
+		t.Errorf("expected the spinner to stop")
+	}
+}
+
+func TestSortCycling(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"a.txt": 30, "b.txt": 10, "c.txt": 20} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20}
+	m.Entries, _ = m.readDirectory(dir)
+	names := func() string {
+		var names []string
+		for _, entry := range m.Entries {
+			names = append(names, entry.Name)
+		}
+		return strings.Join(names, " ")
+	}
+	press := func(key string) {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+
+	// o moves on from name to size, largest first
+	press("o")
+	if got := names(); got != "a.txt c.txt b.txt" {
+		t.Fatalf("expected sorting by size, got %s", got)
+	}
+	// O reverses it, and the status bar says so
+	press("O")
+	if got := names(); got != "b.txt c.txt a.txt" {
+		t.Fatalf("expected the size sort reversed, got %s", got)
+	}
+	if !strings.Contains(m.View(), "sort: size, reversed") {
+		t.Errorf("expected the sort shown in the status bar:\n%s", m.View())
+	}
+}
+
+func TestSortMenu(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no execute bits or plain symlinks on Windows")
+	}
+	dir := t.TempDir()
+	for name, mode := range map[string]os.FileMode{"a.txt": 0644, "z.sh": 0755} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("a.txt", filepath.Join(dir, "m.lnk")); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20}
+	m.Entries, _ = m.readDirectory(dir)
+	names := func() string {
+		var names []string
+		for _, entry := range m.Entries {
+			names = append(names, entry.Name)
+		}
+		return strings.Join(names, " ")
+	}
+	press := func(keys ...string) {
+		for _, key := range keys {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		}
+	}
+
+	// so lists the modes, marking the one in effect
+	press("s", "o")
+	if !m.showSortMenu || !strings.Contains(m.View(), "* n  name") {
+		t.Fatalf("expected the sort menu with name marked:\n%s", m.View())
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.showSortMenu || names() != "a.txt m.lnk z.sh" {
+		t.Fatalf("expected esc to close the menu leaving the sort, got %s", names())
+	}
+
+	// Picking a mode sorts by it: links, then executables, then the rest
+	press("s", "o", "t")
+	if m.showSortMenu || names() != "m.lnk z.sh a.txt" {
+		t.Fatalf("expected sorting by type, got %s", names())
+	}
+	if m.statusMsg != "Sorted by type" {
+		t.Errorf("expected the new sort in the status, got %q", m.statusMsg)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	saved := map[string]string{}
+	m, err := New(Options{StartPath: sub, Bookmarks: map[string]string{"proj": root}, AddBookmark: func(name, path string) error {
+		saved[name] = path
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.Width, m.Height = 80, 20
+	press := func(keys string) {
+		for _, key := range keys {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		}
+	}
+
+	// m and a letter bookmarks the current directory, saved right away
+	press("ms")
+	if saved["s"] != sub || m.Bookmarks()["s"] != sub {
+		t.Fatalf("expected sub bookmarked and saved as s, got %v", saved)
+	}
+
+	// ' lists the bookmarks, and a letter jumps to one
+	m.openDir(root, "")
+	press("'")
+	if view := m.View(); !strings.Contains(view, "proj") || !strings.Contains(view, sub) {
+		t.Errorf("expected the bookmarks listed:\n%s", view)
+	}
+	press("s")
+	if m.CurrentPath != sub {
+		t.Errorf("expected to jump to %s, at %s", sub, m.CurrentPath)
+	}
+
+	// A bookmark that isn't set is reported
+	press("'x")
+	if !m.statusIsError || m.CurrentPath != sub {
+		t.Errorf("expected an error for an unset bookmark, got %q", m.statusMsg)
+	}
+}
+
+func TestInputIdleTimeout(t *testing.T) {
+	m := &FileManager{CurrentPath: t.TempDir(), inputTimeout: time.Minute}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")}); cmd == nil {
+		t.Fatal("expected the idle timer to start with the search")
+	}
+	stale := inputIdleMsg{seq: m.inputSeq}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+
+	// A timer started before the last key doesn't cancel anything
+	m.Update(stale)
+	if !m.searchMode || m.searchQuery != "a" {
+		t.Fatalf("expected the search to go on after a stale timer, query %q", m.searchQuery)
+	}
+	m.Update(inputIdleMsg{seq: m.inputSeq})
+	if m.searchMode || m.searchQuery != "" {
+		t.Fatalf("expected the idle search to be cancelled, query %q", m.searchQuery)
+	}
+
+	// Without a timeout input waits as long as it takes
+	m.inputTimeout = 0
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")}); cmd != nil || !m.searchMode {
+		t.Fatal("expected no idle timer without a timeout")
+	}
+}
+
+func TestZoxideErrors(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileManager{CurrentPath: dir}
+
+	// Without zoxide installed the reason is shown
+	t.Setenv("PATH", t.TempDir())
+	m.navigateWithZoxide("proj")
+	if !m.statusIsError || !strings.Contains(m.statusMsg, "running zoxide") {
+		t.Fatalf("expected zoxide's absence to be reported, got %q", m.statusMsg)
+	}
+
+	// A query nothing matches is reported too
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "zoxide"), []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	m.navigateWithZoxide("proj")
+	if !m.statusIsError || !strings.Contains(m.statusMsg, `no directory for "proj"`) || m.CurrentPath != dir {
+		t.Fatalf("expected no match to be reported, got %q", m.statusMsg)
+	}
+}
+
+func TestFindFile(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{"sub/deep/target.go", "sub/other.txt", ".git/target.go"} {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: root, Width: 100, Height: 30}
+	m.Entries, _ = m.readDirectory(root)
+	press := func(keys string) {
+		for _, key := range keys {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		}
+	}
+
+	// Without fzf the built-in finder lists what the listing would show
+	t.Setenv("PATH", t.TempDir())
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	if m.finder == nil || !strings.Contains(m.View(), "Find file (0/0…)") {
+		t.Fatalf("expected the finder open while walking:\n%s", m.View())
+	}
+	for m.finder.walking {
+		_, cmd = m.Update(awaitMsg[finderFilesMsg](cmd))
+	}
+	if len(m.finder.files) != 2 {
+		t.Fatalf("expected the two visible files in the finder, got %+v", m.finder)
+	}
+	press("trg")
+	if len(m.finder.matches) != 1 || !strings.Contains(m.View(), "Find file (1/2): trg") {
+		t.Fatalf("expected one match for trg:\n%s", m.View())
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	want := filepath.Join(root, "sub", "deep", "target.go")
+	if m.finder != nil || m.selectedPath() != want {
+		t.Fatalf("expected %s selected, got %q", want, m.selectedPath())
+	}
+
+	// fzf prints the picked file relative to where it ran
+	m.Update(fzfDoneMsg{root: root, output: "sub/other.txt\n"})
+	if want := filepath.Join(root, "sub", "other.txt"); m.selectedPath() != want {
+		t.Errorf("expected %s selected from fzf's output, got %q", want, m.selectedPath())
+	}
+}
+
+func TestSearchFiltersListing(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"alpha.txt", "beta.go", "notes.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir, Width: 100, Height: 20}
+	m.Entries, _ = m.readDirectory(dir)
+	m.Cursor = 2
+	press := func(keys string) {
+		for _, key := range keys {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		}
+	}
+	listed := func() string {
+		var names []string
+		for _, name := range []string{"alpha.txt", "beta.go", "notes.md"} {
+			if strings.Contains(m.View(), name) {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, " ")
+	}
+
+	// Only matches are listed while typing, case aside, the best selected
+	press("/BT")
+	if got := listed(); got != "beta.go" {
+		t.Fatalf("expected only beta.go listed, got %q", got)
+	}
+	if m.Entries[m.Cursor].Name != "beta.go" {
+		t.Errorf("expected the top match selected, got %s", m.Entries[m.Cursor].Name)
+	}
+	if got := matchPositions("BT", "beta.go", true); !slices.Equal(got, []int{0, 2}) {
+		t.Errorf("expected b and t highlighted, got %v", got)
+	}
+
+	// esc brings back the whole listing and the entry selected before
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if got := listed(); got != "alpha.txt beta.go notes.md" || m.Cursor != 2 {
+		t.Fatalf("expected everything listed with the cursor back, got %q at %d", got, m.Cursor)
+	}
+
+	// enter keeps the top match selected
+	press("/a")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.searchMode || m.Entries[m.Cursor].Name != "alpha.txt" || listed() != "alpha.txt beta.go notes.md" {
+		t.Fatalf("expected alpha.txt selected in the whole listing, got %s", m.Entries[m.Cursor].Name)
+	}
+}
+
+func TestPickPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pick := func(kind string, cursor int) (*FileManager, tea.Cmd) {
+		m := &FileManager{CurrentPath: dir, pickKind: kind}
+		m.Entries, _ = m.readDirectory(dir)
+		m.Cursor = cursor
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlY})
+		return m, cmd
+	}
+
+	// Quitting any other way picks nothing
+	m := &FileManager{CurrentPath: dir}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if m.Picked() != "" {
+		t.Fatalf("expected nothing picked on quit, got %s", m.Picked())
+	}
+
+	if m, cmd := pick(PickAny, 1); m.Picked() != filepath.Join(dir, "b.txt") || cmd == nil {
+		t.Errorf("expected b.txt picked and tfm quitting, got %q", m.Picked())
+	}
+	// Picking directories from a file keeps the current one
+	if m, _ := pick(PickDir, 1); m.Picked() != dir {
+		t.Errorf("expected the current directory picked, got %q", m.Picked())
+	}
+	if m, _ := pick(PickDir, 0); m.Picked() != filepath.Join(dir, "a") {
+		t.Errorf("expected a picked, got %q", m.Picked())
+	}
+	// Only files can be picked with PickFile
+	if m, _ := pick(PickFile, 0); m.Picked() != "" || m.statusMsg == "" {
+		t.Errorf("expected no directory picked as a file, got %q", m.Picked())
+	}
+}
+
+func TestCreateFileAndDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	typeName := func(key, name string) {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		for _, r := range name {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+		m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	// % creates a file and + a directory, each selected once created
+	typeName("%", "a.txt")
+	if info, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil || info.IsDir() {
+		t.Fatalf("expected a.txt created: %v", err)
+	}
+	if m.Entries[m.Cursor].Name != "a.txt" {
+		t.Errorf("expected a.txt selected, got %s", m.Entries[m.Cursor].Name)
+	}
+	typeName("+", "src")
+	if info, err := os.Stat(filepath.Join(dir, "src")); err != nil || !info.IsDir() {
+		t.Fatalf("expected src created: %v", err)
+	}
+	if m.Entries[m.Cursor].Name != "src" {
+		t.Errorf("expected src selected, got %s", m.Entries[m.Cursor].Name)
+	}
+
+	// An existing name is refused
+	typeName("%", "b.txt")
+	if !m.statusIsError {
+		t.Errorf("expected an error creating over b.txt, got %q", m.statusMsg)
+	}
+
+	// Undo removes what was created, and redo brings it back
+	m.undoLastAction()
+	m.undoLastAction()
+	for _, name := range []string{"a.txt", "src"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s removed by undo: %v", name, err)
+		}
+	}
+	m.redoLastAction()
+	m.redoLastAction()
+	for _, name := range []string{"a.txt", "src"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s back after redo: %v", name, err)
+		}
+	}
+
+	// A file written to since it was created goes to the trash instead
+	m.trashDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.undoLastAction()
+	m.undoLastAction()
+	if _, err := os.Stat(filepath.Join(dir, "src", "main.go")); err != nil {
+		t.Errorf("expected the non-empty src kept: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(m.trashDir, "a.txt")); err != nil || string(content) != "notes" {
+		t.Errorf("expected a.txt in the trash: %q, %v", content, err)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2025, 3, 12, 9, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		t    time.Time
+		want string
+	}{
+		{now.Add(-20 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(-45 * time.Minute), "45 minutes ago"},
+		{now.Add(-3 * time.Hour), "3 hours ago"},
+		{time.Date(2025, 3, 11, 23, 50, 0, 0, time.UTC), "yesterday"},
+		{time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC), "3 days ago"},
+		{time.Date(2025, 3, 5, 8, 0, 0, 0, time.UTC), "7 days ago"},
+		{time.Date(2025, 3, 4, 8, 0, 0, 0, time.UTC), "04 Mar 2025 08:00"},
+	} {
+		if got := relativeTime(tc.t, now); got != tc.want {
+			t.Errorf("relativeTime(%s) = %q, want %q", tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestGitStatus(t *testing.T) {
+	dir := filepath.Join("/repo", "src")
+	output := " M src/main.go\x00M  src/util.go\x00?? src/new/\x00!! src/build/\x00!! src/lib/cache.o\x00" +
+		"R  src/renamed.go\x00src/old.go\x00 M docs/guide.md\x00"
+	states := parseGitStatus(dir, "src/", []byte(output))
+	want := map[string]gitState{
+		filepath.Join(dir, "main.go"):    gitModified,
+		filepath.Join(dir, "util.go"):    gitStaged,
+		filepath.Join(dir, "new"):        gitUntracked,
+		filepath.Join(dir, "build"):      gitIgnored,
+		filepath.Join(dir, "renamed.go"): gitStaged,
+	}
+	if !maps.Equal(states, want) {
+		t.Errorf("expected %v, got %v", want, states)
+	}
+
+	// Inside an untracked directory everything is untracked
+	states = parseGitStatus(filepath.Join(dir, "new"), "src/new/", []byte("?? src/new/\x00"))
+	if states[filepath.Join(dir, "new")] != gitUntracked {
+		t.Errorf("expected the whole directory untracked, got %v", states)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=tfm", "-c", "user.email=tfm@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	git("init", "-q")
+	for name, content := range map[string]string{"tracked.txt": "a", "sub/deep.txt": "a", ".gitignore": "*.log\n"} {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	for name, content := range map[string]string{"sub/deep.txt": "b", "new.txt": "", "debug.log": ""} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &FileManager{CurrentPath: repo, Width: 100, Height: 20, gitStatus: true}
+	m.Entries, _ = m.readDirectory(repo)
+	cmd := m.startGitStatus()
+	if cmd == nil {
+		t.Fatal("expected the git status to be read")
+	}
+	m.Update(cmd())
+	want = map[string]gitState{
+		filepath.Join(repo, "sub"):       gitModified,
+		filepath.Join(repo, "new.txt"):   gitUntracked,
+		filepath.Join(repo, "debug.log"): gitIgnored,
+	}
+	if !maps.Equal(m.gitStates, want) {
+		t.Errorf("expected %v, got %v", want, m.gitStates)
+	}
+	if cmd := m.startGitStatus(); cmd != nil {
+		t.Error("expected the status cached until the listing is reloaded")
+	}
+
+	// A subdirectory only asks git about what is under it
+	sub := filepath.Join(repo, "sub")
+	if states := readGitStatus(sub); !maps.Equal(states, map[string]gitState{filepath.Join(sub, "deep.txt"): gitModified}) {
+		t.Errorf("expected only deep.txt modified in sub, got %v", states)
+	}
+
+	// Outside a repository nothing is shown
+	if states := readGitStatus(t.TempDir()); len(states) != 0 {
+		t.Errorf("expected no status outside a repository, got %v", states)
+	}
+}
+
+func TestCommandPreviewInBackground(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("preview command written for sh")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, Width: 100, Height: 20, autoPreview: true,
+		previewCommands: map[string]string{"txt": "tr a-z A-Z <"}}
+	m.Entries, _ = m.readDirectory(dir)
+
+	// View shows a placeholder until the command has run
+	cmd := m.startPreview()
+	if cmd == nil || !strings.Contains(m.View(), loadingMsg) {
+		t.Fatalf("expected the command started and a placeholder shown:\n%s", m.View())
+	}
+	m.Update(cmd())
+	if !strings.Contains(m.View(), "HELLO") {
+		t.Fatalf("expected the command output previewed:\n%s", m.View())
+	}
+	if m.startPreview() != nil {
+		t.Error("expected the output cached")
+	}
+
+	// A change to the file runs it again
+	if err := os.WriteFile(path, []byte("bye"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if cmd = m.startPreview(); cmd == nil {
+		t.Fatal("expected the command run again for the changed file")
+	}
+	m.Update(cmd())
+	if !strings.Contains(m.View(), "BYE") {
+		t.Errorf("expected the new output previewed:\n%s", m.View())
+	}
+}
+
+func TestDiffPreviewReadAgainOnReload(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=tfm", "-c", "user.email=tfm@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	path := filepath.Join(repo, "notes.txt")
+	git("init", "-q")
+	if err := os.WriteFile(path, []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	if err := os.WriteFile(path, []byte("two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &FileManager{CurrentPath: repo, Width: 100, Height: 20, autoPreview: true, diffPreview: true}
+	m.Entries, _ = m.readDirectory(repo)
+	m.Update(m.startPreview()())
+	if !strings.Contains(m.View(), "+two") {
+		t.Fatalf("expected the change previewed:\n%s", m.View())
+	}
+
+	// Committing leaves the file as it is; a reload reads the diff again
+	git("commit", "-q", "-am", "second")
+	if m.startPreview() != nil {
+		t.Fatal("expected the diff cached until a reload")
+	}
+	m.reloadKeepingSelection()
+	m.Update(m.startPreview()())
+	if !strings.Contains(m.View(), "No changes against HEAD") {
+		t.Errorf("expected the diff read again after the reload:\n%s", m.View())
+	}
+}
+
+func TestOpeners(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"notes.txt", "notes.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20, openers: map[string]Opener{
+		"txt": {Command: "cp {} {}.opened"},
+		"md":  {Command: "cp {} {}.opened", Foreground: true},
+	}}
+	m.Entries, _ = m.readDirectory(dir)
+
+	// A background opener runs right away, leaving the TUI as it is
+	m.selectByName("notes.txt")
+	if cmd := m.openFile(m.Entries[m.Cursor]); cmd != nil {
+		t.Error("expected nothing left for the program to run")
+	}
+	opened := filepath.Join(dir, "notes.txt.opened")
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(opened); err == nil {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("expected notes.txt opened in the background (%s)", m.statusMsg)
+		}
+	}
+
+	// A foreground one is handed to the program, which suspends the TUI to run it
+	m.selectByName("notes.md")
+	if cmd := m.openFile(m.Entries[m.Cursor]); cmd == nil {
+		t.Fatal("expected a command suspending the TUI for the foreground opener")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.md.opened")); !os.IsNotExist(err) {
+		t.Errorf("expected the foreground opener left to the program to run: %v", err)
+	}
+}
+
+func TestRunExecutables(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no execute bits on Windows")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20, runExecutables: executablesAsk,
+		openers: map[string]Opener{"sh": {Command: "true"}}}
+	m.Entries, _ = m.readDirectory(dir)
+	press := func(key string) tea.Cmd {
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		return cmd
+	}
+
+	// Enter asks what to do with an executable
+	m.selectByName("run.sh")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "Run run.sh?") {
+		t.Fatalf("expected to be asked about running run.sh, got %+v", m.confirm)
+	}
+	press("a")
+	if m.prompt == nil || !strings.Contains(m.prompt.label, "run.sh") {
+		t.Fatalf("expected a prompt for the arguments, got %+v", m.prompt)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	// ! asks too, and running hands the program a command suspending the TUI
+	press("!")
+	if m.confirm == nil {
+		t.Fatal("expected ! to ask about running run.sh")
+	}
+	if cmd := m.confirm.actions["r"](); cmd == nil {
+		t.Error("expected running to suspend the TUI")
+	}
+	m.confirm = nil
+
+	m.selectByName("notes.txt")
+	press("!")
+	if m.confirm != nil || m.statusMsg != "notes.txt is not executable" {
+		t.Errorf("expected ! to refuse a plain file, got status %q", m.statusMsg)
+	}
+
+	// By default enter opens executables like any file
+	m.runExecutables = executablesOpen
+	m.selectByName("run.sh")
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.confirm != nil {
+		t.Errorf("expected run.sh opened without asking, got %+v", m.confirm)
+	}
+}
+
+func TestOpenWith(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, Width: 80, Height: 20, openWith: map[string][]Opener{
+		"json": {{Command: "cp {} {}.viewed"}, {Command: "cp {} {}.edited"}},
+	}}
+	m.Entries, _ = m.readDirectory(dir)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("L")})
+	if m.openWithMenu == nil || !strings.Contains(m.View(), "default application") {
+		t.Fatalf("expected the open with menu:\n%s", m.View())
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	if m.openWithMenu != nil {
+		t.Fatal("expected picking an application to close the menu")
+	}
+
+	// The application runs in the background
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(path + ".edited"); err == nil {
+			return
+		}
+	}
+	t.Fatalf("expected %s opened with the second application (%s)", path, m.statusMsg)
+}
+
+func TestListWindow(t *testing.T) {
+	m := &FileManager{Entries: make([]FileEntry, 20)}
+	tests := []struct {
+		marginScroll bool
+		cursor       int
+		top          int
+	}{
+		// Centered: the window follows the cursor but stops at both ends
+		{false, 0, 0},
+		{false, 10, 7},
+		{false, 17, 13},
+		{false, 19, 13},
+		// With a margin of 2, walking down then back up
+		{true, 0, 0},
+		{true, 4, 0},
+		{true, 5, 1},
+		{true, 19, 13},
+		{true, 15, 13},
+		{true, 14, 12},
+	}
+	for _, test := range tests {
+		m.marginScroll, m.scrollOff, m.Cursor = test.marginScroll, 2, test.cursor
+		top := m.listWindow(7)
+		if top != test.top {
+			t.Errorf("marginScroll %v, cursor %d: window starts at %d, want %d", test.marginScroll, test.cursor, top, test.top)
+		}
+		// Update keeps where it scrolled to
+		m.scrollTop = top
+	}
+}
+
+func TestCopySelectedPath(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the clipboard tool here can't be stood in for")
+	}
+	// A stand-in xclip writes what it is given next to itself
+	bin := t.TempDir()
+	copied := filepath.Join(bin, "copied")
+	script := "#!/bin/sh\ncat > " + copied + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "xclip"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	clipboard := func(keys string) string {
+		t.Helper()
+		for _, r := range keys {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+		content, err := os.ReadFile(copied)
+		if err != nil {
+			t.Fatalf("nothing copied with %s: %v (%s)", keys, err, m.statusMsg)
+		}
+		return string(content)
+	}
+
+	if got := clipboard("Y"); got != filepath.Join(dir, "notes.txt") {
+		t.Errorf("Y copied %q, want the absolute path", got)
+	}
+	if got := clipboard("yn"); got != "notes.txt" {
+		t.Errorf("yn copied %q, want the name", got)
+	}
+}
+
+func TestComputeDirSize(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub", "deeper")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"a.bin": 1024, "deeper/b.bin": 2048} {
+		if err := os.WriteFile(filepath.Join(dir, "sub", name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+
+	cmd := m.computeDirSize()
+	if cmd == nil {
+		t.Fatalf("expected a background walk, got status %q", m.statusMsg)
+	}
+	m.handleDirSize(cmd().(dirSizeMsg))
+	if m.statusMsg != "sub: 3.0K in 2 files" {
+		t.Fatalf("unexpected status %q", m.statusMsg)
+	}
+
+	// Selecting it again shows the cached size without another walk
+	if cmd := m.computeDirSize(); cmd != nil || !strings.Contains(m.statusMsg, "3.0K") {
+		t.Fatalf("expected the cached size, got status %q", m.statusMsg)
+	}
+	m.statusMsg = ""
+	m.Width, m.Height = 120, 20
+	if view := m.View(); !strings.Contains(view, "3.0K in total") {
+		t.Errorf("expected the total in the status bar:\n%s", view)
+	}
+}
+
+func TestIconFor(t *testing.T) {
+	icons := loadIcons(map[string]string{".GO": "G", "link": "L"})
+	for _, tc := range []struct {
+		entry FileEntry
+		want  string
+	}{
+		{FileEntry{Name: "main.go"}, "G "},
+		{FileEntry{Name: "src", IsDir: true}, defaultIcons["dir"] + " "},
+		{FileEntry{Name: "notes"}, defaultIcons["file"] + " "},
+		{FileEntry{Name: "latest", IsDir: true, IsSymlink: true}, "L "},
+		{FileEntry{Name: "run.go", IsSymlink: true}, "L "},
+	} {
+		if got := iconFor(icons, tc.entry); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.entry.Name, tc.want, got)
+		}
+	}
+	if got := iconFor(nil, FileEntry{Name: "main.go"}); got != "" {
+		t.Errorf("expected no icon with icons off, got %q", got)
+	}
+}
@@ -0,0 +1,146 @@
+package browser
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bulkRenameMsg is sent when the editor with the names to rename exits
+type bulkRenameMsg struct {
+	entries []FileEntry // Entries named in the file, in order
+	file    string      // Temporary file holding the names
+	err     error       // Error from the editor, if any
+}
+
+// bulkRename writes the names of the selected entries to a temporary file,
+// one per line, and opens it in the editor to rename them all at once
+func (m *FileManager) bulkRename() tea.Cmd {
+	entries := m.selectedEntries()
+	if len(entries) == 0 {
+		return nil
+	}
+	args := strings.Fields(m.editor)
+	if len(args) == 0 {
+		m.setError(fmt.Errorf("no editor configured: set $EDITOR or editor in the config"))
+		return nil
+	}
+
+	var names strings.Builder
+	for _, entry := range entries {
+		if strings.ContainsAny(entry.Name, "\r\n") {
+			m.setError(fmt.Errorf("can't bulk rename %q: it has a line break", entry.Name))
+			return nil
+		}
+		names.WriteString(entry.Name + "\n")
+	}
+	file, err := os.CreateTemp("", "tfm-rename-*.txt")
+	if err != nil {
+		m.setError(err)
+		return nil
+	}
+	_, err = file.WriteString(names.String())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		m.setError(err)
+		return nil
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], file.Name())...)
+	cmd.Dir = m.CurrentPath
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return bulkRenameMsg{entries: entries, file: file.Name(), err: err}
+	})
+}
+
+// handleBulkRename renames each entry to the name on its line of the edited
+// file. Nothing is renamed unless every line names a new entry that doesn't
+// exist yet; the renames are then undone together.
+func (m *FileManager) handleBulkRename(msg bulkRenameMsg) {
+	defer os.Remove(msg.file)
+	if msg.err != nil {
+		m.setError(fmt.Errorf("editor: %w", msg.err))
+		return
+	}
+	content, err := os.ReadFile(msg.file)
+	if err != nil {
+		m.setError(err)
+		return
+	}
+	names := strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n"), "\n")
+	if len(names) != len(msg.entries) {
+		m.setError(fmt.Errorf("expected %d names but got %s, nothing renamed", len(msg.entries), pluralize(len(names), "line")))
+		return
+	}
+
+	// Check everything first, so a bad line doesn't leave a half renamed batch
+	var renames []FileEntry
+	var targets []string
+	taken := make(map[string]bool)
+	for i, entry := range msg.entries {
+		// Names with spaces at either end are kept when their line is
+		// left alone; stray spaces on a line that was edited are dropped
+		if names[i] == entry.Name {
+			continue
+		}
+		name := strings.TrimSpace(names[i])
+		if name == entry.Name {
+			continue
+		}
+		switch {
+		case name == "" || name == "." || name == "..":
+			m.setError(fmt.Errorf("line %d has no valid name, nothing renamed", i+1))
+			return
+		case strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/'):
+			m.setError(fmt.Errorf("%q can't have a path separator, nothing renamed", name))
+			return
+		}
+		target := filepath.Join(filepath.Dir(entry.Path), name)
+		if taken[target] {
+			m.setError(fmt.Errorf("%s is given twice, nothing renamed", name))
+			return
+		}
+		// A case only rename on a case insensitive filesystem finds the entry itself
+		if info, err := m.filesystem().Stat(target); err == nil && !sameEntry(m.filesystem(), entry, info) {
+			m.setError(fmt.Errorf("%s already exists, nothing renamed", name))
+			return
+		}
+		taken[target] = true
+		renames = append(renames, entry)
+		targets = append(targets, target)
+	}
+	if len(renames) == 0 {
+		m.setStatus("No names changed")
+		return
+	}
+
+	from := len(m.undoStack)
+	renamed := 0
+	for i, entry := range renames {
+		if err := m.renamePath(entry, targets[i]); err != nil {
+			m.setError(err)
+			break
+		}
+		renamed++
+	}
+	m.groupUndo(from, "renaming "+pluralize(renamed, "item"))
+	m.clearSelection()
+	m.reloadKeepingSelection()
+	if !m.statusIsError {
+		m.setStatus("Renamed %s", pluralize(renamed, "item"))
+	}
+}
+
+// sameEntry reports whether info is about entry itself
+func sameEntry(fsys FS, entry FileEntry, info fs.FileInfo) bool {
+	current, err := fsys.Stat(entry.Path)
+	return err == nil && os.SameFile(current, info)
+}
@@ -0,0 +1,41 @@
+package browser
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// CopyToClipboard puts text on the system clipboard using the platform's tool
+func CopyToClipboard(text string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("pbcopy")
+	case "windows":
+		cmd = exec.Command("clip")
+	default: // Linux and others
+		switch {
+		case os.Getenv("WAYLAND_DISPLAY") != "" && commandExists("wl-copy"):
+			cmd = exec.Command("wl-copy")
+		case commandExists("xclip"):
+			cmd = exec.Command("xclip", "-selection", "clipboard")
+		case commandExists("xsel"):
+			cmd = exec.Command("xsel", "--clipboard", "--input")
+		default:
+			return errors.New("no clipboard tool found (install wl-copy, xclip or xsel)")
+		}
+	}
+
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+// commandExists checks if a program is available in PATH
+func commandExists(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
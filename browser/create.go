@@ -0,0 +1,68 @@
+package browser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// promptCreate asks for the name of a new file, or directory if dir is set,
+// to create in the current directory
+func (m *FileManager) promptCreate(dir bool) {
+	label := "New file"
+	if dir {
+		label = "New directory"
+	}
+	m.prompt = &textPrompt{
+		label: label,
+		submit: func(name string) tea.Cmd {
+			m.createEntry(name, dir)
+			return nil
+		},
+	}
+}
+
+// createEntry creates an empty file or directory named name in the current
+// directory and selects it. Undo removes it again.
+func (m *FileManager) createEntry(name string, dir bool) {
+	if name == "" || strings.ContainsRune(name, filepath.Separator) {
+		m.setError(fmt.Errorf("invalid name %q", name))
+		return
+	}
+
+	fsys := m.filesystem()
+	dest := filepath.Join(m.CurrentPath, name)
+	if _, err := fsys.Stat(dest); err == nil {
+		m.setError(fmt.Errorf("%s already exists", name))
+		return
+	}
+	m.runHook("pre_create", dest, "")
+	action := UndoAction{Type: "create", NewPath: dest, Entry: FileEntry{Name: name, Path: dest, IsDir: dir}}
+	if dir {
+		if err := fsys.MkdirAll(dest, 0755); err != nil {
+			m.setError(err)
+			return
+		}
+		action.Type = "mkdir"
+	} else if err := createEmptyFile(fsys, dest); err != nil {
+		m.setError(err)
+		return
+	}
+	m.runHook("post_create", dest, "")
+	m.pushUndo(action)
+
+	m.reloadEntries()
+	m.selectByName(name)
+	m.setStatus("Created %s", name)
+}
+
+// createEmptyFile creates an empty file at path
+func createEmptyFile(fsys FS, path string) error {
+	file, err := fsys.Create(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
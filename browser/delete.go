@@ -0,0 +1,228 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// deleteJob is a permanent delete running in the background
+type deleteJob struct {
+	path    string             // What is being deleted
+	removed int                // Files removed so far
+	cancel  context.CancelFunc // Stops the delete (esc)
+	updates chan tea.Msg       // Progress and completion messages
+}
+
+// Custom message reporting how many files a running delete has removed
+type deleteProgressMsg struct {
+	removed int
+}
+
+// Custom message sent when a delete finishes, fails or is cancelled
+type deleteDoneMsg struct {
+	removed int
+	err     error
+}
+
+// removeTree deletes root and everything below it from fsys, files first
+// and then directories bottom-up, calling progress after each removed file.
+// Symlinks are removed, not followed. It stops with ctx's error as soon as
+// ctx is done, leaving whatever hasn't been removed yet in place.
+func removeTree(ctx context.Context, fsys FS, root string, progress func(removed int)) (int, error) {
+	var dirs []string
+	removed := 0
+
+	err := walkTree(ctx, fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		if err := fsys.RemoveAll(path); err != nil {
+			return err
+		}
+		removed++
+		progress(removed)
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	// Parents were visited before their children, so remove in reverse
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		// Emptied by now, so this removes just the directory
+		if err := fsys.RemoveAll(dirs[i]); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// Custom message carrying what a directory waiting to be permanently
+// deleted holds; fill puts it in the prompts
+type deleteCountedMsg struct {
+	fill func()
+}
+
+// confirmPermanentDelete asks before deleting the selected entry without the
+// trash. A directory with anything in it shows how much it holds and needs
+// a second confirmation.
+func (m *FileManager) confirmPermanentDelete() tea.Cmd {
+	if len(m.Entries) == 0 || m.Cursor >= len(m.Entries) {
+		return nil
+	}
+	if m.archive != nil {
+		m.setStatus("Can't delete inside an archive")
+		return nil
+	}
+	if m.deleting != nil {
+		m.setStatus("Already deleting %s", filepath.Base(m.deleting.path))
+		return nil
+	}
+
+	entry := m.Entries[m.Cursor]
+	// Only the link to a directory goes, not what is in it
+	if entry.IsDir && !entry.IsSymlink {
+		if children, err := m.filesystem().ReadDir(entry.Path); err == nil && len(children) > 0 {
+			return m.confirmPermanentDeleteTree(entry)
+		}
+	}
+	m.confirm = &confirmation{
+		prompt: fmt.Sprintf("Permanently delete %s? This cannot be undone. [y]es  [n]o", entry.Name),
+		actions: map[string]func() tea.Cmd{
+			"y": func() tea.Cmd { return m.startPermanentDelete(entry.Path) },
+			"n": func() tea.Cmd { return nil },
+		},
+	}
+	return nil
+}
+
+// confirmPermanentDeleteTree asks twice before permanently deleting a
+// non-empty directory, the second time with a key that isn't y so it can't
+// be confirmed by repeating the first answer. The prompts show right away;
+// what the directory holds is counted in the background and filled in.
+func (m *FileManager) confirmPermanentDeleteTree(entry FileEntry) tea.Cmd {
+	contents := "counting…"
+	second := &confirmation{
+		actions: map[string]func() tea.Cmd{
+			"D": func() tea.Cmd { return m.startPermanentDelete(entry.Path) },
+			"n": func() tea.Cmd { return nil },
+		},
+	}
+	first := &confirmation{
+		actions: map[string]func() tea.Cmd{
+			"y": func() tea.Cmd {
+				m.confirm = second
+				return nil
+			},
+			"n": func() tea.Cmd { return nil },
+		},
+	}
+	prompts := func() {
+		first.prompt = fmt.Sprintf("Permanently delete %s and everything in it (%s)? [y]es  [n]o", entry.Name, contents)
+		second.prompt = fmt.Sprintf("Really wipe %s (%s)? This cannot be undone. [D]elete  [n]o", entry.Name, contents)
+	}
+	prompts()
+	m.confirm = first
+
+	m.startOp()
+	fsys := m.filesystem()
+	return func() tea.Msg {
+		size, files, _ := dirSize(context.Background(), fsys, entry.Path)
+		return deleteCountedMsg{fill: func() {
+			contents = fmt.Sprintf("%s, %s", pluralize(files, "file"), HumanSize(size))
+			prompts()
+		}}
+	}
+}
+
+// handleDeleteCounted fills in what a directory waiting to be deleted holds
+func (m *FileManager) handleDeleteCounted(msg deleteCountedMsg) {
+	m.finishOp()
+	msg.fill()
+}
+
+// startPermanentDelete removes path in the background, reporting progress
+// until it is done or cancelled with esc
+func (m *FileManager) startPermanentDelete(path string) tea.Cmd {
+	m.runHook("pre_delete", path, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &deleteJob{
+		path:    path,
+		cancel:  cancel,
+		updates: make(chan tea.Msg, 1),
+	}
+	m.deleting = job
+	m.startOp()
+
+	fsys := m.filesystem()
+	go func() {
+		removed, err := removeTree(ctx, fsys, path, func(removed int) {
+			// Drop updates the UI hasn't caught up with; the next one carries the total
+			select {
+			case job.updates <- deleteProgressMsg{removed: removed}:
+			default:
+			}
+		})
+		cancel()
+		job.updates <- deleteDoneMsg{removed: removed, err: err}
+	}()
+
+	return waitForDelete(job)
+}
+
+// waitForDelete waits for the next message from a running delete
+func waitForDelete(job *deleteJob) tea.Cmd {
+	return func() tea.Msg {
+		return <-job.updates
+	}
+}
+
+// cancelDelete stops the running delete; its done message reports the result
+func (m *FileManager) cancelDelete() {
+	if m.deleting != nil {
+		m.deleting.cancel()
+	}
+}
+
+// handleDeleteProgress records progress and keeps listening
+func (m *FileManager) handleDeleteProgress(msg deleteProgressMsg) tea.Cmd {
+	if m.deleting == nil {
+		return nil
+	}
+	m.deleting.removed = msg.removed
+	return waitForDelete(m.deleting)
+}
+
+// handleDeleteDone reports how the delete ended and reloads the listing
+func (m *FileManager) handleDeleteDone(msg deleteDoneMsg) {
+	if m.deleting == nil {
+		return
+	}
+	m.finishOp()
+	path := m.deleting.path
+	name := filepath.Base(path)
+	m.deleting = nil
+	m.refresh()
+
+	switch {
+	case errors.Is(msg.err, context.Canceled):
+		m.setStatus("Cancelled deleting %s after %s", name, pluralize(msg.removed, "file"))
+	case msg.err != nil:
+		m.setError(msg.err)
+	default:
+		m.setStatus("Deleted %s (%s)", name, pluralize(msg.removed, "file"))
+		m.runHook("post_delete", path, "")
+	}
+}
@@ -0,0 +1,185 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRemoveTreeCancel(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "big")
+	for i := 0; i < 5; i++ {
+		sub := filepath.Join(root, fmt.Sprintf("dir%d", i))
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 10; j++ {
+			if err := os.WriteFile(filepath.Join(sub, fmt.Sprintf("f%d", j)), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Cancel as if esc was pressed partway through
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	removed, err := removeTree(ctx, OS, root, func(removed int) {
+		if removed == 12 {
+			cancel()
+		}
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if removed != 12 {
+		t.Fatalf("expected 12 files removed before stopping, got %d", removed)
+	}
+	_, files, err := dirSize(context.Background(), OS, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != 50-12 {
+		t.Fatalf("expected %d files left, got %d", 50-12, files)
+	}
+}
+
+func TestRemoveTree(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "tree")
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", "b", "c.txt"), []byte("c"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := removeTree(context.Background(), OS, root, func(int) {})
+	if err != nil || removed != 1 {
+		t.Fatalf("expected 1 file removed without error, got %d, %v", removed, err)
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Fatalf("root still exists: %v", err)
+	}
+}
+
+// removeLogFS records what is removed from it instead of removing anything
+type removeLogFS struct {
+	memFS
+	removed *[]string
+}
+
+func (f removeLogFS) RemoveAll(path string) error {
+	*f.removed = append(*f.removed, path)
+	return nil
+}
+
+func TestRemoveTreeFS(t *testing.T) {
+	var removed []string
+	fsys := removeLogFS{memFS{fstest.MapFS{
+		"tree/a/b/c.txt": {},
+		"tree/d.txt":     {},
+	}}, &removed}
+
+	// The walk and removals go through the given filesystem, not the OS
+	files, err := removeTree(context.Background(), fsys, "/tree", func(int) {})
+	if err != nil || files != 2 {
+		t.Fatalf("expected 2 files removed without error, got %d, %v", files, err)
+	}
+	want := []string{"/tree/a/b/c.txt", "/tree/d.txt", "/tree/a/b", "/tree/a", "/tree"}
+	if strings.Join(removed, " ") != strings.Join(want, " ") {
+		t.Errorf("expected removals %v, got %v", want, removed)
+	}
+}
+
+func TestPermanentDeleteCountsFS(t *testing.T) {
+	fsys := memFS{fstest.MapFS{
+		"project/a.txt":     {Data: []byte("data")},
+		"project/sub/b.txt": {Data: []byte("data")},
+	}}
+	m := &FileManager{CurrentPath: "/", fs: fsys}
+	m.Entries, _ = m.readDirectory(m.CurrentPath)
+	m.selectByName("project")
+
+	// What the directory holds is read from the file manager's filesystem
+	m.handleDeleteCounted(m.confirmPermanentDelete()().(deleteCountedMsg))
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "2 files, 8B") {
+		t.Fatalf("expected the contents in the prompt, got %+v", m.confirm)
+	}
+}
+
+func TestPermanentDeleteNonEmptyDir(t *testing.T) {
+	root := t.TempDir()
+	full := filepath.Join(root, "full")
+	if err := os.MkdirAll(filepath.Join(full, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", filepath.Join("sub", "b.txt")} {
+		if err := os.WriteFile(filepath.Join(full, name), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: root}
+	m.Entries, _ = m.readDirectory(root)
+	press := func(key string) {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+
+	// An empty directory needs a single confirmation
+	m.selectByName("empty")
+	m.confirmPermanentDelete()
+	if m.confirm == nil || strings.Contains(m.confirm.prompt, "everything") {
+		t.Fatalf("expected the plain prompt, got %+v", m.confirm)
+	}
+	press("n")
+
+	// The prompt shows before the directory is counted
+	m.selectByName("full")
+	cmd := m.confirmPermanentDelete()
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "counting") {
+		t.Fatalf("expected the prompt while counting, got %+v", m.confirm)
+	}
+	m.Update(cmd())
+	if !strings.Contains(m.confirm.prompt, "2 files") {
+		t.Fatalf("expected the item count in the prompt, got %+v", m.confirm)
+	}
+	press("y")
+	// Repeating y doesn't get past the second confirmation
+	press("y")
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "[D]elete") || m.deleting != nil {
+		t.Fatalf("expected a second confirmation, got %+v", m.confirm)
+	}
+
+	press("D")
+	if m.deleting == nil {
+		t.Fatal("expected the delete to start")
+	}
+	// Wait for the background delete to finish
+	for {
+		msg := <-m.deleting.updates
+		if _, ok := msg.(deleteDoneMsg); ok {
+			break
+		}
+	}
+	if _, err := os.Stat(full); !os.IsNotExist(err) {
+		t.Fatalf("directory still exists: %v", err)
+	}
+	// Until the delete is reported done, esc only stops it and leaves the
+	// selection alone
+	m.selectByName("empty")
+	selected := m.Cursor
+	m.toggleSelected()
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if !m.Entries[selected].Selected {
+		t.Error("expected esc to keep the selection while deleting")
+	}
+}
@@ -0,0 +1,101 @@
+package browser
+
+import (
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Directories counted per background batch, so counts fill in progressively
+const dirCountBatch = 16
+
+// Custom message carrying item counts for a batch of directories
+type dirCountsMsg struct {
+	dir    string         // Directory being listed when counting started
+	counts map[string]int // Item count by directory path (-1 if unreadable)
+	rest   []string       // Directories still to count
+}
+
+// countDirsCmd counts the items of a batch of directories in the background
+func countDirsCmd(fsys FS, dir string, paths []string) tea.Cmd {
+	return func() tea.Msg {
+		batch := paths[:min(len(paths), dirCountBatch)]
+		counts := make(map[string]int, len(batch))
+		for _, path := range batch {
+			counts[path] = countItems(fsys, path)
+		}
+		return dirCountsMsg{dir: dir, counts: counts, rest: paths[len(batch):]}
+	}
+}
+
+// countItems returns the number of entries in a directory, reading only
+// names where fsys is the OS
+func countItems(fsys FS, path string) int {
+	if fsys != OS {
+		entries, err := fsys.ReadDir(path)
+		if err != nil {
+			return -1
+		}
+		return len(entries)
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		return -1
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return -1
+	}
+	return len(names)
+}
+
+// startDirCounts schedules counting for listed directories that have no
+// cached count. Without counts in the listing only the selected directory
+// is counted, for its file info.
+func (m *FileManager) startDirCounts() tea.Cmd {
+	if m.countingDirs {
+		return nil
+	}
+
+	// Counts are shown next to directories, or in the size column
+	entries := m.Entries
+	if !(m.showDirCounts || m.showSizes) {
+		if m.Cursor >= len(m.Entries) {
+			return nil
+		}
+		entries = m.Entries[m.Cursor : m.Cursor+1]
+	}
+	var missing []string
+	for _, entry := range entries {
+		if _, ok := m.dirCounts[entry.Path]; entry.IsDir && !ok {
+			missing = append(missing, entry.Path)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	m.countingDirs = true
+	m.startOp()
+	return countDirsCmd(m.filesystem(), m.CurrentPath, missing)
+}
+
+// handleDirCounts stores a batch of counts and continues with the rest
+func (m *FileManager) handleDirCounts(msg dirCountsMsg) tea.Cmd {
+	if m.dirCounts == nil {
+		m.dirCounts = make(map[string]int)
+	}
+	for path, count := range msg.counts {
+		m.dirCounts[path] = count
+	}
+
+	// Stop early if we navigated away; the new listing starts its own counts
+	if len(msg.rest) > 0 && msg.dir == m.CurrentPath {
+		return countDirsCmd(m.filesystem(), msg.dir, msg.rest)
+	}
+	m.countingDirs = false
+	m.finishOp()
+	return nil
+}
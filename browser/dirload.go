@@ -0,0 +1,164 @@
+package browser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Listings kept in the cache at most; past that it starts over
+const listingCacheSize = 64
+
+// Custom message carrying a directory listing read in the background
+type dirLoadedMsg struct {
+	path     string
+	entries  []FileEntry
+	err      error
+	tooLarge bool // Left unread for being above large_dir_threshold
+	listing  bool // Read for the parent column or the preview, not to enter
+}
+
+// dirListing is a listing of the parent directory or a previewed one
+type dirListing struct {
+	entries []FileEntry
+	err     error
+	seq     int // listingSeq when it was read
+}
+
+// exceedsItems reports whether a directory in fsys holds more than limit
+// entries, reading at most limit+1 names where fsys is the OS
+func exceedsItems(fsys FS, path string, limit int) bool {
+	if fsys != OS {
+		entries, err := fsys.ReadDir(path)
+		return err == nil && len(entries) > limit
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(limit + 1)
+	if err != nil && err != io.EOF {
+		return false
+	}
+	return len(names) > limit
+}
+
+// confirmLargeDirectory asks before entering a directory found to be above
+// the large_dir_threshold
+func (m *FileManager) confirmLargeDirectory(path string) {
+	m.confirm = &confirmation{
+		prompt: fmt.Sprintf("%s has more than %d entries, open it? [y]es  [n]o", filepath.Base(path), m.largeDirThreshold),
+		actions: map[string]func() tea.Cmd{
+			"y": func() tea.Cmd { return m.loadDirectory(path, "", 0) },
+			"n": func() tea.Cmd { return nil },
+		},
+	}
+}
+
+// loadDirectory reads a directory in the background and enters it once
+// read, with the cursor on selectName, or on the first entry. Until then the
+// listing shows a placeholder, so a slow mount doesn't freeze the UI. One
+// with more than limit entries asks first instead (0 never asks).
+func (m *FileManager) loadDirectory(path, selectName string, limit int) tea.Cmd {
+	m.loadingDir = path
+	m.loadingSelect = selectName
+	m.startOp()
+
+	// Copy the options so the command doesn't share state with the model
+	opts, dirsOnly := m.listOptions, m.dirsOnly
+	opts.FS = m.filesystem()
+	return func() tea.Msg {
+		if limit > 0 && exceedsItems(opts.FS, path, limit) {
+			return dirLoadedMsg{path: path, tooLarge: true}
+		}
+		entries, err := listDirectory(path, opts, dirsOnly)
+		return dirLoadedMsg{path: path, entries: entries, err: err}
+	}
+}
+
+// handleDirLoaded enters a directory read in the background, unless another
+// load was started since. One that couldn't be read is not entered. A
+// listing read for the parent column or the preview is cached.
+func (m *FileManager) handleDirLoaded(msg dirLoadedMsg) {
+	m.finishOp()
+	if msg.listing {
+		delete(m.listingsLoading, msg.path)
+		m.cacheListing(msg.path, msg.entries, msg.err)
+		return
+	}
+	if msg.path != m.loadingDir {
+		return
+	}
+	m.loadingDir = ""
+	if msg.tooLarge {
+		m.confirmLargeDirectory(msg.path)
+		return
+	}
+	if msg.err != nil {
+		m.setError(msg.err)
+		return
+	}
+	// The directory left is shown beside the new one, as its parent or preview
+	if !m.dirsOnly {
+		m.cacheListing(m.CurrentPath, m.Entries, nil)
+	}
+	m.CurrentPath = msg.path
+	m.Entries = msg.entries
+	if m.loadingSelect == "" || !m.selectByName(m.loadingSelect) {
+		m.Cursor = 0
+	}
+}
+
+// startListings reads in the background the listings shown beside the
+// current directory that aren't cached, or were cached before a reload
+func (m *FileManager) startListings() tea.Cmd {
+	// Nothing is drawn before the first WindowSizeMsg
+	if m.Width == 0 && m.Height == 0 {
+		return nil
+	}
+	var cmds []tea.Cmd
+	for _, path := range m.listingsShown() {
+		if listing, ok := m.listings[path]; ok && listing.seq == m.listingSeq || m.listingsLoading[path] {
+			continue
+		}
+		if m.listingsLoading == nil {
+			m.listingsLoading = make(map[string]bool)
+		}
+		m.listingsLoading[path] = true
+		m.startOp()
+		opts := m.fsListOptions()
+		cmds = append(cmds, func() tea.Msg {
+			entries, err := listDirectory(path, opts, false)
+			return dirLoadedMsg{path: path, entries: entries, err: err, listing: true}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// listingsShown returns the directories listed beside the current one: its
+// parent, and the selected directory when it is previewed. The minimal view
+// reads the parent too, though hidden, so H can focus it.
+func (m *FileManager) listingsShown() []string {
+	var paths []string
+	if parent := filepath.Dir(m.CurrentPath); parent != m.CurrentPath {
+		paths = append(paths, parent)
+	}
+	if !m.minimal && m.autoPreview && m.previewLines == nil && m.Cursor < len(m.Entries) && m.Entries[m.Cursor].IsDir {
+		paths = append(paths, m.Entries[m.Cursor].Path)
+	}
+	return paths
+}
+
+// cacheListing keeps a listing of a directory for the parent column and
+// the preview
+func (m *FileManager) cacheListing(path string, entries []FileEntry, err error) {
+	if m.listings == nil || len(m.listings) >= listingCacheSize {
+		m.listings = make(map[string]dirListing)
+	}
+	m.listings[path] = dirListing{entries: entries, err: err, seq: m.listingSeq}
+}
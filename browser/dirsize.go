@@ -0,0 +1,68 @@
+package browser
+
+import (
+	"context"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Custom message carrying the total size of the files under a directory
+type dirSizeMsg struct {
+	path  string // Directory that was walked
+	size  int64  // Bytes in all files below it
+	files int    // How many files were found
+	err   error
+}
+
+// computeDirSize shows the total size of the selected directory, walking it
+// in the background unless it was already computed
+func (m *FileManager) computeDirSize() tea.Cmd {
+	if m.Cursor >= len(m.Entries) {
+		return nil
+	}
+	entry := m.Entries[m.Cursor]
+	if !entry.IsDir {
+		m.setStatus("%s is not a directory", entry.Name)
+		return nil
+	}
+	if m.archive != nil {
+		m.setStatus("Can't compute sizes inside an archive")
+		return nil
+	}
+	if size, ok := m.dirSizes[entry.Path]; ok {
+		m.setStatus("%s: %s", entry.Name, HumanSize(size))
+		return nil
+	}
+	if m.sizingDirs[entry.Path] {
+		return nil
+	}
+
+	if m.sizingDirs == nil {
+		m.sizingDirs = make(map[string]bool)
+	}
+	m.sizingDirs[entry.Path] = true
+	m.startOp()
+	m.setStatus("Computing the size of %s", entry.Name)
+	path := entry.Path
+	fsys := m.filesystem()
+	return func() tea.Msg {
+		size, files, err := dirSize(context.Background(), fsys, path)
+		return dirSizeMsg{path: path, size: size, files: files, err: err}
+	}
+}
+
+// handleDirSize caches a computed size and shows it
+func (m *FileManager) handleDirSize(msg dirSizeMsg) {
+	delete(m.sizingDirs, msg.path)
+	m.finishOp()
+	if msg.err != nil {
+		m.setError(msg.err)
+		return
+	}
+	if m.dirSizes == nil {
+		m.dirSizes = make(map[string]int64)
+	}
+	m.dirSizes[msg.path] = msg.size
+	m.setStatus("%s: %s in %s", filepath.Base(msg.path), HumanSize(msg.size), pluralize(msg.files, "file"))
+}
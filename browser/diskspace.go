@@ -0,0 +1,76 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// spaceFS is implemented by filesystems that can tell how much space is free
+type spaceFS interface {
+	Available(dir string) (uint64, error)
+}
+
+func (osFS) Available(dir string) (uint64, error) { return availableSpace(dir) }
+
+// Custom message carrying whether what is about to be copied fits; paste
+// goes on with the copy
+type spaceCheckedMsg struct {
+	dir   string // Directory pasted into
+	err   error  // Why it doesn't fit
+	paste func() tea.Cmd
+}
+
+// checkSpace walks the files at paths in the background and calls paste
+// once they are found to fit in the space left in the current directory.
+// When the free space can't be told, the copy is left to try.
+func (m *FileManager) checkSpace(paths []string, paste func() tea.Cmd) tea.Cmd {
+	fsys := m.filesystem()
+	space, ok := fsys.(spaceFS)
+	if !ok || len(paths) == 0 {
+		return paste()
+	}
+	m.checkingSpace = true
+	m.startOp()
+	dir := m.CurrentPath
+	return func() tea.Msg {
+		return spaceCheckedMsg{dir: dir, err: fits(fsys, space, dir, paths), paste: paste}
+	}
+}
+
+// handleSpaceChecked goes on with a paste whose files fit, unless the
+// current directory has changed meanwhile
+func (m *FileManager) handleSpaceChecked(msg spaceCheckedMsg) tea.Cmd {
+	m.checkingSpace = false
+	m.finishOp()
+	if msg.err != nil {
+		m.setError(msg.err)
+		return nil
+	}
+	if m.CurrentPath != msg.dir {
+		m.setStatus("Not pasting, left %s", filepath.Base(msg.dir))
+		return nil
+	}
+	return msg.paste()
+}
+
+// fits fails when the files at paths in fsys don't fit in the space left
+// where dir is
+func fits(fsys FS, space spaceFS, dir string, paths []string) error {
+	free, err := space.Available(dir)
+	if err != nil {
+		return nil
+	}
+	var need int64
+	for _, path := range paths {
+		// A file walks as itself; symlinks count as links, not followed
+		size, _, _ := dirSize(context.Background(), fsys, path)
+		need += size
+	}
+	if need > 0 && uint64(need) > free {
+		return fmt.Errorf("not enough space to copy: %s needed, %s free", HumanSize(need), HumanSize(int64(free)))
+	}
+	return nil
+}
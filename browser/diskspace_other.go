@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package browser
+
+import "errors"
+
+// availableSpace can't tell the free space on this platform
+func availableSpace(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
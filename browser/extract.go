@@ -0,0 +1,214 @@
+package browser
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// extractJob is an archive being extracted in the background
+type extractJob struct {
+	archive   string       // Archive being extracted
+	dest      string       // Directory it is extracted into
+	extracted int          // Files written so far
+	updates   chan tea.Msg // Progress and completion messages
+	redo      *UndoAction  // Undone extraction being redone, if it is one
+}
+
+// Custom message reporting how many files a running extraction has written
+type extractProgressMsg struct {
+	extracted int
+}
+
+// Custom message sent when an extraction finishes or fails
+type extractDoneMsg struct {
+	extracted int
+	err       error
+}
+
+// archiveStem returns an archive's name without its archive extension,
+// like "photos" for photos.tar.gz
+func archiveStem(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) && len(name) > len(ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// extractArchive writes the members of an archive below dest, calling
+// progress after each file. A member whose path would lead outside dest
+// fails the whole extraction; links and other special members are skipped.
+func extractArchive(fsys FS, archivePath, dest string, progress func(extracted int)) (int, error) {
+	extracted := 0
+	err := listArchive(fsys, archivePath, func(name string, info fs.FileInfo, open func() (io.ReadCloser, error)) error {
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if rel, err := filepath.Rel(dest, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s leads outside the archive, not extracted", name)
+		}
+		if info.IsDir() {
+			return fsys.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if err := fsys.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		src, err := open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := fsys.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, src); err != nil {
+			dst.Close()
+			return err
+		}
+		if err := dst.Close(); err != nil {
+			return err
+		}
+		copyMetadata(fsys, target, info)
+		extracted++
+		progress(extracted)
+		return nil
+	})
+	return extracted, err
+}
+
+// startExtract extracts the selected archive into a new directory next to
+// it, named after it, in the background
+func (m *FileManager) startExtract() tea.Cmd {
+	if m.Cursor >= len(m.Entries) || m.Entries[m.Cursor].IsDir || !isArchive(m.Entries[m.Cursor].Name) {
+		m.setStatus("Not an archive")
+		return nil
+	}
+	if m.extracting != nil {
+		m.setStatus("Already extracting %s", filepath.Base(m.extracting.archive))
+		return nil
+	}
+	entry := m.Entries[m.Cursor]
+	dest := uniquePath(m.filesystem(), filepath.Join(m.CurrentPath, archiveStem(entry.Name)))
+	cmd, err := m.extractInto(entry.Path, dest, nil)
+	if err != nil {
+		m.setError(err)
+	}
+	return cmd
+}
+
+// redoExtract extracts an undone extraction again in the background, into
+// the directory it made before. It goes back on the undo stack once done.
+func (m *FileManager) redoExtract(action UndoAction) (tea.Cmd, error) {
+	if m.extracting != nil {
+		return nil, fmt.Errorf("already extracting %s", filepath.Base(m.extracting.archive))
+	}
+	// Whatever took its place since isn't extracted into
+	if _, err := m.filesystem().Stat(action.NewPath); err == nil {
+		return nil, &fs.PathError{Op: "extract", Path: action.NewPath, Err: fs.ErrExist}
+	}
+	return m.extractInto(action.OldPath, action.NewPath, &action)
+}
+
+// extractInto creates dest and extracts the archive into it in the
+// background, for redo when redo is set
+func (m *FileManager) extractInto(archive, dest string, redo *UndoAction) (tea.Cmd, error) {
+	fsys := m.filesystem()
+	m.runHook("pre_create", dest, "")
+	if err := fsys.MkdirAll(dest, 0755); err != nil {
+		return nil, err
+	}
+
+	job := &extractJob{
+		archive: archive,
+		dest:    dest,
+		updates: make(chan tea.Msg, 1),
+		redo:    redo,
+	}
+	m.extracting = job
+	m.startOp()
+
+	go func() {
+		extracted, err := extractArchive(fsys, job.archive, job.dest, func(extracted int) {
+			// Drop updates the UI hasn't caught up with; the next one carries the total
+			select {
+			case job.updates <- extractProgressMsg{extracted: extracted}:
+			default:
+			}
+		})
+		if err != nil {
+			// Don't leave a partial extraction behind
+			fsys.RemoveAll(job.dest)
+		}
+		job.updates <- extractDoneMsg{extracted: extracted, err: err}
+	}()
+
+	return waitForExtract(job), nil
+}
+
+// waitForExtract waits for the next message from a running extraction
+func waitForExtract(job *extractJob) tea.Cmd {
+	return func() tea.Msg {
+		return <-job.updates
+	}
+}
+
+// handleExtractProgress records progress and keeps listening
+func (m *FileManager) handleExtractProgress(msg extractProgressMsg) tea.Cmd {
+	if m.extracting == nil {
+		return nil
+	}
+	m.extracting.extracted = msg.extracted
+	return waitForExtract(m.extracting)
+}
+
+// handleExtractDone reports how the extraction ended and selects the new
+// directory
+func (m *FileManager) handleExtractDone(msg extractDoneMsg) {
+	if m.extracting == nil {
+		return
+	}
+	m.finishOp()
+	job := m.extracting
+	m.extracting = nil
+	if msg.err != nil {
+		if job.redo != nil {
+			// Keep it to retry
+			m.redoStack = append(m.redoStack, *job.redo)
+		}
+		m.reloadKeepingSelection()
+		m.setError(fmt.Errorf("extracting %s: %w", filepath.Base(job.archive), msg.err))
+		return
+	}
+
+	m.runHook("post_create", job.dest, "")
+	name := filepath.Base(job.dest)
+	if job.redo != nil {
+		m.undoStack = append(m.undoStack, *job.redo)
+	} else {
+		m.pushUndo(UndoAction{
+			Type:    "extract",
+			OldPath: job.archive,
+			NewPath: job.dest,
+			Entry:   FileEntry{Name: name, Path: job.dest, IsDir: true},
+		})
+	}
+	if filepath.Dir(job.dest) == m.CurrentPath {
+		m.reloadEntries()
+		m.selectByName(name)
+	}
+	if job.redo != nil {
+		m.setStatus("Redid extract of %s into %s/", filepath.Base(job.archive), name)
+	} else {
+		m.setStatus("Extracted %s into %s/", pluralize(msg.extracted, "file"), name)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package browser
+
+import "io/fs"
+
+// fileOwner reports no ownership on platforms without Unix file stats, like
+// Windows, where files have ACLs rather than a single owner and group, so
+// the columns are left out.
+func fileOwner(info fs.FileInfo) (owner, group string, ok bool) {
+	return "", "", false
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package browser
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner resolves the owner and group names of a file, falling back to
+// the numeric ids when they have no name. Archive members carry no
+// ownership and show "-" for both.
+func fileOwner(info fs.FileInfo) (owner, group string, ok bool) {
+	stat, isStat := info.Sys().(*syscall.Stat_t)
+	if !isStat {
+		return "-", "-", true
+	}
+
+	owner = strconv.FormatUint(uint64(stat.Uid), 10)
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+
+	group = strconv.FormatUint(uint64(stat.Gid), 10)
+	if g, err := user.LookupGroupId(group); err == nil {
+		group = g.Name
+	}
+	return owner, group, true
+}
@@ -0,0 +1,137 @@
+package browser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Style of the characters a search matched
+var matchStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("220")).
+	Underline(true)
+
+// filteredEntry is an entry listed while searching
+type filteredEntry struct {
+	index     int   // Position in Entries
+	positions []int // Runes of the name the query matched
+}
+
+// startSearch enters search mode, remembering the cursor for esc
+func (m *FileManager) startSearch() {
+	m.searchMode = true
+	m.searchQuery = ""
+	m.searchCursor = m.Cursor
+	m.filteredEntries = nil
+}
+
+// cancelSearch leaves search mode, back to the whole listing and the entry
+// selected before searching
+func (m *FileManager) cancelSearch() {
+	m.searchMode = false
+	m.searchQuery = ""
+	m.filteredEntries = nil
+	m.Cursor = max(min(m.searchCursor, len(m.Entries)-1), 0)
+}
+
+// filterEntries narrows the listing to the entries matching the search
+// typed so far, best first, and selects the best
+func (m *FileManager) filterEntries() {
+	m.filteredEntries = nil
+	if m.searchQuery == "" {
+		m.Cursor = max(min(m.searchCursor, len(m.Entries)-1), 0)
+		return
+	}
+
+	names := make([]string, len(m.Entries))
+	for i, entry := range m.Entries {
+		names[i] = entry.Name
+	}
+	for _, i := range rankMatches(m.searchQuery, names, !m.substring) {
+		m.filteredEntries = append(m.filteredEntries, filteredEntry{
+			index:     i,
+			positions: matchPositions(m.searchQuery, names[i], !m.substring),
+		})
+	}
+	if len(m.filteredEntries) > 0 {
+		m.Cursor = m.filteredEntries[0].index
+	}
+}
+
+// matchPositions returns the positions of the runes in name that query
+// matches, ignoring case
+func matchPositions(query, name string, fuzzy bool) []int {
+	if fuzzy {
+		_, positions, _ := fuzzyMatch(query, name)
+		return positions
+	}
+	q, n := lowerRunes(query), lowerRunes(name)
+	for start := 0; start+len(q) <= len(n); start++ {
+		if slices.Equal(n[start:start+len(q)], q) {
+			positions := make([]int, len(q))
+			for i := range positions {
+				positions[i] = start + i
+			}
+			return positions
+		}
+	}
+	return nil
+}
+
+// highlightMatches renders name cut to width in style, with the runes at
+// positions highlighted
+func highlightMatches(name string, positions []int, width int, style lipgloss.Style) string {
+	shown := []rune(truncateRight(name, width, "…"))
+	if len(shown) < len([]rune(name)) {
+		// The ellipsis stands in for the rest, matched or not
+		positions = slices.DeleteFunc(slices.Clone(positions), func(p int) bool { return p >= len(shown)-1 })
+	}
+
+	var line strings.Builder
+	start := 0
+	for start < len(shown) {
+		matched := slices.Contains(positions, start)
+		end := start + 1
+		for end < len(shown) && slices.Contains(positions, end) == matched {
+			end++
+		}
+		if matched {
+			line.WriteString(matchStyle.Render(string(shown[start:end])))
+		} else {
+			line.WriteString(style.Render(string(shown[start:end])))
+		}
+		start = end
+	}
+	return line.String()
+}
+
+// renderFiltered renders the entries matching the search, best first, in
+// place of the listing
+func (m *FileManager) renderFiltered(nameWidth, height int) string {
+	if len(m.filteredEntries) == 0 {
+		return emptyStateStyle.Render(fmt.Sprintf("No match for %q", m.searchQuery))
+	}
+
+	var list strings.Builder
+	for i, filtered := range m.filteredEntries[:min(len(m.filteredEntries), height)] {
+		entry := m.Entries[filtered.index]
+		style := lipgloss.NewStyle()
+		if entry.IsDir {
+			style = dirStyle
+		}
+		line := highlightMatches(entry.Name, filtered.positions, nameWidth, style)
+		if entry.IsDir {
+			line += dirStyle.Render("/")
+		}
+		line = iconFor(m.icons, entry) + line
+		if i == 0 {
+			line = selectedStyle.Render("> ") + line
+		} else {
+			line = "  " + line
+		}
+		list.WriteString(line + "\n")
+	}
+	return list.String()
+}
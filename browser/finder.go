@@ -0,0 +1,310 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	maxFinderFiles  = 100000 // Files the internal finder lists at most
+	finderBatchSize = 2000   // Files found before the finder is updated
+	finderRows      = 10     // Matches shown in the internal finder
+)
+
+// fileFinder is the internal fuzzy finder, used when fzf isn't installed
+type fileFinder struct {
+	root    string   // Directory searched
+	files   []string // Files under root found so far, relative to it
+	query   string   // Typed so far
+	matches []int    // Files matching query, best first
+	cursor  int      // Selected match
+
+	walking bool                // Whether files are still being found
+	cancel  context.CancelFunc  // Stops the walk (esc)
+	updates chan finderFilesMsg // Files found by the walk
+}
+
+// Custom message carrying files found by the internal finder's walk
+type finderFilesMsg struct {
+	finder *fileFinder // Finder the files are for
+	files  []string    // Files found since the last message
+	done   bool        // Whether the walk is over
+	err    error       // Why the walk failed
+}
+
+// fzfDoneMsg is sent when fzf exits
+type fzfDoneMsg struct {
+	root   string // Directory fzf searched
+	output string // What fzf printed: the picked file, relative to root
+	err    error
+}
+
+// walkFindable calls fn with every file under root, relative to it, leaving
+// out the hidden files and directories the listing leaves out. Unreadable
+// directories are skipped. The walk stops when fn returns an error.
+func walkFindable(root string, opts ListOptions, fn func(rel string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if path == root {
+			return err
+		}
+		if err != nil || !opts.showEntry(d.Name()) {
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		return fn(rel)
+	})
+}
+
+// findFile finds a file anywhere under the current directory with fzf,
+// or with the internal finder when fzf isn't installed
+func (m *FileManager) findFile() tea.Cmd {
+	if m.archive != nil {
+		m.setStatus("Can't search for files inside an archive")
+		return nil
+	}
+	if _, err := exec.LookPath("fzf"); err != nil {
+		return m.openFinder()
+	}
+
+	// fzf lists files while they are found rather than after the walk
+	root, opts := m.CurrentPath, m.listOptions
+	files, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(walkFindable(root, opts, func(rel string) error {
+			_, err := io.WriteString(writer, rel+"\n")
+			return err
+		}))
+	}()
+
+	var output strings.Builder
+	cmd := exec.Command("fzf")
+	cmd.Dir = root
+	cmd.Stdin = files
+	cmd.Stdout = &output
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		// fzf is done reading; stop the walk if it hasn't finished
+		files.Close()
+		return fzfDoneMsg{root: root, output: output.String(), err: err}
+	})
+}
+
+// handleFzfDone goes to the file picked in fzf
+func (m *FileManager) handleFzfDone(msg fzfDoneMsg) {
+	var exitErr *exec.ExitError
+	if errors.As(msg.err, &exitErr) {
+		// fzf exits with an error when cancelled or nothing matched
+		return
+	} else if msg.err != nil {
+		m.setError(fmt.Errorf("running fzf: %w", msg.err))
+		return
+	}
+
+	rel := strings.TrimSpace(msg.output)
+	if rel == "" {
+		return
+	}
+	m.revealFile(filepath.Join(msg.root, rel))
+}
+
+// revealFile goes to the directory holding path with the cursor on it
+func (m *FileManager) revealFile(path string) {
+	if !m.openDir(filepath.Dir(path), filepath.Base(path)) {
+		return
+	}
+	if m.Cursor >= len(m.Entries) || m.Entries[m.Cursor].Path != path {
+		m.setError(fmt.Errorf("%s no longer exists", path))
+	}
+}
+
+// openFinder opens the internal finder on the current directory, listing
+// the files under it as the walk in the background finds them
+func (m *FileManager) openFinder() tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &fileFinder{
+		root:    m.CurrentPath,
+		walking: true,
+		cancel:  cancel,
+		updates: make(chan finderFilesMsg),
+	}
+	m.finder = f
+	m.startOp()
+
+	root, opts := m.CurrentPath, m.listOptions
+	go func() {
+		// Closing updates lets a wait for a finder closed meanwhile end
+		defer close(f.updates)
+		send := func(msg finderFilesMsg) bool {
+			select {
+			case f.updates <- msg:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		var batch []string
+		found := 0
+		errFull := errors.New("finder full")
+		err := walkFindable(root, opts, func(rel string) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			batch = append(batch, rel)
+			if found++; found == maxFinderFiles {
+				return errFull
+			}
+			if len(batch) == finderBatchSize {
+				if !send(finderFilesMsg{files: batch}) {
+					return ctx.Err()
+				}
+				batch = nil
+			}
+			return nil
+		})
+		if errors.Is(err, errFull) {
+			err = nil
+		}
+		send(finderFilesMsg{files: batch, done: true, err: err})
+	}()
+
+	return waitForFinder(f)
+}
+
+// waitForFinder waits for the next files found by the finder's walk
+func waitForFinder(f *fileFinder) tea.Cmd {
+	return func() tea.Msg {
+		msg := <-f.updates
+		msg.finder = f
+		return msg
+	}
+}
+
+// handleFinderFiles adds the files found by the walk to the finder, keeping
+// the selected match, and keeps listening until the walk is over
+func (m *FileManager) handleFinderFiles(msg finderFilesMsg) tea.Cmd {
+	f := msg.finder
+	if m.finder != f {
+		// Closed while walking; the walk has been stopped
+		return nil
+	}
+	if msg.done {
+		f.walking = false
+		m.finishOp()
+	}
+
+	f.files = append(f.files, msg.files...)
+	cursor := f.cursor
+	f.filter()
+	f.cursor = min(cursor, max(len(f.matches)-1, 0))
+	if !msg.done {
+		return waitForFinder(f)
+	}
+
+	if msg.err != nil {
+		m.closeFinder()
+		m.setError(msg.err)
+	} else if len(f.files) == 0 {
+		m.closeFinder()
+		m.setStatus("No files under %s", f.root)
+	}
+	return nil
+}
+
+// closeFinder closes the internal finder, stopping its walk
+func (m *FileManager) closeFinder() {
+	if m.finder == nil {
+		return
+	}
+	if m.finder.walking {
+		m.finishOp()
+	}
+	m.finder.cancel()
+	m.finder = nil
+}
+
+// filter ranks the files against the query, selecting the best
+func (f *fileFinder) filter() {
+	f.cursor = 0
+	if f.query == "" {
+		f.matches = make([]int, len(f.files))
+		for i := range f.matches {
+			f.matches[i] = i
+		}
+		return
+	}
+	f.matches = rankMatches(f.query, f.files, true)
+}
+
+// handleFinderKey handles keys while the internal finder is open
+func (m *FileManager) handleFinderKey(msg tea.KeyMsg) tea.Cmd {
+	f := m.finder
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m.quit()
+	case tea.KeyEsc:
+		m.closeFinder()
+	case tea.KeyUp, tea.KeyCtrlP:
+		f.cursor = max(f.cursor-1, 0)
+	case tea.KeyDown, tea.KeyCtrlN:
+		f.cursor = max(min(f.cursor+1, len(f.matches)-1), 0)
+	case tea.KeyEnter:
+		if len(f.matches) == 0 {
+			return nil
+		}
+		m.closeFinder()
+		m.revealFile(filepath.Join(f.root, f.files[f.matches[f.cursor]]))
+	case tea.KeyBackspace:
+		if len(f.query) > 0 {
+			runes := []rune(f.query)
+			f.query = string(runes[:len(runes)-1])
+			f.filter()
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		f.query += string(msg.Runes)
+		f.filter()
+	}
+	return nil
+}
+
+// renderFinder renders the internal finder overlay: the query and the best
+// matches, scrolled to keep the selected one shown
+func (m *FileManager) renderFinder() string {
+	f := m.finder
+	var content strings.Builder
+	found := fmt.Sprint(len(f.files))
+	if f.walking {
+		found += "…"
+	}
+	fmt.Fprintf(&content, "Find file (%d/%s): %s█", len(f.matches), found, f.query)
+
+	top := max(f.cursor-finderRows+1, 0)
+	width := max(m.Width-6, 0)
+	for i := top; i < min(top+finderRows, len(f.matches)); i++ {
+		rel := f.files[f.matches[i]]
+		line := highlightMatches(rel, matchPositions(f.query, rel, true), width, lipgloss.NewStyle())
+		if i == f.cursor {
+			line = selectedStyle.Render("> ") + line
+		} else {
+			line = "  " + line
+		}
+		content.WriteString("\n" + line)
+	}
+	return whichKeyStyle.Width(m.Width).Render(content.String())
+}
@@ -0,0 +1,116 @@
+package browser
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// FS is the filesystem that listings and file operations work on. OS is
+// used unless another one is given, e.g. a wrapper that injects failures in tests.
+type FS interface {
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Rename(oldpath, newpath string) error
+	RemoveAll(path string) error
+}
+
+// OS is the FS backed by the os package
+var OS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Open(name string) (io.ReadCloser, error)    { return os.Open(name) }
+func (osFS) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+func (osFS) Rename(oldpath, newpath string) error { return os.Rename(oldpath, newpath) }
+func (osFS) RemoveAll(path string) error          { return os.RemoveAll(path) }
+
+// metadataFS is implemented by filesystems that keep permissions and times
+type metadataFS interface {
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+func (osFS) Chmod(name string, mode fs.FileMode) error { return os.Chmod(name, mode) }
+func (osFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// copyMetadata gives dst the permissions and modification time in info, as
+// far as fsys keeps them. The access time is set to the modification time,
+// since reading the source to copy it has just touched its own. It is best
+// effort: a filesystem that refuses them still gets the contents.
+func copyMetadata(fsys FS, dst string, info fs.FileInfo) {
+	meta, ok := fsys.(metadataFS)
+	if !ok {
+		return
+	}
+	meta.Chmod(dst, info.Mode().Perm())
+	meta.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// exclusiveFS is implemented by filesystems that can create a file only if
+// it doesn't exist yet, atomically
+type exclusiveFS interface {
+	CreateExclusive(name string) (io.WriteCloser, error)
+}
+
+func (osFS) CreateExclusive(name string) (io.WriteCloser, error) {
+	return os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+}
+
+// createExclusive creates name in fsys, failing with fs.ErrExist if it is
+// already there. Filesystems that can't do that atomically are checked
+// first, which only keeps out users that don't race each other.
+func createExclusive(fsys FS, name string) (io.WriteCloser, error) {
+	if excl, ok := fsys.(exclusiveFS); ok {
+		return excl.CreateExclusive(name)
+	}
+	if _, err := fsys.Stat(name); err == nil {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrExist}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return fsys.Create(name)
+}
+
+// readFile reads a whole file from fsys
+func readFile(fsys FS, name string) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// readFileHead reads at most limit bytes from the start of a file in fsys
+func readFileHead(fsys FS, name string, limit int64) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, limit))
+}
+
+// filesystem returns the FS the file manager operates on
+func (m *FileManager) filesystem() FS {
+	if m.archive != nil {
+		return m.archive
+	}
+	if m.fs != nil {
+		return m.fs
+	}
+	return OS
+}
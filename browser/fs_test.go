@@ -0,0 +1,551 @@
+package browser
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// exdevFS fails renames like a move across mounts does
+type exdevFS struct{ FS }
+
+func (exdevFS) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+}
+
+// failingFS fails the chosen operations with errFailed
+type failingFS struct {
+	FS
+	create, write, rename bool
+}
+
+var errFailed = errors.New("injected failure")
+
+func (f failingFS) Create(name string) (io.WriteCloser, error) {
+	if f.create {
+		return nil, errFailed
+	}
+	file, err := f.FS.Create(name)
+	if err != nil || !f.write {
+		return file, err
+	}
+	return failingWriter{file}, nil
+}
+
+// failingWriter writes a byte and then fails, like a disk filling up
+type failingWriter struct{ io.WriteCloser }
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	n, _ := w.WriteCloser.Write(p[:min(len(p), 1)])
+	return n, errFailed
+}
+
+func (f failingFS) Rename(oldpath, newpath string) error {
+	if f.rename {
+		return errFailed
+	}
+	return f.FS.Rename(oldpath, newpath)
+}
+
+// fullDiskFS reports only free bytes of space
+type fullDiskFS struct {
+	FS
+	free uint64
+}
+
+func (f fullDiskFS) Available(string) (uint64, error) { return f.free, nil }
+
+// noMetadataFS refuses permissions and times, like some network mounts
+type noMetadataFS struct{ FS }
+
+func (noMetadataFS) Chmod(string, fs.FileMode) error            { return errFailed }
+func (noMetadataFS) Chtimes(string, time.Time, time.Time) error { return errFailed }
+
+// unreadableFS fails to list dir like a directory without read permission
+type unreadableFS struct {
+	FS
+	dir string
+}
+
+func (f unreadableFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == f.dir {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.FS.ReadDir(name)
+}
+
+// memFS serves reads from an in-memory fstest.MapFS rooted at "/"
+type memFS struct{ fstest.MapFS }
+
+func (m memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(m.MapFS, m.rel(name))
+}
+func (m memFS) Stat(name string) (fs.FileInfo, error) { return fs.Stat(m.MapFS, m.rel(name)) }
+
+// The embedded MapFS.Lstat would take names not rooted at "/"
+func (m memFS) Lstat(name string) (fs.FileInfo, error) { return m.Stat(name) }
+func (m memFS) Open(name string) (io.ReadCloser, error) {
+	return m.MapFS.Open(m.rel(name))
+}
+func (memFS) Create(string) (io.WriteCloser, error) { return nil, errors.ErrUnsupported }
+func (memFS) MkdirAll(string, fs.FileMode) error    { return errors.ErrUnsupported }
+func (memFS) Rename(string, string) error           { return errors.ErrUnsupported }
+func (memFS) RemoveAll(string) error                { return errors.ErrUnsupported }
+
+func (memFS) rel(name string) string {
+	if name = strings.TrimPrefix(filepath.ToSlash(name), "/"); name == "" {
+		return "."
+	}
+	return name
+}
+
+func TestReadDirectoryFS(t *testing.T) {
+	fsys := memFS{fstest.MapFS{
+		"project/src/main.go": {},
+		"project/README.md":   {},
+		"project/.env":        {},
+		"project/.git/HEAD":   {},
+		"project/docs":        {Mode: fs.ModeDir},
+	}}
+
+	var names []string
+	for _, entry := range mustReadDirectory(t, "/project", ListOptions{FS: fsys, HiddenPatterns: []string{".env"}}) {
+		names = append(names, entry.Name)
+	}
+	want := "docs src .env README.md"
+	if got := strings.Join(names, " "); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	// With hidden files shown, directories still come first
+	names = nil
+	for _, entry := range mustReadDirectory(t, "/project", ListOptions{FS: fsys, ShowHidden: true}) {
+		names = append(names, entry.Name)
+	}
+	want = ".git docs src .env README.md"
+	if got := strings.Join(names, " "); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestNewFS(t *testing.T) {
+	fsys := memFS{fstest.MapFS{
+		"project/a.txt": {},
+		"project/b.txt": {},
+	}}
+
+	// The start directory only exists in the given filesystem
+	m, err := New(Options{StartPath: "/project", FS: fsys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if m.CurrentPath != "/project" {
+		t.Errorf("expected to start in /project, got %s", m.CurrentPath)
+	}
+	if !exceedsItems(fsys, "/project", 1) || exceedsItems(fsys, "/project", 2) {
+		t.Error("expected the entries counted in the given filesystem")
+	}
+}
+
+func TestEntryInfoFS(t *testing.T) {
+	fsys := memFS{fstest.MapFS{"notes.txt": {Data: []byte("hello")}}}
+
+	// Without a listing entry to read it from, the info comes from the entry's
+	// filesystem, not from the OS
+	entry := FileEntry{Name: "notes.txt", Path: "/notes.txt", fsys: fsys}
+	info, err := entry.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 5 {
+		t.Errorf("expected a size of 5, got %d", info.Size())
+	}
+}
+
+// readDirCountingFS counts the directories listed through it
+type readDirCountingFS struct {
+	FS
+	reads *int
+}
+
+func (f readDirCountingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	*f.reads++
+	return f.FS.ReadDir(name)
+}
+
+func TestPreviewsOffInfoReadsNothing(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub", "inner"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	reads := 0
+	fsys := readDirCountingFS{OS, &reads}
+	m := &FileManager{CurrentPath: dir, Width: 100, Height: 20, fs: fsys}
+	m.Entries, _ = m.readDirectory(dir)
+
+	// The info shown in place of the preview doesn't list the directory
+	reads = 0
+	if view := m.View(); !strings.Contains(view, "counting…") || reads != 0 {
+		t.Fatalf("expected the item count pending without a read, %d reads:\n%s", reads, view)
+	}
+	m.Update(awaitMsg[dirCountsMsg](m.startDirCounts()))
+	if view := m.View(); !strings.Contains(view, "1 item") {
+		t.Errorf("expected the counted items shown:\n%s", view)
+	}
+}
+
+func TestUnreadableDirectory(t *testing.T) {
+	dir := t.TempDir()
+	locked := filepath.Join(dir, "locked")
+	if err := os.Mkdir(locked, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fsys := unreadableFS{FS: OS, dir: locked}
+
+	entries, err := ReadDirectory(locked, ListOptions{FS: fsys})
+	if !errors.Is(err, fs.ErrPermission) || entries != nil {
+		t.Fatalf("expected a permission error, got %v and %d entries", err, len(entries))
+	}
+
+	m := &FileManager{CurrentPath: dir, fs: fsys, Width: 80, Height: 20, autoPreview: true}
+	m.Entries, _ = m.readDirectory(dir)
+	loadListings(m)
+	if got := m.renderDirPreview(locked, 40); got != unreadableMsg {
+		t.Errorf("expected the preview to say the directory can't be read, got %q", got)
+	}
+
+	// Entering it keeps the current directory and shows why
+	send(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.CurrentPath != dir || len(m.Entries) != 1 {
+		t.Fatalf("expected to stay in %s, now in %s", dir, m.CurrentPath)
+	}
+	if !m.statusIsError || !strings.Contains(m.statusMsg, "permission denied") {
+		t.Fatalf("expected a permission error in the status bar, got %q", m.statusMsg)
+	}
+	if m.loadingDir != "" {
+		t.Fatalf("load still pending for %s", m.loadingDir)
+	}
+}
+
+func TestToggleHidden(t *testing.T) {
+	fsys := memFS{fstest.MapFS{
+		"project/src/.cache/data": {},
+		"project/src/main.go":     {},
+		"project/.config/tfm":     {},
+	}}
+	m := &FileManager{CurrentPath: "/project/src", fs: fsys, Width: 120, Height: 20}
+	m.Entries, _ = m.readDirectory(m.CurrentPath)
+	loadListings(m)
+	if len(m.Entries) != 1 || strings.Contains(m.View(), ".config") {
+		t.Fatalf("expected hidden entries to be skipped, got %v", m.Entries)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'.'}})
+	if len(m.Entries) != 2 || m.Entries[0].Name != ".cache" {
+		t.Fatalf("expected .cache listed first, got %v", m.Entries)
+	}
+	// The parent column follows the same setting
+	loadListings(m)
+	if !strings.Contains(m.View(), ".config") {
+		t.Fatal("expected .config in the parent column")
+	}
+}
+
+func TestPasteCopyFailure(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: src, fs: failingFS{FS: OS, create: true}}
+	m.Entries, _ = m.readDirectory(src)
+	m.copyFile()
+
+	m.CurrentPath = dst
+	paste(m)
+
+	if !m.statusIsError || !strings.Contains(m.statusMsg, errFailed.Error()) {
+		t.Fatalf("expected the copy error to be shown, got %q", m.statusMsg)
+	}
+	if len(m.undoStack) != 0 {
+		t.Fatalf("failed copy should not be undoable: %+v", m.undoStack)
+	}
+}
+
+func TestPasteKeepsModeAndTimes(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	tool := filepath.Join(src, "tools", "run.sh")
+	if err := os.MkdirAll(filepath.Dir(tool), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, path := range []string{tool, filepath.Dir(tool)} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: src}
+	m.Entries, _ = m.readDirectory(src)
+	m.copyFile()
+	m.CurrentPath = dst
+	paste(m)
+
+	for path, mode := range map[string]fs.FileMode{
+		filepath.Join(dst, "tools", "run.sh"): 0755,
+		filepath.Join(dst, "tools"):           0750,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("%s: expected mode %v, got %v", path, mode, info.Mode().Perm())
+		}
+		if !info.ModTime().Equal(old) {
+			t.Errorf("%s: expected the time kept, got %v", path, info.ModTime())
+		}
+	}
+
+	// A filesystem that refuses them still gets the contents
+	m.fs = noMetadataFS{OS}
+	m.CurrentPath = t.TempDir()
+	paste(m)
+	if m.statusIsError {
+		t.Fatalf("expected the copy to succeed, got %q", m.statusMsg)
+	}
+	if _, err := os.Stat(filepath.Join(m.CurrentPath, "tools", "run.sh")); err != nil {
+		t.Errorf("expected run.sh copied: %v", err)
+	}
+}
+
+func TestPasteWithoutSpace(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"small.txt": 10, "dir/a.bin": 60, "dir/b.bin": 60} {
+		if err := os.WriteFile(filepath.Join(src, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: src, fs: fullDiskFS{FS: OS, free: 100}}
+	m.Entries, _ = m.readDirectory(src)
+
+	// The directory's 120 bytes don't fit in 100
+	m.Cursor = 0
+	m.copyFile()
+	m.CurrentPath = dst
+	paste(m)
+	if !m.statusIsError || !strings.Contains(m.statusMsg, "not enough space") {
+		t.Fatalf("expected the copy refused for lack of space, got %q", m.statusMsg)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "dir")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing copied, got %v", err)
+	}
+
+	// The small file fits
+	m.CurrentPath = src
+	m.Entries, _ = m.readDirectory(src)
+	m.Cursor = 1
+	m.copyFile()
+	m.CurrentPath = dst
+	paste(m)
+	if _, err := os.Stat(filepath.Join(dst, "small.txt")); err != nil {
+		t.Fatalf("expected the small file copied: %v", err)
+	}
+}
+
+func TestPasteFailureRemovesPartialCopy(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: src, fs: failingFS{FS: OS, write: true}}
+	m.Entries, _ = m.readDirectory(src)
+	m.copyFile()
+	m.CurrentPath = dst
+	paste(m)
+
+	if !m.statusIsError || !strings.Contains(m.statusMsg, errFailed.Error()) {
+		t.Fatalf("expected the write error to be shown, got %q", m.statusMsg)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected the partial copy removed, got %v", err)
+	}
+}
+
+func TestPasteKeepsWhatAppearedMeanwhile(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dst}
+	job, ok, err := m.planPaste(FileEntry{Name: "a.txt", Path: filepath.Join(src, "a.txt")}, "copy", collisionKeep, nil)
+	if err != nil || !ok {
+		t.Fatalf("expected a job, got %v, %v", ok, err)
+	}
+
+	// Another program writes the destination before the copy runs
+	if err := os.WriteFile(job.dest, []byte("theirs"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := job.run(OS); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected the copy to fail, got %v", err)
+	}
+	if content, _ := os.ReadFile(job.dest); string(content) != "theirs" {
+		t.Errorf("expected their file kept, got %q", content)
+	}
+}
+
+func TestRenameFailure(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, fs: failingFS{FS: OS, rename: true}}
+	m.Entries, _ = m.readDirectory(dir)
+
+	m.renameFile("b.txt")
+
+	if !m.statusIsError || len(m.undoStack) != 0 {
+		t.Fatalf("expected an error and no undo entry, got %q, %+v", m.statusMsg, m.undoStack)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatalf("file should be untouched: %v", err)
+	}
+}
+
+func TestUndoRenameFailureKeepsAction(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir}
+	m.Entries, _ = m.readDirectory(dir)
+	m.renameFile("b.txt")
+
+	// Undo fails, so the rename stays on the stack to retry
+	m.fs = failingFS{FS: OS, rename: true}
+	m.undoLastAction()
+	if !m.statusIsError || len(m.undoStack) != 1 {
+		t.Fatalf("expected an error and the rename kept, got %q, %+v", m.statusMsg, m.undoStack)
+	}
+
+	m.fs = nil
+	m.undoLastAction()
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatalf("retried undo did not restore the name: %v", err)
+	}
+}
+
+func TestRelativeSymlinkTarget(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "target.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Relative to sub, not to the working directory the tests run in
+	link := filepath.Join(sub, "link")
+	if err := os.Symlink("../target.txt", link); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(sub, "broken")
+	if err := os.Symlink("missing.txt", broken); err != nil {
+		t.Fatal(err)
+	}
+
+	target, err := readSymlink(OS, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "target.txt"); target.raw != "../target.txt" || target.resolved != want || target.broken {
+		t.Fatalf("expected ../target.txt resolved to %s, got %+v", want, target)
+	}
+	if info := getFileInfo(OS, link, false, nil); !strings.Contains(info, "→ ../target.txt ("+target.resolved+")") {
+		t.Errorf("expected the raw and resolved targets in the info, got %q", info)
+	}
+
+	target, err = readSymlink(OS, broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !target.broken || target.resolved != filepath.Join(sub, "missing.txt") {
+		t.Fatalf("expected a broken link to sub/missing.txt, got %+v", target)
+	}
+	if info := getFileInfo(OS, broken, false, nil); !strings.Contains(info, "[broken]") {
+		t.Errorf("expected the info to show the link is broken, got %q", info)
+	}
+
+	// The OS follows .. after a link to a directory from where the link leads,
+	// not lexically, so dir/jump/.. is dir/a and target.txt isn't there
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("a", "b"), filepath.Join(dir, "jump")); err != nil {
+		t.Fatal(err)
+	}
+	through := filepath.Join(sub, "through")
+	if err := os.Symlink("../jump/../target.txt", through); err != nil {
+		t.Fatal(err)
+	}
+	if target, err = readSymlink(OS, through); err != nil || !target.broken {
+		t.Errorf("expected a link through jump/.. to be broken, got %+v, %v", target, err)
+	}
+
+	// Regular files aren't links
+	if _, err := readSymlink(OS, filepath.Join(dir, "target.txt")); err == nil {
+		t.Error("expected a regular file not to read as a symlink")
+	}
+}
+
+func TestSymlinkToDirectory(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "real")
+	if err := os.Mkdir(real, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(real, "inside.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := mustReadDirectory(t, dir, ListOptions{})
+	if len(entries) != 2 || entries[0].Name != "link" {
+		t.Fatalf("expected the link listed with the directories, got %v", entries)
+	}
+	link := entries[0]
+	if !link.IsDir || !link.IsSymlink || link.Target != real {
+		t.Fatalf("expected a link to the directory %s, got %+v", real, link)
+	}
+
+	m := &FileManager{CurrentPath: dir, Entries: entries, Width: 100, Height: 20}
+	if !strings.Contains(m.View(), "link/ → ") {
+		t.Errorf("expected the link shown with its target:\n%s", m.View())
+	}
+
+	// Entered through the link, like a shell would
+	send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	if want := filepath.Join(dir, "link"); m.CurrentPath != want {
+		t.Fatalf("expected to enter %s, got %s", want, m.CurrentPath)
+	}
+	if len(m.Entries) != 1 || m.Entries[0].Name != "inside.txt" {
+		t.Errorf("expected the directory's contents, got %v", m.Entries)
+	}
+}
@@ -0,0 +1,128 @@
+package browser
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Fuzzy scoring weights
+const (
+	fuzzyMatchScore       = 1 // Every matched character
+	fuzzyConsecutiveBonus = 6 // Character directly follows the previous match
+	fuzzyBoundaryBonus    = 8 // Character starts a word (after a separator or camelCase hump)
+	fuzzyGapStartPenalty  = 3 // Starting a gap between matches
+	fuzzyGapPenalty       = 1 // Every skipped character in a gap
+)
+
+// fuzzyMatch scores how well pattern matches candidate, ignoring case. All
+// pattern characters must appear in candidate in order. It returns the score,
+// the rune positions that matched, and whether it matched at all.
+func fuzzyMatch(pattern, candidate string) (int, []int, bool) {
+	p := lowerRunes(pattern)
+	original := []rune(candidate)
+	c := lowerRunes(candidate)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	// Try every occurrence of the first character as a starting point and
+	// keep the best greedy match from there
+	bestScore := 0
+	var bestPositions []int
+	found := false
+	for start := range c {
+		if c[start] != p[0] {
+			continue
+		}
+		score, positions, ok := fuzzyMatchFrom(p, c, original, start)
+		if ok && (!found || score > bestScore) {
+			bestScore, bestPositions, found = score, positions, true
+		}
+	}
+	return bestScore, bestPositions, found
+}
+
+// fuzzyMatchFrom greedily matches p against c starting at index start
+func fuzzyMatchFrom(p, c, original []rune, start int) (int, []int, bool) {
+	score := 0
+	positions := make([]int, 0, len(p))
+	pi := 0
+	for ci := start; ci < len(c) && pi < len(p); ci++ {
+		if c[ci] != p[pi] {
+			continue
+		}
+
+		score += fuzzyMatchScore
+		if len(positions) > 0 {
+			last := positions[len(positions)-1]
+			if last == ci-1 {
+				score += fuzzyConsecutiveBonus
+			} else {
+				score -= fuzzyGapStartPenalty + (ci-last-1)*fuzzyGapPenalty
+			}
+		}
+		if isWordStart(original, ci) {
+			score += fuzzyBoundaryBonus
+		}
+
+		positions = append(positions, ci)
+		pi++
+	}
+	return score, positions, pi == len(p)
+}
+
+// lowerRunes lowercases s rune by rune, so positions line up with []rune(s)
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// isWordStart reports whether the rune at i begins a word
+func isWordStart(runes []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, cur := runes[i-1], runes[i]
+	if strings.ContainsRune(" -_./", prev) {
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// rankMatches returns the indexes of names matching query, best first. With
+// fuzzy off it falls back to case-insensitive substring matching in list order.
+func rankMatches(query string, names []string, fuzzy bool) []int {
+	var matches []int
+
+	if !fuzzy {
+		query = strings.ToLower(query)
+		for i, name := range names {
+			if strings.Contains(strings.ToLower(name), query) {
+				matches = append(matches, i)
+			}
+		}
+		return matches
+	}
+
+	scores := make(map[int]int)
+	for i, name := range names {
+		if score, _, ok := fuzzyMatch(query, name); ok {
+			matches = append(matches, i)
+			scores[i] = score
+		}
+	}
+
+	// Best score first; shorter names win ties, then list order
+	sort.SliceStable(matches, func(a, b int) bool {
+		ia, ib := matches[a], matches[b]
+		if scores[ia] != scores[ib] {
+			return scores[ia] > scores[ib]
+		}
+		return len(names[ia]) < len(names[ib])
+	})
+	return matches
+}
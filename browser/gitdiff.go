@@ -0,0 +1,79 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// renderDiffPreview renders a file's changes against git HEAD, read in the
+// background, colored by line type
+func (m *FileManager) renderDiffPreview(file FileEntry, colWidth, maxHeight int) string {
+	preview, ok := m.loadedPreviewOf("diff", file.Path)
+	if !ok {
+		return loadingMsg
+	}
+	text := strings.TrimSuffix(renderTextPreview([]byte(preview.text), colWidth, maxHeight, 0), "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = colorDiffLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gitDiff returns the diff of a file against HEAD, or a short explanation
+// when there is nothing to diff
+func gitDiff(path string) string {
+	if !commandExists("git") {
+		return "git not found"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), previewCommandTimeout)
+	defer cancel()
+
+	dir, name := filepath.Dir(path), filepath.Base(path)
+	git := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+		output := &limitedBuffer{limit: previewCommandLimit}
+		cmd.Stdout = output
+		err := cmd.Run()
+		return output.String(), err
+	}
+
+	if _, err := git("rev-parse", "--is-inside-work-tree"); err != nil {
+		return "Not in a git repository"
+	}
+	if _, err := git("ls-files", "--error-unmatch", "--", name); err != nil {
+		return "Untracked file"
+	}
+
+	diff, err := git("diff", "--no-color", "HEAD", "--", name)
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "git diff timed out"
+	case err != nil:
+		// e.g. a repository without commits has no HEAD
+		return "git diff failed: " + err.Error()
+	case diff == "":
+		return "No changes against HEAD"
+	}
+	return diff
+}
+
+// colorDiffLine styles a line of unified diff output
+func colorDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"),
+		strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "):
+		return diffMetaStyle.Render(line)
+	case strings.HasPrefix(line, "@@"):
+		return diffHunkStyle.Render(line)
+	case strings.HasPrefix(line, "+"):
+		return diffAddStyle.Render(line)
+	case strings.HasPrefix(line, "-"):
+		return diffRemoveStyle.Render(line)
+	}
+	return line
+}
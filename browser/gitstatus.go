@@ -0,0 +1,145 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// gitState is an entry's git status. A directory takes the highest state
+// of anything changed inside it.
+type gitState int
+
+const (
+	gitIgnored   gitState = iota + 1 // Matched by .gitignore
+	gitUntracked                     // Not tracked yet
+	gitStaged                        // Changes staged for commit
+	gitModified                      // Changes not staged yet, or conflicts
+)
+
+// Colors of entries by git status
+var gitStyles = map[gitState]lipgloss.Style{
+	gitIgnored:   lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+	gitUntracked: lipgloss.NewStyle().Foreground(lipgloss.Color("174")),
+	gitStaged:    lipgloss.NewStyle().Foreground(lipgloss.Color("34")),
+	gitModified:  lipgloss.NewStyle().Foreground(lipgloss.Color("220")),
+}
+
+// Custom message carrying the git status of a directory's entries
+type gitStatusMsg struct {
+	dir    string              // Directory the status was read for
+	seq    int                 // gitSeq when the read started
+	states map[string]gitState // Status by entry path
+}
+
+// readGitStatus runs git status for dir, limited to what is under it, and
+// maps it onto dir's entries. It returns nothing when git isn't installed
+// or dir isn't in a repository.
+func readGitStatus(dir string) map[string]gitState {
+	ctx, cancel := context.WithTimeout(context.Background(), previewCommandTimeout)
+	defer cancel()
+
+	prefix, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--show-prefix").Output()
+	if err != nil {
+		return nil
+	}
+	output, err := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain", "-z", "--ignored=matching", "--", ".").Output()
+	if err != nil {
+		return nil
+	}
+	return parseGitStatus(dir, strings.TrimSpace(string(prefix)), output)
+}
+
+// parseGitStatus maps git status --porcelain -z output onto the entries of
+// dir, which is at prefix (like "src/", "" at the top) in the repository.
+// A state stored under dir itself applies to everything in it, e.g. inside
+// an untracked directory.
+func parseGitStatus(dir, prefix string, output []byte) map[string]gitState {
+	states := make(map[string]gitState)
+	fields := bytes.Split(output, []byte{0})
+	for i := 0; i < len(fields); i++ {
+		field := string(fields[i])
+		if len(field) < 4 {
+			continue
+		}
+		code, path := field[:2], strings.TrimSuffix(field[3:], "/")
+		if code[0] == 'R' || code[0] == 'C' {
+			i++ // The path it was renamed or copied from follows
+		}
+
+		var state gitState
+		switch {
+		case code == "!!":
+			state = gitIgnored
+		case code == "??":
+			state = gitUntracked
+		case code[1] != ' ':
+			state = gitModified
+		default:
+			state = gitStaged
+		}
+
+		if rest, ok := strings.CutPrefix(path, prefix); ok && rest != "" {
+			name, inside, _ := strings.Cut(rest, "/")
+			if inside != "" && state == gitIgnored {
+				// Ignoring a file doesn't make its directory ignored
+				continue
+			}
+			entry := filepath.Join(dir, name)
+			states[entry] = max(states[entry], state)
+		} else if strings.HasPrefix(prefix, path+"/") {
+			// A whole directory we are inside of
+			states[dir] = max(states[dir], state)
+		}
+	}
+	return states
+}
+
+// startGitStatus reads the git status of the current directory in the
+// background, unless it has already been read since the last reload
+func (m *FileManager) startGitStatus() tea.Cmd {
+	if !m.gitStatus || m.gitLoading || m.archive != nil || m.gitStatesDir == m.CurrentPath {
+		return nil
+	}
+	m.gitLoading = true
+	m.startOp()
+	dir, seq := m.CurrentPath, m.gitSeq
+	return func() tea.Msg {
+		return gitStatusMsg{dir: dir, seq: seq, states: readGitStatus(dir)}
+	}
+}
+
+// handleGitStatus keeps a git status unless the listing changed while it
+// was read; a fresh read is then started
+func (m *FileManager) handleGitStatus(msg gitStatusMsg) {
+	m.gitLoading = false
+	m.finishOp()
+	if msg.dir != m.CurrentPath || msg.seq != m.gitSeq {
+		return
+	}
+	m.gitStates = msg.states
+	m.gitStatesDir = msg.dir
+}
+
+// forgetGitStatus marks the git status as stale, to read it again
+func (m *FileManager) forgetGitStatus() {
+	m.gitStatesDir = ""
+	m.gitSeq++
+}
+
+// gitStyle returns the style of an entry by its git status, if it has one
+func (m *FileManager) gitStyle(entry FileEntry) (lipgloss.Style, bool) {
+	if m.gitStatesDir != m.CurrentPath {
+		return lipgloss.Style{}, false
+	}
+	state, ok := m.gitStates[entry.Path]
+	if !ok {
+		state, ok = m.gitStates[m.CurrentPath]
+	}
+	return gitStyles[state], ok
+}
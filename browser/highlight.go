@@ -0,0 +1,56 @@
+package browser
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// renderHighlightedPreview renders a source file from line scroll on with
+// syntax highlighting in theme, picking the language from its name. It
+// reports false for files that aren't source code, which keep the plain
+// text preview.
+func renderHighlightedPreview(name string, content []byte, theme string, colWidth, maxHeight, scroll int) (string, bool) {
+	if theme == "" {
+		return "", false
+	}
+	lexer := lexers.Match(name)
+	if lexer == nil || lexer.Config().Name == "plaintext" {
+		return "", false
+	}
+
+	// Only the lines shown are highlighted, however long the file is
+	lines := scrollLines(strings.Split(string(content), "\n"), maxHeight, scroll)
+	more := len(lines) > maxHeight
+	if more {
+		lines = lines[:maxHeight]
+	}
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, strings.Join(lines, "\n"))
+	if err != nil {
+		return "", false
+	}
+	var highlighted strings.Builder
+	if err := formatters.TTY256.Format(&highlighted, styles.Get(theme), tokens); err != nil {
+		return "", false
+	}
+
+	var preview strings.Builder
+	limit := max(colWidth-4, 0)
+	for _, line := range strings.Split(highlighted.String(), "\n") {
+		// Cut by what is shown, leaving the color codes out of the count
+		if limit > 3 {
+			line = ansi.Truncate(line, limit, "...")
+		} else {
+			line = ansi.Truncate(line, limit, "")
+		}
+		preview.WriteString(line + "\n")
+	}
+	if more {
+		preview.WriteString("...\n")
+	}
+	return preview.String(), true
+}
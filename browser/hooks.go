@@ -0,0 +1,100 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// How long a pre hook may hold up its operation
+const preHookTimeout = 5 * time.Second
+
+// pendingHook is a hook waiting to be started by Update
+type pendingHook struct {
+	point   string // Hook point, e.g. "post_rename"
+	command string
+	path    string // Affected path
+	oldPath string // Previous path, for renames and moves
+}
+
+// Custom message reporting that the hooks started together finished
+type hookDoneMsg struct {
+	err error // Why hooks failed, nil when all succeeded
+}
+
+// runHook runs the hook configured for point, if any. Points are "pre_" or
+// "post_" followed by create, delete, rename, move or copy. Pre hooks run
+// right away, for up to preHookTimeout, so they see the files before the
+// operation; post hooks are queued and run in the background once the
+// current message is handled. A failing hook is reported but never stops
+// the operation.
+func (m *FileManager) runHook(point, path, oldPath string) {
+	command := m.hooks[point]
+	if command == "" {
+		return
+	}
+	hook := pendingHook{point: point, command: command, path: path, oldPath: oldPath}
+	if strings.HasPrefix(point, "pre_") {
+		ctx, cancel := context.WithTimeout(context.Background(), preHookTimeout)
+		defer cancel()
+		if err := execHook(ctx, hook); err != nil {
+			m.setError(fmt.Errorf("%s hook failed: %w", point, err))
+		}
+		return
+	}
+	m.pendingHooks = append(m.pendingHooks, hook)
+}
+
+// startHooks starts the queued post hooks, running them in order
+func (m *FileManager) startHooks() tea.Cmd {
+	if len(m.pendingHooks) == 0 {
+		return nil
+	}
+	hooks := m.pendingHooks
+	m.pendingHooks = nil
+	m.startOp()
+	return func() tea.Msg {
+		var errs []error
+		for _, hook := range hooks {
+			if err := execHook(context.Background(), hook); err != nil {
+				errs = append(errs, fmt.Errorf("%s hook failed: %w", hook.point, err))
+			}
+		}
+		return hookDoneMsg{err: errors.Join(errs...)}
+	}
+}
+
+// execHook runs a hook command through the shell. The path replaces "{}"
+// (or is appended), and is also in $TFM_PATH, with $TFM_OLD_PATH and
+// $TFM_HOOK alongside.
+func execHook(ctx context.Context, hook pendingHook) error {
+	cmd := shellCommand(ctx, expandCommand(hook.command, hook.path))
+	cmd.Dir = filepath.Dir(hook.path)
+	cmd.Env = append(os.Environ(),
+		"TFM_HOOK="+hook.point,
+		"TFM_PATH="+hook.path,
+		"TFM_OLD_PATH="+hook.oldPath,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+	}
+	return err
+}
+
+// handleHookDone reports hooks that failed; their operations stand
+func (m *FileManager) handleHookDone(msg hookDoneMsg) {
+	m.finishOp()
+	if msg.err != nil {
+		m.setError(msg.err)
+	}
+}
@@ -0,0 +1,95 @@
+package browser
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Default Nerd Font glyphs by extension. "dir" and "file" are the fallbacks,
+// and "link" is for symlinks.
+var defaultIcons = map[string]string{
+	"dir":  "",
+	"link": "",
+	"file": "",
+
+	// Source code
+	"go":   "",
+	"py":   "",
+	"js":   "",
+	"ts":   "",
+	"rs":   "",
+	"c":    "",
+	"h":    "",
+	"cpp":  "",
+	"java": "",
+	"rb":   "",
+	"lua":  "",
+	"vim":  "",
+	"html": "",
+	"css":  "",
+	"sh":   "",
+	"bash": "",
+	"zsh":  "",
+
+	// Data and config
+	"json": "",
+	"yaml": "",
+	"yml":  "",
+	"toml": "",
+	"lock": "",
+
+	// Documents
+	"md":  "",
+	"txt": "",
+	"pdf": "",
+
+	// Media
+	"png":  "",
+	"jpg":  "",
+	"jpeg": "",
+	"gif":  "",
+	"svg":  "",
+	"mp3":  "",
+	"flac": "",
+	"wav":  "",
+	"mp4":  "",
+	"mkv":  "",
+	"mov":  "",
+
+	// Archives
+	"zip": "",
+	"tar": "",
+	"gz":  "",
+	"xz":  "",
+	"7z":  "",
+}
+
+// loadIcons returns the icon map with config overrides applied over the defaults
+func loadIcons(overrides map[string]string) map[string]string {
+	icons := make(map[string]string, len(defaultIcons)+len(overrides))
+	for key, glyph := range defaultIcons {
+		icons[key] = glyph
+	}
+	for key, glyph := range overrides {
+		icons[strings.TrimPrefix(strings.ToLower(key), ".")] = glyph
+	}
+	return icons
+}
+
+// iconFor returns the glyph and a trailing space for an entry, or "" when icons are off
+func iconFor(icons map[string]string, entry FileEntry) string {
+	if icons == nil {
+		return ""
+	}
+	if entry.IsSymlink {
+		return icons["link"] + " "
+	}
+	if entry.IsDir {
+		return icons["dir"] + " "
+	}
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(entry.Name)), ".")
+	if glyph, ok := icons[ext]; ok {
+		return glyph + " "
+	}
+	return icons["file"] + " "
+}
@@ -0,0 +1,46 @@
+package browser
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Custom message cancelling an input mode nothing was typed into for a while
+type inputIdleMsg struct {
+	seq int // inputSeq when the timer started
+}
+
+// typing reports whether text is being typed into a mode
+func (m *FileManager) typing() bool {
+	return m.searchMode || m.renameMode || m.zoxideMode || m.relBaseMode || m.prompt != nil
+}
+
+// scheduleInputIdle restarts the timer that cancels the input mode once it
+// is left idle for inputTimeout
+func (m *FileManager) scheduleInputIdle() tea.Cmd {
+	if m.inputTimeout <= 0 || !m.typing() {
+		return nil
+	}
+	m.inputSeq++
+	seq := m.inputSeq
+	return tea.Tick(m.inputTimeout, func(time.Time) tea.Msg {
+		return inputIdleMsg{seq: seq}
+	})
+}
+
+// handleInputIdle cancels the input mode, as esc would, unless a key was
+// pressed since the timer started
+func (m *FileManager) handleInputIdle(msg inputIdleMsg) {
+	if msg.seq != m.inputSeq || !m.typing() {
+		return
+	}
+	if m.searchMode {
+		m.cancelSearch()
+	}
+	m.renameMode, m.renameText = false, ""
+	m.zoxideMode, m.zoxideQuery = false, ""
+	m.relBaseMode, m.relBaseText = false, ""
+	m.prompt = nil
+	m.setStatus("Cancelled input left idle for %s", m.inputTimeout)
+}
@@ -0,0 +1,121 @@
+package browser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Custom message asking the TUI to show a directory, or a file's directory
+// with the file selected
+type remoteCdMsg struct {
+	path string
+}
+
+// Custom message asking the TUI to select an entry of the current directory
+type remoteSelectMsg struct {
+	name string
+}
+
+// parseRemoteCommand turns one line received on the socket into a message.
+// Commands: "cd <path>", "select <name>" and "refresh".
+func parseRemoteCommand(line string) (tea.Msg, error) {
+	command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case "cd":
+		if arg == "" {
+			return nil, errors.New("cd needs a path")
+		}
+		path, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		return remoteCdMsg{path: path}, nil
+	case "select":
+		if arg == "" {
+			return nil, errors.New("select needs a name")
+		}
+		return remoteSelectMsg{name: arg}, nil
+	case "refresh":
+		return reloadDirectoryMsg{}, nil
+	default:
+		return nil, fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// ListenSocket accepts commands on a Unix-domain socket and delivers them to
+// the program. A stale socket left by a crashed instance is replaced. Close
+// the returned listener and remove path when done.
+func ListenSocket(path string, p *tea.Program) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is in use by another instance", path)
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return // Listener closed on exit
+			}
+			go serveSocketConn(conn, p)
+		}
+	}()
+	return listener, nil
+}
+
+// serveSocketConn handles one command per line, answering "ok" or "error: ..."
+func serveSocketConn(conn net.Conn, p *tea.Program) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		msg, err := parseRemoteCommand(scanner.Text())
+		if err != nil {
+			fmt.Fprintf(conn, "error: %v\n", err)
+			continue
+		}
+		p.Send(msg)
+		fmt.Fprintln(conn, "ok")
+	}
+}
+
+// handleRemoteCd shows a directory, or a file's directory with it selected
+func (m *FileManager) handleRemoteCd(msg remoteCdMsg) {
+	dir, name := msg.path, ""
+	if info, err := os.Stat(msg.path); err != nil {
+		m.setError(err)
+		return
+	} else if !info.IsDir() {
+		dir, name = filepath.Dir(msg.path), filepath.Base(msg.path)
+	}
+
+	m.openDir(dir, name)
+}
+
+// handleRemoteSelect moves the cursor to an entry of the current directory
+func (m *FileManager) handleRemoteSelect(msg remoteSelectMsg) {
+	if !m.selectByName(msg.name) {
+		m.setError(fmt.Errorf("%s not found in %s", msg.name, m.CurrentPath))
+	}
+}
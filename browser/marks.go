@@ -0,0 +1,147 @@
+package browser
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// What the next key does after M, `, m or '
+const (
+	markSet      = "set"           // M: mark the selected entry with the letter
+	markJump     = "jump"          // `: go to the entry marked with the letter
+	bookmarkSet  = "bookmark"      // m: bookmark the current directory as the letter
+	bookmarkJump = "bookmark jump" // ': go to the directory bookmarked as the letter
+)
+
+// isMarkLetter reports whether key can name a mark
+func isMarkLetter(key string) bool {
+	return len(key) == 1 && (key[0] >= 'a' && key[0] <= 'z' || key[0] >= 'A' && key[0] <= 'Z')
+}
+
+// Marks returns the file marks, letter to path
+func (m *FileManager) Marks() map[string]string {
+	return m.marks
+}
+
+// startMark waits for the letter of a mark to set or jump to
+func (m *FileManager) startMark(mode string) {
+	if mode == markSet && m.Cursor >= len(m.Entries) {
+		return
+	}
+	if mode == markJump && len(m.marks) == 0 {
+		m.setStatus("No marks set (M and a letter sets one)")
+		return
+	}
+	m.markMode = mode
+}
+
+// handleMarkKey takes the letter after M, `, m or '; anything else cancels.
+// A second ' opens the recent directories instead, like jumping back in vim.
+func (m *FileManager) handleMarkKey(msg tea.KeyMsg) tea.Cmd {
+	mode := m.markMode
+	m.markMode = ""
+	key := msg.String()
+	if key == "ctrl+c" {
+		return m.quit()
+	}
+	if mode == bookmarkJump && key == "'" {
+		m.openRecentPicker()
+		return nil
+	}
+	if !isMarkLetter(key) {
+		return nil
+	}
+
+	switch mode {
+	case markSet:
+		m.setMark(key, m.Entries[m.Cursor].Path)
+	case markJump:
+		m.jumpToMark(key)
+	case bookmarkSet:
+		m.setBookmark(key)
+	case bookmarkJump:
+		m.jumpToBookmark(key)
+	}
+	return nil
+}
+
+// setMark marks path with letter, replacing what it marked before
+func (m *FileManager) setMark(letter, path string) {
+	if m.marks == nil {
+		m.marks = make(map[string]string)
+	}
+	m.marks[letter] = path
+	m.setStatus("Marked %s as %s", filepath.Base(path), letter)
+}
+
+// jumpToMark goes to the directory of the marked entry and selects it. If
+// the entry has gone, the mark is removed, and its directory is opened if
+// that still exists.
+func (m *FileManager) jumpToMark(letter string) {
+	path, ok := m.marks[letter]
+	if !ok {
+		m.setError(fmt.Errorf("mark %s is not set", letter))
+		return
+	}
+
+	fsys := m.filesystem()
+	dir := filepath.Dir(path)
+	if _, err := fsys.Stat(path); err != nil {
+		delete(m.marks, letter)
+		if info, err := fsys.Stat(dir); err == nil && info.IsDir() {
+			m.openDir(dir, "")
+		}
+		m.setError(fmt.Errorf("%s was moved or deleted, removed mark %s", path, letter))
+		return
+	}
+	m.openDir(dir, filepath.Base(path))
+}
+
+// openDir shows dir with the cursor on name, or on the first entry. When
+// dir can't be read it stays put and shows why.
+func (m *FileManager) openDir(dir, name string) bool {
+	entries, err := m.readDirectory(dir)
+	if err != nil {
+		m.setError(err)
+		return false
+	}
+	m.CurrentPath = dir
+	m.Entries = entries
+	m.Cursor = 0
+	if name != "" {
+		m.selectByName(name)
+	}
+	return true
+}
+
+// moveMarks keeps marks on an entry that tfm renamed or moved, including
+// marks on anything inside it
+func (m *FileManager) moveMarks(oldPath, newPath string) {
+	for letter, path := range m.marks {
+		if path == oldPath {
+			m.marks[letter] = newPath
+		} else if rest, ok := strings.CutPrefix(path, oldPath+string(filepath.Separator)); ok {
+			m.marks[letter] = filepath.Join(newPath, rest)
+		}
+	}
+}
+
+// renderMarks renders the marks overlay shown while picking one to jump to
+func (m *FileManager) renderMarks() string {
+	letters := make([]string, 0, len(m.marks))
+	for letter := range m.marks {
+		letters = append(letters, letter)
+	}
+	sort.Strings(letters)
+
+	var content strings.Builder
+	content.WriteString("Marks (letter jumps, esc cancels)")
+	for _, letter := range letters {
+		content.WriteString(fmt.Sprintf("\n  %s  %s", letter, truncateLeft(m.marks[letter], m.Width-9)))
+	}
+	return whichKeyStyle.Width(m.Width).Render(content.String())
+}
@@ -0,0 +1,66 @@
+package browser
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Opener is a command files are opened with
+type Opener struct {
+	Command    string // Shell command, "{}" is replaced by the path (or it is appended)
+	Foreground bool   // Suspend the TUI until it exits, for terminal programs
+}
+
+// openerFor returns the configured opener for a file, if any
+func openerFor(openers map[string]Opener, name string) (Opener, bool) {
+	ext := fileExt(name)
+	if ext == "" {
+		return Opener{}, false
+	}
+	opener, ok := openers[ext]
+	return opener, ok && opener.Command != ""
+}
+
+// openFile opens a file with its configured opener, or the system's default
+// program in the background when there is none
+func (m *FileManager) openFile(entry FileEntry) tea.Cmd {
+	opener, ok := openerFor(m.openers, entry.Name)
+	if !ok {
+		if err := openWithDefaultApp(entry.Path); err != nil {
+			m.setError(err)
+		}
+		return nil
+	}
+	return m.runOpener(opener, entry.Path)
+}
+
+// runOpener opens path with opener, suspending the TUI for foreground ones
+func (m *FileManager) runOpener(opener Opener, path string) tea.Cmd {
+	cmd := shellCommand(context.Background(), expandCommand(opener.Command, path))
+	cmd.Dir = filepath.Dir(path)
+	if opener.Foreground {
+		return tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return reloadDirectoryMsg{err: err}
+		})
+	}
+
+	// Detached: GUI programs keep running without taking over the terminal
+	if err := cmd.Start(); err != nil {
+		m.setError(err)
+		return nil
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// shellCommand runs a command line through the platform shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
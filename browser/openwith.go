@@ -0,0 +1,108 @@
+package browser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openWithMenu lists the applications a file can be opened with
+type openWithMenu struct {
+	entry  FileEntry // File to open
+	apps   []Opener  // Applications configured for its extension
+	cursor int       // Selected application; len(apps) is the default one
+}
+
+// openWithFor returns the applications configured for a file
+func openWithFor(apps map[string][]Opener, name string) []Opener {
+	ext := fileExt(name)
+	if ext == "" {
+		return nil
+	}
+	var configured []Opener
+	for _, app := range apps[ext] {
+		if app.Command != "" {
+			configured = append(configured, app)
+		}
+	}
+	return configured
+}
+
+// startOpenWith offers the applications configured for the selected file,
+// or opens it as enter would when there are none
+func (m *FileManager) startOpenWith() tea.Cmd {
+	if m.Cursor >= len(m.Entries) {
+		return nil
+	}
+	entry := m.Entries[m.Cursor]
+	if entry.IsDir {
+		m.setStatus("Open with works on files")
+		return nil
+	}
+	if m.archive != nil {
+		m.setStatus("Can't open files inside an archive with other applications")
+		return nil
+	}
+	apps := openWithFor(m.openWith, entry.Name)
+	if len(apps) == 0 {
+		return m.openFile(entry)
+	}
+	m.openWithMenu = &openWithMenu{entry: entry, apps: apps}
+	return nil
+}
+
+// handleOpenWithKey picks an application from the open with menu
+func (m *FileManager) handleOpenWithKey(msg tea.KeyMsg) tea.Cmd {
+	menu := m.openWithMenu
+	switch key := msg.String(); key {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		m.openWithMenu = nil
+	case "up", "k":
+		menu.cursor = max(menu.cursor-1, 0)
+	case "down", "j":
+		menu.cursor = min(menu.cursor+1, len(menu.apps))
+	case "l", "enter", "right":
+		return m.openWithPicked(menu.cursor)
+	default:
+		// Digits pick the application listed with them
+		if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(menu.apps)+1 {
+			return m.openWithPicked(n - 1)
+		}
+	}
+	return nil
+}
+
+// openWithPicked closes the menu and opens its file with the i-th
+// application, or the default one past the configured ones
+func (m *FileManager) openWithPicked(i int) tea.Cmd {
+	menu := m.openWithMenu
+	m.openWithMenu = nil
+	if i >= len(menu.apps) {
+		return m.openFile(menu.entry)
+	}
+	return m.runOpener(menu.apps[i], menu.entry.Path)
+}
+
+// renderOpenWith renders the open with menu overlay
+func (m *FileManager) renderOpenWith() string {
+	menu := m.openWithMenu
+	var content strings.Builder
+	fmt.Fprintf(&content, "Open %s with (enter picks, esc cancels)", menu.entry.Name)
+	labels := make([]string, 0, len(menu.apps)+1)
+	for _, app := range menu.apps {
+		labels = append(labels, app.Command)
+	}
+	labels = append(labels, "default application")
+	for i, label := range labels {
+		marker := "  "
+		if i == menu.cursor {
+			marker = "> "
+		}
+		fmt.Fprintf(&content, "\n%s%d  %s", marker, i+1, label)
+	}
+	return whichKeyStyle.Width(m.Width).Render(content.String())
+}
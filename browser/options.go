@@ -0,0 +1,183 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Options configures a FileManager created with New. The zero value opens
+// the working directory with the default behavior.
+type Options struct {
+	StartPath string // Directory to open (default is the working directory)
+	Inline    bool   // Render in the normal screen instead of the alternate one
+	Minimal   bool   // Show only the current column and status bar
+	FS        FS     // Filesystem to operate on (default is OS)
+
+	// Listing
+	ShowHidden     bool              // Start with dotfiles listed (. toggles)
+	HiddenPatterns []string          // Dotfiles matching these patterns are listed anyway
+	DirSort        string            // "name" (default), "size", "mtime", "ext" or "type"
+	FileSort       string            // Same modes as DirSort
+	SortReverse    bool              // Reverse both sorts (O toggles)
+	DirCounts      bool              // Show item counts next to directories
+	ShowSizes      bool              // Show sizes, and item counts for directories, at the right (i toggles)
+	Icons          bool              // Show Nerd Font icons before entries
+	IconOverrides  map[string]string // Glyphs by extension, or "dir" and "file"
+	MarginScroll   bool              // Keep ScrollOff lines around the cursor instead of centering it
+	ScrollOff      int               // Lines kept above/below the cursor with MarginScroll
+	KeepCutVisible bool              // List cut entries dimmed instead of hiding them until pasted
+	Watch          bool              // Reload the current directory when it changes (w toggles)
+	RelativeTimes  bool              // Show recent modification times like "3 minutes ago"
+	GitStatus      bool              // Color entries by their git status inside repositories
+
+	// Previews
+	NoPreview       bool              // Start with previews off (ctrl+p turns them on)
+	PreviewCommands map[string]string // External preview command by file extension
+	PreviewTheme    string            // Chroma style source code is highlighted with ("" shows plain text)
+
+	// Behavior
+	SubstringSearch   bool              // Plain substring search instead of fuzzy ranking
+	Editor            string            // Command e opens directories with
+	RenameOverwrite   string            // "ask" (default), "refuse" or "suffix" when a rename target exists
+	LargeDirThreshold int               // Ask before entering directories with more entries (0 never asks)
+	InputTimeout      time.Duration     // Cancel search, rename and other input left untouched this long (0 never does)
+	Openers           map[string]Opener // Commands files are opened with by extension (default is the system opener)
+	TemplateDir       string            // Directory T lists templates for new files from
+	RunExecutables    string            // "open" (default) or "ask" to offer running executables on enter
+	EnterFiles        string            // "open" (default) or "preview" to show files in the quick look on enter
+	PickKind          string            // PickAny (default), PickFile or PickDir: what ctrl+y picks
+	BatchWorkers      int               // Items of a bulk copy, move or delete processed at once (default 1)
+	BatchStopOnError  bool              // Stop a bulk operation at its first failure instead of going on
+	Hooks             map[string]string // Commands run in the background around operations, e.g. "post_create"
+	RecentDirs        []string          // Recently visited directories from earlier sessions, most recent first
+	RecentLimit       int               // How many recent directories are kept (default 50)
+	Marks             map[string]string // File marks from earlier sessions, letter to path
+	TrashDir          string            // Trash kept across sessions (default is a temporary one removed on exit)
+	TrashRetention    time.Duration     // How long entries stay in TrashDir (0 is forever)
+	Keymap            map[string]string // Extra keys mapped to default bindings, e.g. "ctrl+n": "j"
+
+	// Applications the open with menu (L) offers by extension
+	OpenWith map[string][]Opener
+
+	// Directory bookmarks, name to path; m sets and ' jumps to those named by a letter
+	Bookmarks   map[string]string
+	AddBookmark func(name, path string) error // Saves a bookmark set with m (nil keeps it for the session)
+}
+
+// New creates a file manager showing opts.StartPath
+func New(opts Options) (*FileManager, error) {
+	startPath := opts.StartPath
+	if startPath == "" {
+		startPath = "."
+	}
+	absPath, err := filepath.Abs(startPath)
+	if err != nil {
+		return nil, err
+	}
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = OS
+	}
+	info, err := fsys.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", absPath)
+	}
+
+	m := &FileManager{
+		CurrentPath: absPath,
+		inline:      opts.Inline,
+		minimal:     opts.Minimal,
+		keymap:      opts.Keymap,
+		fs:          opts.FS,
+		substring:   opts.SubstringSearch,
+
+		previewCommands:   opts.PreviewCommands,
+		previewTheme:      opts.PreviewTheme,
+		openers:           opts.Openers,
+		openWith:          opts.OpenWith,
+		templateDir:       opts.TemplateDir,
+		runExecutables:    opts.RunExecutables,
+		enterFiles:        opts.EnterFiles,
+		pickKind:          opts.PickKind,
+		relativeTimes:     opts.RelativeTimes,
+		gitStatus:         opts.GitStatus,
+		batchWorkers:      opts.BatchWorkers,
+		batchStop:         opts.BatchStopOnError,
+		hooks:             opts.Hooks,
+		autoPreview:       !opts.NoPreview,
+		editor:            opts.Editor,
+		renameOverwrite:   opts.RenameOverwrite,
+		largeDirThreshold: opts.LargeDirThreshold,
+		inputTimeout:      opts.InputTimeout,
+		showDirCounts:     opts.DirCounts,
+		showSizes:         opts.ShowSizes,
+		marginScroll:      opts.MarginScroll,
+		scrollOff:         max(0, opts.ScrollOff),
+		keepCutVisible:    opts.KeepCutVisible,
+		recentDirs:        opts.RecentDirs,
+		recentLimit:       opts.RecentLimit,
+		marks:             opts.Marks,
+		bookmarks:         opts.Bookmarks,
+		addBookmark:       opts.AddBookmark,
+		trashDir:          opts.TrashDir,
+		keepTrash:         opts.TrashDir != "",
+		trashRetention:    opts.TrashRetention,
+
+		listOptions: ListOptions{
+			ShowHidden:     opts.ShowHidden,
+			HiddenPatterns: opts.HiddenPatterns,
+			DirSort:        opts.DirSort,
+			FileSort:       opts.FileSort,
+			Reverse:        opts.SortReverse,
+		},
+	}
+	if m.recentLimit <= 0 {
+		m.recentLimit = defaultRecentLimit
+	}
+	if opts.Icons {
+		m.icons = loadIcons(opts.IconOverrides)
+	}
+	if opts.Watch {
+		if _, err := m.startWatching(); err != nil {
+			return nil, err
+		}
+	}
+	// Relative path copies default to where tfm was started
+	m.workDir, err = os.Getwd()
+	if err != nil {
+		m.workDir = absPath
+	}
+	if m.Entries, err = m.readDirectory(absPath); err != nil {
+		m.Close()
+		return nil, err
+	}
+	m.visitRecent(absPath)
+	return m, nil
+}
+
+// LastDirectory returns the real directory being shown, which for an
+// archive is the directory holding it
+func (m *FileManager) LastDirectory() string {
+	if m.archive != nil {
+		return filepath.Dir(m.archive.root)
+	}
+	return m.CurrentPath
+}
+
+// Close stops watching and any delete still running, and removes the
+// temporary trash. Call it once the program has exited; calling it again,
+// as a deferred call after a panic would, does no harm.
+func (m *FileManager) Close() {
+	m.stopWatching()
+	if m.archive != nil {
+		m.archive.Close()
+		m.archive = nil
+	}
+	m.cancelDelete()
+	m.cleanupTrash()
+}
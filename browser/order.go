@@ -0,0 +1,122 @@
+package browser
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Name of the file that keeps a directory's entries in a manual order, one
+// name per line. Listings follow it when it is present.
+const orderFileName = ".tfm_order"
+
+// readOrder returns the names in a directory's order file
+func readOrder(fsys FS, dir string) []string {
+	content, err := readFile(fsys, filepath.Join(dir, orderFileName))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, name := range strings.Split(string(content), "\n") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// applyOrder moves the entries named in order to the front, in that order,
+// ahead of the others in their sorted order. Names that are no longer in
+// the directory are skipped.
+func applyOrder(entries []FileEntry, order []string) {
+	rank := make(map[string]int, len(order))
+	for i, name := range order {
+		if _, ok := rank[name]; !ok {
+			rank[name] = i
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, oki := rank[entries[i].Name]
+		rj, okj := rank[entries[j].Name]
+		if oki != okj {
+			return oki
+		}
+		return oki && ri < rj
+	})
+}
+
+// writeOrder saves the order of entries to dir's order file. Names the old
+// file had that aren't listed, e.g. hidden files, are kept after them as
+// long as they still exist.
+func writeOrder(fsys FS, dir string, entries []FileEntry) error {
+	names := make([]string, 0, len(entries))
+	listed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+		listed[entry.Name] = true
+	}
+	for _, name := range readOrder(fsys, dir) {
+		if _, err := fsys.Stat(filepath.Join(dir, name)); err == nil && !listed[name] {
+			names = append(names, name)
+			listed[name] = true
+		}
+	}
+
+	file, err := fsys.Create(filepath.Join(dir, orderFileName))
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write([]byte(strings.Join(names, "\n") + "\n")); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// startReorder lets J and K move entries in the current directory
+func (m *FileManager) startReorder() {
+	if len(m.Entries) < 2 {
+		m.setStatus("Nothing to reorder")
+		return
+	}
+	m.reorderMode = true
+}
+
+// handleReorderKey handles keys while reordering entries
+func (m *FileManager) handleReorderKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc", "enter":
+		m.reorderMode = false
+	case "down", "j":
+		m.Cursor = min(m.Cursor+1, len(m.Entries)-1)
+	case "up", "k":
+		m.Cursor = max(m.Cursor-1, 0)
+	case "J":
+		m.moveEntry(1)
+	case "K":
+		m.moveEntry(-1)
+	}
+	return nil
+}
+
+// moveEntry swaps the selected entry with the one delta places away and
+// saves the new order
+func (m *FileManager) moveEntry(delta int) {
+	to := m.Cursor + delta
+	if m.Cursor >= len(m.Entries) || to < 0 || to >= len(m.Entries) {
+		return
+	}
+	m.Entries[m.Cursor], m.Entries[to] = m.Entries[to], m.Entries[m.Cursor]
+	if err := writeOrder(m.filesystem(), m.CurrentPath, m.Entries); err != nil {
+		// Put it back, as the listing would on the next read
+		m.Entries[m.Cursor], m.Entries[to] = m.Entries[to], m.Entries[m.Cursor]
+		m.setError(fmt.Errorf("saving the order: %w", err))
+		return
+	}
+	m.Cursor = to
+}
@@ -0,0 +1,41 @@
+package browser
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// What ctrl+y picks
+const (
+	PickAny  = "any"  // Default: the selected entry, file or directory
+	PickFile = "file" // Only files
+	PickDir  = "dir"  // Directories, the current one when a file is selected
+)
+
+// Picked returns the path picked with ctrl+y, or "" when tfm was quit
+// any other way
+func (m *FileManager) Picked() string {
+	return m.picked
+}
+
+// pickPath picks the selected path and quits, for a shell wrapper to use
+func (m *FileManager) pickPath() tea.Cmd {
+	if m.archive != nil {
+		m.setStatus("Paths inside archives can't be picked")
+		return nil
+	}
+	var selected *FileEntry
+	if m.Cursor < len(m.Entries) {
+		selected = &m.Entries[m.Cursor]
+	}
+
+	path := m.CurrentPath
+	switch {
+	case m.pickKind == PickFile && (selected == nil || selected.IsDir):
+		m.setStatus("Select a file to pick it")
+		return nil
+	case m.pickKind == PickDir && (selected == nil || !selected.IsDir):
+		// Keep the directory we're in
+	case selected != nil:
+		path = selected.Path
+	}
+	m.picked = path
+	return tea.Quit
+}
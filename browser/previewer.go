@@ -0,0 +1,101 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	previewCommandTimeout = 2 * time.Second
+	previewCommandLimit   = 64 * 1024 // Bytes of output kept from a preview command
+)
+
+// previewCommandFor returns the configured preview command for a file, if any
+func previewCommandFor(commands map[string]string, name string) (string, bool) {
+	ext := fileExt(name)
+	if ext == "" {
+		return "", false
+	}
+	command, ok := commands[ext]
+	return command, ok && command != ""
+}
+
+// fileExt returns the lowercase extension of a file name without the dot,
+// as used for keys in the preview and open settings
+func fileExt(name string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+}
+
+// renderCommandPreview renders the output of a file's preview command, run
+// in the background
+func (m *FileManager) renderCommandPreview(file FileEntry, colWidth, maxHeight int) string {
+	preview, ok := m.loadedPreviewOf("command", file.Path)
+	if !ok {
+		return loadingMsg
+	}
+	return renderTextPreview([]byte(preview.text), colWidth, maxHeight, 0)
+}
+
+// expandCommand fills a command template with a path. "{}" is replaced by
+// the quoted path, otherwise the path is appended.
+func expandCommand(command, path string) string {
+	quoted := shellQuote(path)
+	if strings.Contains(command, "{}") {
+		return strings.ReplaceAll(command, "{}", quoted)
+	}
+	return command + " " + quoted
+}
+
+// runPreviewCommand runs a preview command template through the shell
+func runPreviewCommand(command, path string) string {
+	command = expandCommand(command, path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), previewCommandTimeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Dir = filepath.Dir(path)
+
+	output := &limitedBuffer{limit: previewCommandLimit}
+	cmd.Stdout = output
+	err := cmd.Run()
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "Preview command timed out"
+	case err != nil && output.Len() == 0:
+		return "Preview command failed: " + err.Error()
+	}
+	return output.String()
+}
+
+// shellQuote quotes a path for the platform shell
+func shellQuote(path string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + path + `"`
+	}
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// limitedBuffer keeps the first limit bytes written and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	// Report everything as written so the command isn't killed by a short write
+	return len(p), nil
+}
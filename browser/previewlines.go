@@ -0,0 +1,161 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Bytes of a file read for the line cursor at most; lines past them can't
+// be yanked
+const previewLinesLimit = 1 << 20
+
+// previewLines is a line cursor moved through a text file's preview, to
+// yank lines from it
+type previewLines struct {
+	lines  []string // Its lines
+	cursor int      // Line under the cursor
+	anchor int      // Other end of the selected range, or -1 for none
+	top    int      // First line shown
+}
+
+// selection returns the first and last lines to yank
+func (p *previewLines) selection() (int, int) {
+	if p.anchor < 0 {
+		return p.cursor, p.cursor
+	}
+	return min(p.anchor, p.cursor), max(p.anchor, p.cursor)
+}
+
+// text returns the lines to yank
+func (p *previewLines) text() string {
+	first, last := p.selection()
+	return strings.Join(p.lines[first:last+1], "\n")
+}
+
+// enterPreviewLines puts a line cursor in the preview of the selected file
+func (m *FileManager) enterPreviewLines() {
+	if m.Cursor >= len(m.Entries) || m.Entries[m.Cursor].IsDir {
+		return
+	}
+	if !m.autoPreview || m.minimal {
+		m.setStatus("No preview shown")
+		return
+	}
+	entry := m.Entries[m.Cursor]
+	content, err := readFileHead(m.filesystem(), entry.Path, previewLinesLimit+1)
+	if err != nil {
+		m.setError(err)
+		return
+	}
+	if len(content) == 0 || containsNullByte(content) {
+		m.setError(fmt.Errorf("%s is not a text file", entry.Name))
+		return
+	}
+	cut := len(content) > previewLinesLimit
+	if cut {
+		content = content[:previewLinesLimit]
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if cut {
+		// The last line read may be cut short
+		lines = lines[:max(len(lines)-1, 1)]
+		m.setStatus("Only the first %s of %s can be yanked", HumanSize(previewLinesLimit), entry.Name)
+	}
+	// The cursor starts on the first line shown
+	start := min(m.previewScroll, len(lines)-1)
+	m.previewLines = &previewLines{
+		lines:  lines,
+		cursor: start,
+		anchor: -1,
+		top:    start,
+	}
+}
+
+// follow scrolls the lines to keep the cursor among the height shown
+func (p *previewLines) follow(height int) {
+	height = max(height, 1)
+	if p.cursor < p.top {
+		p.top = p.cursor
+	} else if p.cursor >= p.top+height {
+		p.top = p.cursor - height + 1
+	}
+}
+
+// handlePreviewLinesKey handles keys while the preview has a line cursor
+func (m *FileManager) handlePreviewLinesKey(msg tea.KeyMsg) tea.Cmd {
+	p := m.previewLines
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		if p.anchor >= 0 {
+			p.anchor = -1
+		} else {
+			m.previewLines = nil
+		}
+	case "down", "j":
+		p.cursor = min(p.cursor+1, len(p.lines)-1)
+	case "up", "k":
+		p.cursor = max(p.cursor-1, 0)
+	case "g":
+		p.cursor = 0
+	case "G":
+		p.cursor = len(p.lines) - 1
+	case "v":
+		// Start a range at the cursor, or drop it
+		if p.anchor < 0 {
+			p.anchor = p.cursor
+		} else {
+			p.anchor = -1
+		}
+	case "y", "enter":
+		m.yankPreviewLines()
+	}
+	if m.previewLines != nil {
+		p.follow(m.previewHeight())
+	}
+	return nil
+}
+
+// yankPreviewLines copies the line under the cursor, or the selected range,
+// to the system clipboard and leaves the line cursor
+func (m *FileManager) yankPreviewLines() {
+	p := m.previewLines
+	first, last := p.selection()
+	if err := CopyToClipboard(p.text()); err != nil {
+		m.setError(err)
+		return
+	}
+	m.previewLines = nil
+	if first == last {
+		m.setStatus("Copied line %d to clipboard", first+1)
+	} else {
+		m.setStatus("Copied lines %d-%d to clipboard", first+1, last+1)
+	}
+}
+
+// renderPreviewLines renders the preview with the line cursor and the
+// selected range highlighted, from the first line scrolled to
+func (m *FileManager) renderPreviewLines(colWidth, maxHeight int) string {
+	p := m.previewLines
+	height := max(maxHeight, 1)
+
+	first, last := p.selection()
+	limit := max(colWidth-6, 0)
+	var preview strings.Builder
+	for i := p.top; i < min(p.top+height, len(p.lines)); i++ {
+		line := truncateRight(p.lines[i], limit, "…")
+		switch {
+		case i == p.cursor:
+			line = selectedStyle.Render("> " + line)
+		case i >= first && i <= last:
+			line = markedStyle.Render("| " + line)
+		default:
+			line = "  " + line
+		}
+		preview.WriteString(line + "\n")
+	}
+	return preview.String()
+}
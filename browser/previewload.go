@@ -0,0 +1,132 @@
+package browser
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Previews kept in the cache at most; past that it starts over
+const previewCacheSize = 256
+
+// previewKey identifies a preview worked out in the background: its kind,
+// the file it is of, and a stamp of what it was made from, like the file's
+// modification time, so a change makes a new one
+type previewKey struct {
+	kind  string
+	path  string
+	stamp string
+}
+
+// loadedPreview is a preview worked out in the background
+type loadedPreview struct {
+	text    string           // Text shown as it is, or after the members of an archive
+	members []archiveListing // First members of an archive
+	more    bool             // Whether the archive has more members than listed
+}
+
+// Custom message carrying a preview worked out in the background
+type previewLoadedMsg struct {
+	key     previewKey
+	preview loadedPreview
+}
+
+// startPreview works out in the background the preview of the selected
+// entry, when it takes running a program or reading more than View should,
+// unless it is cached or already being worked out
+func (m *FileManager) startPreview() tea.Cmd {
+	key, load := m.previewToLoad()
+	m.previewWanted = key
+	if load == nil || m.previewLoading[key] {
+		return nil
+	}
+	if _, ok := m.previewCache[key]; ok {
+		return nil
+	}
+
+	if m.previewLoading == nil {
+		m.previewLoading = make(map[previewKey]bool)
+	}
+	m.previewLoading[key] = true
+	m.startOp()
+	return func() tea.Msg {
+		return previewLoadedMsg{key: key, preview: load()}
+	}
+}
+
+// previewToLoad returns the key of the background preview the selected
+// entry needs and how to work it out, or nil when it needs none
+func (m *FileManager) previewToLoad() (previewKey, func() loadedPreview) {
+	if !m.autoPreview || m.previewLines != nil || m.Cursor >= len(m.Entries) {
+		return previewKey{}, nil
+	}
+	entry := m.Entries[m.Cursor]
+	if entry.IsDir {
+		return previewKey{}, nil
+	}
+	if m.diffPreview {
+		// A change to the file or a reload, as after staging, reads it again
+		info, err := m.filesystem().Stat(entry.Path)
+		if err != nil {
+			return previewKey{kind: "diff", path: entry.Path}, func() loadedPreview {
+				return loadedPreview{text: "Error reading file"}
+			}
+		}
+		key := previewKey{kind: "diff", path: entry.Path, stamp: fmt.Sprintf("%s %d", info.ModTime(), m.gitSeq)}
+		return key, func() loadedPreview {
+			return loadedPreview{text: gitDiff(entry.Path)}
+		}
+	}
+	if command, ok := previewCommandFor(m.previewCommands, entry.Name); ok {
+		// A new command or a change to the file runs it again
+		info, err := m.filesystem().Stat(entry.Path)
+		if err != nil {
+			return previewKey{kind: "command", path: entry.Path}, func() loadedPreview {
+				return loadedPreview{text: "Error reading file"}
+			}
+		}
+		key := previewKey{kind: "command", path: entry.Path, stamp: command + "\x00" + info.ModTime().String()}
+		return key, func() loadedPreview {
+			return loadedPreview{text: runPreviewCommand(command, entry.Path)}
+		}
+	}
+	if isVideoFile(entry.Name) && m.archive == nil {
+		return previewKey{kind: "video", path: entry.Path}, func() loadedPreview {
+			return loadedPreview{text: probeVideo(entry.Path)}
+		}
+	}
+	if isArchive(entry.Name) {
+		fsys := m.filesystem()
+		info, err := fsys.Stat(entry.Path)
+		if err != nil {
+			return previewKey{kind: "archive", path: entry.Path}, func() loadedPreview {
+				return loadedPreview{text: "Error reading archive: " + err.Error()}
+			}
+		}
+		key := previewKey{kind: "archive", path: entry.Path, stamp: fmt.Sprintf("%s %d", info.ModTime(), info.Size())}
+		return key, func() loadedPreview {
+			return listArchivePreview(fsys, entry.Path)
+		}
+	}
+	return previewKey{}, nil
+}
+
+// handlePreviewLoaded caches a preview worked out in the background
+func (m *FileManager) handlePreviewLoaded(msg previewLoadedMsg) {
+	delete(m.previewLoading, msg.key)
+	m.finishOp()
+	if m.previewCache == nil || len(m.previewCache) >= previewCacheSize {
+		m.previewCache = make(map[previewKey]loadedPreview)
+	}
+	m.previewCache[msg.key] = msg.preview
+}
+
+// loadedPreviewOf returns the background preview of the given kind of the
+// file at path, reporting false while it is being worked out
+func (m *FileManager) loadedPreviewOf(kind, path string) (loadedPreview, bool) {
+	if m.previewWanted.kind != kind || m.previewWanted.path != path {
+		return loadedPreview{}, false
+	}
+	preview, ok := m.previewCache[m.previewWanted]
+	return preview, ok
+}
@@ -0,0 +1,86 @@
+package browser
+
+import (
+	"strings"
+	"time"
+)
+
+// lineCountKey identifies a counted preview: the file, as it was last
+// modified, laid out in a window width wide
+type lineCountKey struct {
+	path    string
+	modTime time.Time
+	width   int
+}
+
+// scrollLines returns the lines of a preview shown from scroll on, at most
+// maxHeight of them and "..." when more follow. The offset stops once the
+// last line is in view.
+func scrollLines(lines []string, maxHeight, scroll int) []string {
+	scroll = min(max(scroll, 0), max(len(lines)-maxHeight, 0))
+	lines = lines[scroll:]
+	if len(lines) > maxHeight {
+		lines = append(lines[:maxHeight:maxHeight], "...")
+	}
+	return lines
+}
+
+// selectedPath returns the path of the selected entry, or "" for none
+func (m *FileManager) selectedPath() string {
+	if m.Cursor < len(m.Entries) {
+		return m.Entries[m.Cursor].Path
+	}
+	return ""
+}
+
+// scrollPreview scrolls the preview of the selected file by delta lines,
+// keeping the last line in view
+func (m *FileManager) scrollPreview(delta int) {
+	if m.Cursor >= len(m.Entries) || m.Entries[m.Cursor].IsDir || !m.autoPreview {
+		return
+	}
+	lines := m.previewLineCount(m.Entries[m.Cursor])
+	m.previewScroll = min(max(m.previewScroll+delta, 0), max(lines-m.previewHeight(), 0))
+}
+
+// previewLineCount returns how many lines the preview of file has before it
+// is cut to the pane, or 0 when it can't be scrolled. It is counted once
+// for each version of the file and width of the window.
+func (m *FileManager) previewLineCount(file FileEntry) int {
+	if m.diffPreview || isVideoFile(file.Name) {
+		return 0
+	}
+	if _, ok := previewCommandFor(m.previewCommands, file.Name); ok {
+		return 0
+	}
+	info, err := m.filesystem().Stat(file.Path)
+	if err != nil {
+		return 0
+	}
+	key := lineCountKey{path: file.Path, modTime: info.ModTime(), width: m.Width}
+	if lines, ok := m.lineCounts[key]; ok {
+		return lines
+	}
+	lines := m.countPreviewLines(file)
+	if m.lineCounts == nil || len(m.lineCounts) >= previewCacheSize {
+		m.lineCounts = make(map[lineCountKey]int)
+	}
+	m.lineCounts[key] = lines
+	return lines
+}
+
+// countPreviewLines reads and lays out the preview of file to count its lines
+func (m *FileManager) countPreviewLines(file FileEntry) int {
+	content, err := readFile(m.filesystem(), file.Path)
+	if err != nil || len(content) == 0 || containsNullByte(content) {
+		return 0
+	}
+	if strings.HasSuffix(strings.ToLower(file.Name), ".md") {
+		rendered, err := markdownRenderer.Render(string(content))
+		if err != nil {
+			return 0
+		}
+		return strings.Count(rendered, "\n") + 1
+	}
+	return strings.Count(string(content), "\n") + 1
+}
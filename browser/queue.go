@@ -0,0 +1,103 @@
+package browser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// queuedOp is a cut or copy waiting in the paste queue
+type queuedOp struct {
+	op    string    // "copy" or "cut"
+	entry FileEntry // What to paste
+}
+
+// queueSelected adds the selected entry to the paste queue. Queueing an
+// entry again replaces its earlier operation.
+func (m *FileManager) queueSelected(op string) {
+	if len(m.Entries) == 0 || m.Cursor >= len(m.Entries) {
+		return
+	}
+	entry := m.Entries[m.Cursor]
+
+	queue := m.pasteQueue[:0]
+	for _, queued := range m.pasteQueue {
+		if queued.entry.Path != entry.Path {
+			queue = append(queue, queued)
+		}
+	}
+	m.pasteQueue = append(queue, queuedOp{op: op, entry: entry})
+	m.setStatus("Queued %s of %s (%d in queue)", op, entry.Name, len(m.pasteQueue))
+}
+
+// pasteQueued runs every queued operation into the current directory, in the
+// order they were queued. Each records its own undo entry; operations that
+// fail stay in the queue.
+func (m *FileManager) pasteQueued() tea.Cmd {
+	if len(m.pasteQueue) == 0 {
+		m.setStatus("Paste queue is empty")
+		return nil
+	}
+	if m.checkingSpace {
+		m.setStatus("Still checking the space for the last paste")
+		return nil
+	}
+
+	var copies []string
+	for _, queued := range m.pasteQueue {
+		if queued.op == "copy" {
+			copies = append(copies, queued.entry.Path)
+		}
+	}
+	return m.checkSpace(copies, m.pasteQueuedFitting)
+}
+
+// pasteQueuedFitting is pasteQueued once the copies are known to fit
+func (m *FileManager) pasteQueuedFitting() tea.Cmd {
+	var failed []queuedOp
+	var lastErr error
+	pasted := 0
+	m.pastedName = ""
+	for _, queued := range m.pasteQueue {
+		// Moving into the directory it's already in is a no-op
+		if queued.op == "cut" && filepath.Dir(queued.entry.Path) == m.CurrentPath {
+			continue
+		}
+		ok, err := m.pasteEntry(queued.entry, queued.op, collisionKeep)
+		if err != nil {
+			failed = append(failed, queued)
+			lastErr = err
+		} else if ok {
+			pasted++
+		}
+	}
+	m.pasteQueue = failed
+	m.reloadEntries()
+	if m.pastedName != "" {
+		m.selectByName(m.pastedName)
+	}
+
+	if lastErr != nil {
+		m.setError(fmt.Errorf("%s left in the queue: %w", pluralize(len(failed), "item"), lastErr))
+		return nil
+	}
+	m.showQueue = false
+	m.setStatus("Pasted %s from the queue", pluralize(pasted, "item"))
+	return nil
+}
+
+// renderQueue renders the paste queue overlay
+func (m *FileManager) renderQueue() string {
+	var content strings.Builder
+	if len(m.pasteQueue) == 0 {
+		content.WriteString("Paste queue is empty (c to queue a copy, x a cut)")
+	} else {
+		content.WriteString(fmt.Sprintf("Paste queue: %s (ctrl+v pastes here, C clears)", pluralize(len(m.pasteQueue), "item")))
+		for _, queued := range m.pasteQueue {
+			content.WriteString(fmt.Sprintf("\n  %-4s %s", queued.op, queued.entry.Path))
+		}
+	}
+	return whichKeyStyle.Width(m.Width).Render(content.String())
+}
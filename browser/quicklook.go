@@ -0,0 +1,71 @@
+package browser
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// What enter does on files
+const (
+	enterFilesOpen    = "open"    // Default: open them with their opener or the system's
+	enterFilesPreview = "preview" // Show them in the quick look
+)
+
+// quickLook is a file's preview shown across the whole screen
+type quickLook struct {
+	entry FileEntry // File being shown
+	top   int       // First line shown
+}
+
+// openQuickLook shows the selected file's preview across the whole screen
+func (m *FileManager) openQuickLook() {
+	if m.Cursor >= len(m.Entries) || m.Entries[m.Cursor].IsDir {
+		return
+	}
+	m.quickLook = &quickLook{entry: m.Entries[m.Cursor]}
+}
+
+// handleQuickLookKey handles keys while the quick look is open
+func (m *FileManager) handleQuickLookKey(msg tea.KeyMsg) tea.Cmd {
+	q := m.quickLook
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc", "V", "h", "left":
+		m.quickLook = nil
+	case "down", "j":
+		// Stop scrolling once the last line is at the top
+		lines := m.quickLookLines(max(m.Width-4, 0), q.top+1+m.listHeight())
+		q.top = min(q.top+1, max(len(lines)-1, 0))
+	case "up", "k":
+		q.top = max(q.top-1, 0)
+	case "g":
+		q.top = 0
+	case "ctrl+o":
+		m.quickLook = nil
+		return m.openFile(q.entry)
+	}
+	return nil
+}
+
+// renderQuickLook renders the quick look, scrolled down to its top line.
+// The preview is rendered down to the last line shown and its start cut,
+// so scrolling works the same for every kind of preview.
+func (m *FileManager) renderQuickLook(width, height int) string {
+	q := m.quickLook
+	if !m.autoPreview {
+		return getFileInfo(m.filesystem(), q.entry.Path, m.relativeTimes, m.dirCounts)
+	}
+	lines := m.quickLookLines(width, q.top+height)
+	// The file may have shrunk since it was scrolled
+	top := min(q.top, max(len(lines)-1, 0))
+	return strings.Join(lines[top:min(top+height, len(lines))], "\n")
+}
+
+// quickLookLines returns the lines of the quick look's preview, rendered
+// width wide down to line rows
+func (m *FileManager) quickLookLines(width, rows int) []string {
+	content := m.renderEntryPreview(m.quickLook.entry, width, rows)
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
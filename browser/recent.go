@@ -0,0 +1,107 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// How many recent directories are kept when no limit is configured
+const defaultRecentLimit = 50
+
+// visitRecent moves path to the front of the recent directories, dropping
+// the oldest beyond the limit. Directories inside archives aren't kept, as
+// they can't be returned to directly.
+func (m *FileManager) visitRecent(path string) {
+	if m.archive != nil {
+		return
+	}
+	recent := []string{path}
+	for _, dir := range m.recentDirs {
+		if dir != path {
+			recent = append(recent, dir)
+		}
+	}
+	m.recentDirs = recent[:min(len(recent), m.recentLimit)]
+}
+
+// RecentDirs returns the recently visited directories, most recent first
+func (m *FileManager) RecentDirs() []string {
+	return m.recentDirs
+}
+
+// openRecentPicker lists the recent directories to jump back to, leaving
+// out the current one
+func (m *FileManager) openRecentPicker() {
+	var picks []string
+	for _, dir := range m.recentDirs {
+		if dir != m.CurrentPath {
+			picks = append(picks, dir)
+		}
+	}
+	if len(picks) == 0 {
+		m.setStatus("No recent directories")
+		return
+	}
+	m.recentPicks = picks
+	m.recentCursor = 0
+}
+
+// handleRecentKey handles keys while the recent directories picker is open
+func (m *FileManager) handleRecentKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		m.recentPicks = nil
+	case "up", "k":
+		if m.recentCursor > 0 {
+			m.recentCursor--
+		}
+	case "down", "j":
+		if m.recentCursor < len(m.recentPicks)-1 {
+			m.recentCursor++
+		}
+	case "l", "enter", "right":
+		dir := m.recentPicks[m.recentCursor]
+		m.recentPicks = nil
+		m.jumpToRecent(dir)
+	}
+	return nil
+}
+
+// jumpToRecent navigates to a recent directory, forgetting it if it's gone
+func (m *FileManager) jumpToRecent(dir string) {
+	if info, err := m.filesystem().Stat(dir); err != nil || !info.IsDir() {
+		m.forgetRecent(dir)
+		m.setError(fmt.Errorf("%s no longer exists", dir))
+		return
+	}
+	m.openDir(dir, "")
+}
+
+// forgetRecent removes a directory from the recent list
+func (m *FileManager) forgetRecent(dir string) {
+	recent := m.recentDirs[:0]
+	for _, d := range m.recentDirs {
+		if d != dir {
+			recent = append(recent, d)
+		}
+	}
+	m.recentDirs = recent
+}
+
+// renderRecent renders the recent directories picker overlay
+func (m *FileManager) renderRecent() string {
+	var content strings.Builder
+	content.WriteString("Recent directories (enter jumps, esc cancels)")
+	for i, dir := range m.recentPicks {
+		marker := "  "
+		if i == m.recentCursor {
+			marker = "> "
+		}
+		content.WriteString("\n" + marker + truncateLeft(dir, m.Width-6))
+	}
+	return whichKeyStyle.Width(m.Width).Render(content.String())
+}
@@ -0,0 +1,114 @@
+package browser
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Style of the character under the rename caret and of the selected stem
+var caretStyle = lipgloss.NewStyle().Reverse(true)
+
+// startRename edits the selected entry's name. A file's stem is selected,
+// so typing replaces it and keeps the extension.
+func (m *FileManager) startRename() {
+	if len(m.Entries) == 0 || m.Cursor >= len(m.Entries) {
+		return
+	}
+	entry := m.Entries[m.Cursor]
+	m.renameMode = true
+	m.renameText = entry.Name
+	m.renameExists = false
+	m.renameCursor = len([]rune(entry.Name))
+	m.renameStem = 0
+
+	// Dotfiles like .bashrc are all stem
+	stem := strings.TrimSuffix(entry.Name, filepath.Ext(entry.Name))
+	if !entry.IsDir && stem != "" {
+		m.renameStem = len([]rune(stem))
+		m.renameCursor = m.renameStem
+	}
+}
+
+// handleRenameKey edits the rename text at the caret
+func (m *FileManager) handleRenameKey(msg tea.KeyMsg) tea.Cmd {
+	text := []rune(m.renameText)
+	selected := m.renameStem
+	m.renameStem = 0
+
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.renameMode = false
+		m.renameFile(m.renameText)
+		m.renameText = ""
+		return nil
+	case tea.KeyEsc:
+		m.renameMode = false
+		m.renameText = ""
+		return nil
+	case tea.KeyLeft:
+		// Leaving the selection puts the caret at its start
+		if selected > 0 {
+			m.renameCursor = 0
+		} else {
+			m.renameCursor = max(m.renameCursor-1, 0)
+		}
+		return nil
+	case tea.KeyRight:
+		m.renameCursor = min(m.renameCursor+1, len(text))
+		if selected > 0 {
+			m.renameCursor = selected
+		}
+		return nil
+	case tea.KeyHome, tea.KeyCtrlA:
+		m.renameCursor = 0
+		return nil
+	case tea.KeyEnd, tea.KeyCtrlE:
+		m.renameCursor = len(text)
+		return nil
+	case tea.KeyBackspace:
+		if selected > 0 {
+			text = text[selected:]
+			m.renameCursor = 0
+		} else if m.renameCursor > 0 {
+			text = slices.Delete(text, m.renameCursor-1, m.renameCursor)
+			m.renameCursor--
+		}
+	case tea.KeyDelete:
+		if selected > 0 {
+			text = text[selected:]
+			m.renameCursor = 0
+		} else if m.renameCursor < len(text) {
+			text = slices.Delete(text, m.renameCursor, m.renameCursor+1)
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		// Typing over the selected stem replaces it
+		if selected > 0 {
+			text = text[selected:]
+			m.renameCursor = 0
+		}
+		text = slices.Insert(text, m.renameCursor, msg.Runes...)
+		m.renameCursor += len(msg.Runes)
+	default:
+		m.renameStem = selected
+		return nil
+	}
+	m.renameText = string(text)
+	return m.scheduleRenameCheck()
+}
+
+// renderRenameText renders the rename text with the selected stem or the
+// character under the caret highlighted
+func (m *FileManager) renderRenameText() string {
+	text := []rune(m.renameText)
+	if m.renameStem > 0 {
+		return caretStyle.Render(string(text[:m.renameStem])) + string(text[m.renameStem:])
+	}
+	if m.renameCursor >= len(text) {
+		return m.renameText + "█"
+	}
+	return string(text[:m.renameCursor]) + caretStyle.Render(string(text[m.renameCursor])) + string(text[m.renameCursor+1:])
+}
@@ -0,0 +1,66 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// What enter does on executable files
+const (
+	executablesOpen = "open" // Default: hand them to the opener like any file
+	executablesAsk  = "ask"  // Ask whether to run them
+)
+
+// isExecutable reports whether an entry is a file with an execute bit set
+func (m *FileManager) isExecutable(entry FileEntry) bool {
+	if entry.IsDir || runtime.GOOS == "windows" {
+		return false
+	}
+	info, err := m.filesystem().Stat(entry.Path)
+	return err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0
+}
+
+// confirmRun asks whether to run an executable, run it with arguments, or
+// open it like any other file
+func (m *FileManager) confirmRun(entry FileEntry) {
+	m.confirm = &confirmation{
+		prompt: fmt.Sprintf("Run %s? [r]un  [a]rguments  [o]pen  [n]o", entry.Name),
+		actions: map[string]func() tea.Cmd{
+			"r": func() tea.Cmd { return m.runExecutable(entry, "") },
+			"a": func() tea.Cmd {
+				m.prompt = &textPrompt{
+					label: "Arguments for " + entry.Name,
+					submit: func(args string) tea.Cmd {
+						return m.runExecutable(entry, args)
+					},
+				}
+				return nil
+			},
+			"o": func() tea.Cmd { return m.openFile(entry) },
+			"n": func() tea.Cmd { return nil },
+		},
+	}
+}
+
+// runExecutable runs a file in the current directory with the TUI suspended.
+// Arguments are passed through the shell as typed. The output stays on
+// screen until enter is pressed, and the listing is reloaded afterwards.
+func (m *FileManager) runExecutable(entry FileEntry, args string) tea.Cmd {
+	command := shellQuote(entry.Path)
+	if args = strings.TrimSpace(args); args != "" {
+		command += " " + args
+	}
+	if runtime.GOOS != "windows" {
+		command += `; printf '\n[exit %d] Press enter to return to tfm' $?; read _`
+	}
+
+	cmd := shellCommand(context.Background(), command)
+	cmd.Dir = m.CurrentPath
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return reloadDirectoryMsg{err: err}
+	})
+}
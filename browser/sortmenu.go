@@ -0,0 +1,115 @@
+package browser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sortMenuItem is a sort mode offered in the sort menu
+type sortMenuItem struct {
+	key   string
+	mode  string
+	label string
+}
+
+// Sort modes in the order the menu lists them
+var sortMenuItems = []sortMenuItem{
+	{"n", sortName, "name"},
+	{"s", sortSize, "size, largest first"},
+	{"m", sortMtime, "modified, newest first"},
+	{"e", sortExt, "extension"},
+	{"t", sortType, "type (links, executables, then other files)"},
+}
+
+// handleSortMenuKey picks a sort mode from the open sort menu
+func (m *FileManager) handleSortMenuKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		m.showSortMenu = false
+	default:
+		for _, item := range sortMenuItems {
+			if msg.String() == item.key {
+				m.showSortMenu = false
+				m.setSort(item.mode)
+			}
+		}
+	}
+	return nil
+}
+
+// setSort sorts directories and files by mode, keeping the cursor on the
+// selected entry
+func (m *FileManager) setSort(mode string) {
+	m.listOptions.DirSort = mode
+	m.listOptions.FileSort = mode
+	m.reloadKeepingSelection()
+	m.setStatus("Sorted by %s", m.sortLabel())
+}
+
+// cycleSort sorts by the mode after the files' current one, in the menu's order
+func (m *FileManager) cycleSort() {
+	current := sortModeOrName(m.listOptions.FileSort)
+	i := slices.IndexFunc(sortMenuItems, func(item sortMenuItem) bool { return item.mode == current })
+	m.setSort(sortMenuItems[(i+1)%len(sortMenuItems)].mode)
+}
+
+// toggleSortReverse flips the order within directories and files
+func (m *FileManager) toggleSortReverse() {
+	m.listOptions.Reverse = !m.listOptions.Reverse
+	m.reloadKeepingSelection()
+	m.setStatus("Sorted by %s", m.sortLabel())
+}
+
+// sortModeOrName returns mode, or the name mode an empty mode sorts by
+func sortModeOrName(mode string) string {
+	if mode == "" {
+		return sortName
+	}
+	return mode
+}
+
+// sortLabel describes the sort in effect, e.g. "size, reversed"
+func (m *FileManager) sortLabel() string {
+	dirSort, fileSort := sortModeOrName(m.listOptions.DirSort), sortModeOrName(m.listOptions.FileSort)
+	label := fileSort
+	if dirSort != fileSort {
+		label = fmt.Sprintf("%s for directories, %s for files", dirSort, fileSort)
+	}
+	if m.listOptions.Reverse {
+		label += ", reversed"
+	}
+	return label
+}
+
+// renderSortMenu renders the sort menu overlay, marking the current modes
+func (m *FileManager) renderSortMenu() string {
+	dirSort, fileSort := sortModeOrName(m.listOptions.DirSort), sortModeOrName(m.listOptions.FileSort)
+
+	var content strings.Builder
+	content.WriteString("Sort by (esc cancels)")
+	for _, item := range sortMenuItems {
+		var marks []string
+		if item.mode == dirSort {
+			marks = append(marks, "directories")
+		}
+		if item.mode == fileSort {
+			marks = append(marks, "files")
+		}
+		marker := "  "
+		if len(marks) > 0 {
+			marker = "* "
+		}
+		line := fmt.Sprintf("\n%s%s  %s", marker, item.key, item.label)
+		// Only say which group when the two are sorted differently
+		if len(marks) == 1 {
+			line += " (" + marks[0] + ")"
+		}
+		content.WriteString(line)
+	}
+	return whichKeyStyle.Width(m.Width).Render(content.String())
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// readlinkFS is implemented by filesystems with symlinks
+type readlinkFS interface {
+	Readlink(name string) (string, error)
+}
+
+func (osFS) Readlink(name string) (string, error) { return os.Readlink(name) }
+
+// lstatFS is implemented by filesystems that can stat a symlink itself
+type lstatFS interface {
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+func (osFS) Lstat(name string) (fs.FileInfo, error) { return os.Lstat(name) }
+
+// lstat stats name without following a symlink, where fsys tells them apart
+func lstat(fsys FS, name string) (fs.FileInfo, error) {
+	if links, ok := fsys.(lstatFS); ok {
+		return links.Lstat(name)
+	}
+	return fsys.Stat(name)
+}
+
+// symlinkTarget is where a symlink points
+type symlinkTarget struct {
+	raw      string // As stored in the link, maybe relative to the link's directory
+	resolved string // Absolute path the link leads to
+	broken   bool   // Whether nothing exists at resolved
+	dir      bool   // Whether resolved is a directory
+}
+
+// readSymlink reads where the symlink at path points. A relative target is
+// relative to the directory holding the link, not to tfm's working
+// directory, so it is resolved against that before checking it exists.
+func readSymlink(fsys FS, path string) (symlinkTarget, error) {
+	links, ok := fsys.(readlinkFS)
+	if !ok {
+		return symlinkTarget{}, errors.ErrUnsupported
+	}
+	raw, err := links.Readlink(path)
+	if err != nil {
+		return symlinkTarget{}, err
+	}
+
+	resolved := raw
+	if !filepath.IsAbs(raw) {
+		resolved = filepath.Join(filepath.Dir(path), raw)
+	}
+	// Stat the link itself, so links to links are followed to the end, and
+	// only count it broken when nothing is there, not when it can't be read
+	info, err := fsys.Stat(path)
+	return symlinkTarget{raw: raw, resolved: resolved, broken: errors.Is(err, fs.ErrNotExist), dir: err == nil && info.IsDir()}, nil
+}
+
+// String describes the target, with the absolute path when the link holds
+// a relative one
+func (t symlinkTarget) String() string {
+	text := "→ " + t.raw
+	if t.resolved != t.raw {
+		text += " (" + t.resolved + ")"
+	}
+	if t.broken {
+		text += " [broken]"
+	}
+	return text
+}
+
+// renderLink styles the name of a symlink and follows it with where it
+// points, in what is left of width
+func renderLink(entry FileEntry, name string, width int) string {
+	style := linkStyle
+	if entry.brokenLink {
+		style = brokenLinkStyle
+	}
+	if entry.IsDir {
+		name += "/"
+	}
+	line := style.Render(name)
+	if room := width - lipgloss.Width(name) - 3; room > 3 && entry.Target != "" {
+		line += countStyle.Render(" → " + truncateLeft(entry.Target, room))
+	}
+	return line
+}
@@ -0,0 +1,112 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openTemplatePicker lists the templates to create a new file from
+func (m *FileManager) openTemplatePicker() {
+	if m.templateDir == "" {
+		m.setError(fmt.Errorf("no template directory configured"))
+		return
+	}
+	files, err := m.filesystem().ReadDir(m.templateDir)
+	if err != nil && !os.IsNotExist(err) {
+		m.setError(err)
+		return
+	}
+
+	var templates []string
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), ".") {
+			templates = append(templates, file.Name())
+		}
+	}
+	if len(templates) == 0 {
+		m.setStatus("No templates in %s", m.templateDir)
+		return
+	}
+	m.templates = templates
+	m.templateCursor = 0
+}
+
+// handleTemplateKey handles keys while the template picker is open
+func (m *FileManager) handleTemplateKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		m.templates = nil
+	case "up", "k":
+		if m.templateCursor > 0 {
+			m.templateCursor--
+		}
+	case "down", "j":
+		if m.templateCursor < len(m.templates)-1 {
+			m.templateCursor++
+		}
+	case "l", "enter", "right":
+		// Ask for the new file's name, starting from the template's
+		template := m.templates[m.templateCursor]
+		m.templates = nil
+		m.prompt = &textPrompt{
+			label: "New file from " + template,
+			text:  template,
+			submit: func(name string) tea.Cmd {
+				m.createFromTemplate(template, name)
+				return nil
+			},
+		}
+	}
+	return nil
+}
+
+// createFromTemplate copies a template into the current directory as name
+func (m *FileManager) createFromTemplate(template, name string) {
+	if name == "" || strings.ContainsRune(name, filepath.Separator) {
+		m.setError(fmt.Errorf("invalid file name %q", name))
+		return
+	}
+
+	fsys := m.filesystem()
+	dest := filepath.Join(m.CurrentPath, name)
+	if _, err := fsys.Stat(dest); err == nil {
+		m.setError(fmt.Errorf("%s already exists", name))
+		return
+	}
+	m.runHook("pre_create", dest, "")
+	if err := copyPath(fsys, filepath.Join(m.templateDir, template), dest); err != nil {
+		m.setError(err)
+		return
+	}
+	m.runHook("post_create", dest, "")
+	m.pushUndo(UndoAction{
+		Type:    "create",
+		OldPath: filepath.Join(m.templateDir, template), // Copied from, for redo
+		NewPath: dest,
+		Entry:   FileEntry{Name: name, Path: dest},
+	})
+
+	m.reloadEntries()
+	m.selectByName(name)
+	m.setStatus("Created %s from %s", name, template)
+}
+
+// renderTemplates renders the template picker overlay
+func (m *FileManager) renderTemplates() string {
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("Templates in %s (enter picks, esc cancels)", m.templateDir))
+	for i, template := range m.templates {
+		marker := "  "
+		if i == m.templateCursor {
+			marker = "> "
+		}
+		content.WriteString("\n" + marker + template)
+	}
+	return whichKeyStyle.Width(m.Width).Render(content.String())
+}
@@ -0,0 +1,31 @@
+package browser
+
+import "github.com/mattn/go-runewidth"
+
+// Text is measured in terminal cells rather than bytes or runes, so wide
+// characters (CJK, emoji) take two and multibyte ones are never split.
+
+// truncateRight shortens s to width cells, ending it with tail when cut
+func truncateRight(s string, width int, tail string) string {
+	if width < 1 {
+		return ""
+	}
+	return runewidth.Truncate(s, width, tail)
+}
+
+// truncateLeft shortens s to width cells by dropping its start
+func truncateLeft(s string, width int) string {
+	if width < 1 || runewidth.StringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	kept := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		w := runewidth.RuneWidth(runes[i])
+		if kept+w > width-1 {
+			return "…" + string(runes[i+1:])
+		}
+		kept += w
+	}
+	return s
+}
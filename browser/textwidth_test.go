@@ -0,0 +1,188 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+func TestTruncateRight(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		tail  string
+		want  string
+	}{
+		{"hello", 10, "...", "hello"},
+		{"hello world", 8, "...", "hello..."},
+		// A two-byte rune right at the boundary is kept whole or dropped
+		{"caféteria", 4, "", "café"},
+		{"naïve", 2, "", "na"},
+		// Wide runes take two cells; one that doesn't fit is dropped
+		{"日本語のテキスト", 7, "...", "日本..."},
+		{"日本語", 5, "", "日本"},
+		{"😀😀😀", 4, "", "😀😀"},
+		{"abc", 0, "", ""},
+	}
+	for _, tt := range tests {
+		got := truncateRight(tt.s, tt.width, tt.tail)
+		if got != tt.want {
+			t.Errorf("truncateRight(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateRight(%q, %d) split a rune: %q", tt.s, tt.width, got)
+		}
+	}
+}
+
+func TestTruncateLeft(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"/short", 10, "/short"},
+		{"/a/long/path", 6, "…/path"},
+		{"/données/été", 5, "…/été"},
+		{"/写真/猫.jpg", 7, "…猫.jpg"},
+	}
+	for _, tt := range tests {
+		got := truncateLeft(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("truncateLeft(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if w := runewidth.StringWidth(got); w > tt.width {
+			t.Errorf("truncateLeft(%q, %d) is %d cells wide", tt.s, tt.width, w)
+		}
+	}
+}
+
+func TestTextPreviewMultibyte(t *testing.T) {
+	// Lines longer than the column, with multibyte runes where they get cut
+	content := strings.Repeat("é", 40) + "\n" + strings.Repeat("漢", 40) + "\n"
+	preview := renderTextPreview([]byte(content), 20, 10, 0)
+
+	if !utf8.ValidString(preview) {
+		t.Fatalf("preview split a rune: %q", preview)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(preview, "\n"), "\n") {
+		if w := runewidth.StringWidth(line); w > 16 {
+			t.Errorf("line %q is %d cells wide, want at most 16", line, w)
+		}
+	}
+}
+
+func TestViewWideNames(t *testing.T) {
+	// Wide names in every column: the parent, the current one and the preview
+	root := t.TempDir()
+	current := filepath.Join(root, "日本語のディレクトリ名がとても長い")
+	inner := filepath.Join(current, "写真と動画のフォルダ😀😀😀😀😀😀")
+	if err := os.MkdirAll(filepath.Join(inner, "猫の写真をたくさん集めたファイル名.jpg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, width := range []int{minWidth, 80, 120} {
+		m := &FileManager{CurrentPath: current, Width: width, Height: 20, autoPreview: true}
+		m.Entries, _ = m.readDirectory(current)
+		loadListings(m)
+		lines := strings.Split(m.View(), "\n")
+
+		// Each name is cut to one line instead of wrapping onto the next
+		for _, prefix := range []string{"日本", "写真", "猫の"} {
+			found := false
+			for i, line := range lines {
+				// The header shows the whole path when it fits
+				if !strings.Contains(line, prefix) || strings.Contains(line, root) {
+					continue
+				}
+				found = true
+				if !strings.Contains(line, "…") {
+					t.Errorf("width %d: name starting %s not truncated on line %d: %q", width, prefix, i, line)
+				}
+			}
+			if !found {
+				t.Errorf("width %d: no name starting %s shown", width, prefix)
+			}
+		}
+		for i, line := range lines {
+			// Blank padding lines are cut by the renderer; text must fit
+			if w := lipgloss.Width(strings.TrimRight(line, " ")); w > width {
+				t.Errorf("width %d: line %d is %d cells wide: %q", width, i, w, line)
+			}
+		}
+	}
+}
+
+func TestViewSizes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{"a.txt": 10, "写真のファイル名がとても長いです.jpg": 2048, "b.bin": 5 << 20}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, Width: 100, Height: 20, showSizes: true, dirCounts: map[string]int{filepath.Join(dir, "sub"): 3}}
+	m.Entries, _ = m.readDirectory(dir)
+
+	// Sizes and directory counts end in the same column
+	end := -1
+	for _, size := range []string{"3", "10B", "2.0K", "5.0M"} {
+		found := false
+		for _, line := range strings.Split(m.View(), "\n") {
+			i := strings.Index(line, " "+size+" ")
+			if i < 0 || !strings.Contains(line, "  ") {
+				continue
+			}
+			found = true
+			if e := lipgloss.Width(line[:i+1+len(size)]); end < 0 {
+				end = e
+			} else if e != end {
+				t.Errorf("size %s ends at %d, want %d: %q", size, e, end, line)
+			}
+			break
+		}
+		if !found {
+			t.Errorf("size %s not shown", size)
+		}
+	}
+
+	// Directories not counted yet show a dash
+	uncounted := FileEntry{Name: "new", Path: filepath.Join(dir, "new"), IsDir: true}
+	if size := m.entrySize(&uncounted); size != "-" {
+		t.Errorf("expected - for a directory not counted yet, got %q", size)
+	}
+}
+
+func TestHighlightedPreview(t *testing.T) {
+	source := "package main\n\n// main prints a greeting that is much longer than the preview column\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+	preview, ok := renderHighlightedPreview("main.go", []byte(source), "monokai", 30, 4, 0)
+	if !ok || !strings.Contains(preview, "\x1b[") {
+		t.Fatalf("expected Go source highlighted, got %q", preview)
+	}
+	lines := strings.Split(strings.TrimSuffix(preview, "\n"), "\n")
+	// Four lines of the file, then a marker for the rest
+	if len(lines) != 5 || lines[4] != "..." {
+		t.Errorf("expected 4 lines and a marker, got %q", lines)
+	}
+	for _, line := range lines {
+		if w := lipgloss.Width(line); w > 26 {
+			t.Errorf("line %q is %d cells wide, want at most 26", line, w)
+		}
+	}
+
+	// Plain text and a theme left empty keep the plain preview
+	if _, ok := renderHighlightedPreview("notes.txt", []byte("hello"), "monokai", 30, 4, 0); ok {
+		t.Error("expected plain text left unhighlighted")
+	}
+	if _, ok := renderHighlightedPreview("main.go", []byte(source), "", 30, 4, 0); ok {
+		t.Error("expected no highlighting without a theme")
+	}
+}
@@ -0,0 +1,393 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Name of the index kept inside a trash directory. A deleted file with the
+// same name is stored with a suffix, like any other name collision.
+const trashIndexName = ".tfm-trash.json"
+
+// Name of the file held while the index is changed, so tfm processes
+// sharing a trash, or a trash emptied in the background, don't lose entries
+const trashLockName = ".tfm-trash.lock"
+
+// How long a lock can be held before it is taken to be left behind by a
+// process that died
+const trashLockStale = 10 * time.Second
+
+// TrashItem is an entry moved to the trash
+type TrashItem struct {
+	Name      string    `json:"name"`       // Name in the trash directory
+	Path      string    `json:"path"`       // Where it was deleted from
+	DeletedAt time.Time `json:"deleted_at"` // When it was deleted
+}
+
+// Trash is a directory deleted entries are moved to, with an index of where
+// each came from so it can be restored, in this session or a later one
+type Trash struct {
+	Dir string
+	FS  FS // Filesystem the trash is on; OS if nil
+}
+
+// fsys returns the filesystem the trash is on
+func (t Trash) fsys() FS {
+	if t.FS == nil {
+		return OS
+	}
+	return t.FS
+}
+
+// indexPath returns the path of the trash index
+func (t Trash) indexPath() string {
+	return filepath.Join(t.Dir, trashIndexName)
+}
+
+// Items returns the entries in the trash, oldest first. Entries that have
+// gone since they were trashed (restored by undo, or removed by hand) are
+// left out.
+func (t Trash) Items() ([]TrashItem, error) {
+	items, err := t.index()
+	if err != nil {
+		return nil, err
+	}
+	children, err := t.fsys().ReadDir(t.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	present := make(map[string]bool, len(children))
+	for _, child := range children {
+		present[child.Name()] = true
+	}
+	kept := items[:0]
+	for _, item := range items {
+		if present[item.Name] {
+			kept = append(kept, item)
+		}
+	}
+	return kept, nil
+}
+
+// index reads the index as it is, including entries recorded for a move
+// that hasn't happened yet
+func (t Trash) index() ([]TrashItem, error) {
+	content, err := readFile(t.fsys(), t.indexPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []TrashItem
+	if err := json.Unmarshal(content, &items); err != nil {
+		return nil, fmt.Errorf("reading trash index: %w", err)
+	}
+	return items, nil
+}
+
+// save replaces the index with items. Only call it holding the lock.
+func (t Trash) save(items []TrashItem) error {
+	content, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	fsys := t.fsys()
+	tmp := t.indexPath() + ".tmp"
+	file, err := fsys.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return moveFileOrDir(fsys, tmp, t.indexPath())
+}
+
+// lock takes the trash lock, waiting for whoever holds it, and returns how
+// to release it. A lock older than trashLockStale is taken over.
+func (t Trash) lock() (unlock func(), err error) {
+	fsys := t.fsys()
+	path := filepath.Join(t.Dir, trashLockName)
+	for {
+		file, err := createExclusive(fsys, path)
+		if err == nil {
+			file.Close()
+			return func() { fsys.RemoveAll(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := fsys.Stat(path); err == nil && time.Since(info.ModTime()) > trashLockStale {
+			fsys.RemoveAll(path)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// update reads the index, changes it and saves it, holding the lock
+func (t Trash) update(change func(items []TrashItem) []TrashItem) error {
+	unlock, err := t.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	items, err := t.index()
+	if err != nil {
+		return err
+	}
+	return t.save(change(items))
+}
+
+// forget drops the named entries from the index
+func (t Trash) forget(names map[string]bool) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return t.update(func(items []TrashItem) []TrashItem {
+		return slices.DeleteFunc(items, func(item TrashItem) bool { return names[item.Name] })
+	})
+}
+
+// record adds an entry to the index, to be moved to trashPath from path.
+// It is recorded first so nothing lands in the trash without its origin;
+// if the move then fails, the entry is left out of Items.
+func (t Trash) record(trashPath, path string) error {
+	if err := t.fsys().MkdirAll(t.Dir, 0755); err != nil {
+		return err
+	}
+	// The other entries of a batch being deleted are recorded but not moved
+	// yet. One recorded under the same name before is gone from the trash.
+	name := filepath.Base(trashPath)
+	return t.update(func(items []TrashItem) []TrashItem {
+		items = slices.DeleteFunc(items, func(item TrashItem) bool { return item.Name == name })
+		return append(items, TrashItem{Name: name, Path: path, DeletedAt: time.Now()})
+	})
+}
+
+// Restore moves the named entry back to where it was deleted from, with a
+// suffix if that name has been taken since, and returns where it went
+func (t Trash) Restore(name string) (string, error) {
+	items, err := t.Items()
+	if err != nil {
+		return "", err
+	}
+	i := slices.IndexFunc(items, func(item TrashItem) bool { return item.Name == name })
+	if i < 0 {
+		return "", fmt.Errorf("%s is not in the trash", name)
+	}
+
+	item := items[i]
+	fsys := t.fsys()
+	if err := fsys.MkdirAll(filepath.Dir(item.Path), 0755); err != nil {
+		return "", err
+	}
+	dest := uniquePath(fsys, item.Path)
+	if err := moveFileOrDir(fsys, filepath.Join(t.Dir, item.Name), dest); err != nil {
+		return "", err
+	}
+	return dest, t.forget(map[string]bool{name: true})
+}
+
+// Prune permanently deletes the entries trashed longer ago than retention
+func (t Trash) Prune(retention time.Duration) error {
+	items, err := t.Items()
+	if err != nil || len(items) == 0 {
+		return err
+	}
+	pruned := make(map[string]bool)
+	for _, item := range items {
+		if time.Since(item.DeletedAt) > retention {
+			if err = t.fsys().RemoveAll(filepath.Join(t.Dir, item.Name)); err != nil {
+				break
+			}
+			pruned[item.Name] = true
+		}
+	}
+	if forgetErr := t.forget(pruned); err == nil {
+		err = forgetErr
+	}
+	return err
+}
+
+// Empty permanently deletes everything in the trash and returns how many
+// entries there were. Entries trashed while it runs are kept.
+func (t Trash) Empty() (int, error) {
+	items, err := t.Items()
+	if err != nil {
+		return 0, err
+	}
+	emptied := make(map[string]bool)
+	for _, item := range items {
+		if err = t.fsys().RemoveAll(filepath.Join(t.Dir, item.Name)); err != nil {
+			break
+		}
+		emptied[item.Name] = true
+	}
+	if forgetErr := t.forget(emptied); err == nil {
+		err = forgetErr
+	}
+	return len(emptied), err
+}
+
+// Temporary trash directories still to be removed, by Close or, if the
+// process is interrupted before it gets there, by RemoveTrashOnSignal
+var (
+	tempTrashDirs   []string
+	tempTrashDirsMu sync.Mutex
+)
+
+// addTempTrash registers a temporary trash directory for removal
+func addTempTrash(dir string) {
+	tempTrashDirsMu.Lock()
+	defer tempTrashDirsMu.Unlock()
+	tempTrashDirs = append(tempTrashDirs, dir)
+}
+
+// removeTempTrash removes a temporary trash directory. Removing it again,
+// or one that was never registered, is harmless.
+func removeTempTrash(dir string) {
+	tempTrashDirsMu.Lock()
+	defer tempTrashDirsMu.Unlock()
+	os.RemoveAll(dir)
+	tempTrashDirs = slices.DeleteFunc(tempTrashDirs, func(d string) bool { return d == dir })
+}
+
+// RemoveTrashOnSignal removes the temporary trash when the process gets one
+// of signals, then delivers the signal again so it takes its usual course:
+// the program quits, or the process is terminated if it isn't running.
+// Call stop once the trash has been cleaned up normally.
+func RemoveTrashOnSignal(signals ...os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	return removeTrashOn(ch, func(sig os.Signal) {
+		signal.Stop(ch)
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			p.Signal(sig)
+		}
+	})
+}
+
+// removeTrashOn removes the temporary trash on the first signal from ch and
+// passes it to raise
+func removeTrashOn(ch <-chan os.Signal, raise func(os.Signal)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-ch:
+			tempTrashDirsMu.Lock()
+			for _, dir := range tempTrashDirs {
+				os.RemoveAll(dir)
+			}
+			tempTrashDirs = nil
+			tempTrashDirsMu.Unlock()
+			raise(sig)
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// How many entries the trash view shows at once
+const trashViewHeight = 10
+
+// openTrashView lists what is in the trash, most recently deleted first
+func (m *FileManager) openTrashView() {
+	var items []TrashItem
+	if m.trashDir != "" {
+		var err error
+		if items, err = m.trash().Items(); err != nil {
+			m.setError(err)
+			return
+		}
+	}
+	if len(items) == 0 {
+		m.setStatus("Trash is empty")
+		return
+	}
+	slices.Reverse(items)
+	m.trashItems = items
+	m.trashCursor = 0
+}
+
+// handleTrashKey handles keys while the trash view is open
+func (m *FileManager) handleTrashKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		m.trashItems = nil
+	case "up", "k":
+		if m.trashCursor > 0 {
+			m.trashCursor--
+		}
+	case "down", "j":
+		if m.trashCursor < len(m.trashItems)-1 {
+			m.trashCursor++
+		}
+	case "l", "enter", "right":
+		item := m.trashItems[m.trashCursor]
+		m.trashItems = nil
+		m.restoreFromTrash(item)
+	}
+	return nil
+}
+
+// restoreFromTrash moves an entry back to where it was deleted from. Undo
+// entries for its delete are dropped, as there is nothing left to undo.
+func (m *FileManager) restoreFromTrash(item TrashItem) {
+	dest, err := m.trash().Restore(item.Name)
+	if err != nil {
+		m.setError(err)
+		return
+	}
+	trashPath := filepath.Join(m.trashDir, item.Name)
+	kept := m.undoStack[:0]
+	for _, action := range m.undoStack {
+		if action.Type != "delete" || action.NewPath != trashPath {
+			kept = append(kept, action)
+		}
+	}
+	m.undoStack = kept
+
+	m.reloadKeepingSelection()
+	m.setStatus("Restored %s", dest)
+}
+
+// renderTrash renders the trash view overlay
+func (m *FileManager) renderTrash() string {
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("Trash, %s (enter restores, esc cancels)", pluralize(len(m.trashItems), "item")))
+	// Show a window of entries that follows the cursor
+	start := max(m.trashCursor-trashViewHeight+1, 0)
+	end := min(start+trashViewHeight, len(m.trashItems))
+	for i := start; i < end; i++ {
+		item := m.trashItems[i]
+		marker := "  "
+		if i == m.trashCursor {
+			marker = "> "
+		}
+		deleted := item.DeletedAt.Format("02 Jan 15:04")
+		// The original path is cut from the left to keep its name visible
+		path := truncateLeft(item.Path, max(m.Width-len(deleted)-10, 0))
+		content.WriteString(fmt.Sprintf("\n%s%s  %s", marker, deleted, path))
+	}
+	return whichKeyStyle.Width(m.Width).Render(content.String())
+}
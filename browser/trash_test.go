@@ -0,0 +1,274 @@
+package browser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTrashAcrossSessions(t *testing.T) {
+	dir, trashDir := t.TempDir(), t.TempDir()
+	for _, name := range []string{"old.txt", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Delete both in one session, which then ends
+	m, err := New(Options{StartPath: dir, TrashDir: trashDir, TrashRetention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.deleteFile()
+	m.deleteFile()
+	m.Close()
+
+	trash := Trash{Dir: trashDir}
+	items, err := trash.Items()
+	if err != nil || len(items) != 2 {
+		t.Fatalf("expected 2 items kept after exit, got %v, %v", items, err)
+	}
+
+	// Age one past the retention period; the next exit prunes it
+	items[0].DeletedAt = time.Now().Add(-2 * time.Hour)
+	kept := items[1].Name
+	if err := trash.save(items); err != nil {
+		t.Fatal(err)
+	}
+	m, err = New(Options{StartPath: dir, TrashDir: trashDir, TrashRetention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	items, _ = trash.Items()
+	if len(items) != 1 || items[0].Name != kept {
+		t.Fatalf("expected only %s left, got %v", kept, items)
+	}
+
+	// Restoring onto a name taken since keeps both
+	name := items[0].Name
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest, err := trash.Restore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(dest); err != nil || string(content) != name || dest == filepath.Join(dir, name) {
+		t.Fatalf("expected %s restored beside the new file, got %s: %q, %v", name, dest, content, err)
+	}
+	if items, _ := trash.Items(); len(items) != 0 {
+		t.Errorf("expected the trash empty, got %v", items)
+	}
+}
+
+func TestTrashUndoKeepsIndex(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, trashDir: t.TempDir()}
+	m.Entries, _ = m.readDirectory(dir)
+
+	m.deleteFile()
+	m.undoLastAction()
+	if items, _ := m.trash().Items(); len(items) != 0 {
+		t.Fatalf("expected the undone delete out of the trash, got %v", items)
+	}
+	m.redoLastAction()
+	if items, _ := m.trash().Items(); len(items) != 1 || items[0].Path != filepath.Join(dir, "a.txt") {
+		t.Fatalf("expected the redone delete back in the trash, got %v", items)
+	}
+}
+
+func TestTempTrashRemovedOnInterrupt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir}
+	if _, err := m.moveToTrash(path); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// An interrupt before Close removes the temporary trash, then is passed on
+	signals := make(chan os.Signal, 1)
+	raised := make(chan os.Signal, 1)
+	stop := removeTrashOn(signals, func(sig os.Signal) { raised <- sig })
+	defer stop()
+	signals <- os.Interrupt
+
+	select {
+	case sig := <-raised:
+		if sig != os.Interrupt {
+			t.Errorf("expected the interrupt passed on, got %v", sig)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("interrupt not handled")
+	}
+	if _, err := os.Stat(m.trashDir); !os.IsNotExist(err) {
+		t.Errorf("expected %s removed, got %v", m.trashDir, err)
+	}
+}
+
+func TestUndoDeleteWithoutItsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(sub, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &FileManager{CurrentPath: sub, trashDir: t.TempDir()}
+	m.Entries, _ = m.readDirectory(sub)
+	m.deleteFile()
+	m.Entries, _ = m.readDirectory(sub)
+	m.deleteFile()
+
+	// The directory is made again when it can be
+	m.CurrentPath = dir
+	if err := os.Remove(sub); err != nil {
+		t.Fatal(err)
+	}
+	m.undoLastAction()
+	if _, err := os.Stat(filepath.Join(sub, "b.txt")); err != nil {
+		t.Fatalf("expected b.txt restored into a new sub: %v (%s)", err, m.statusMsg)
+	}
+
+	// Otherwise the entry comes back in the current directory
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sub, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.undoLastAction()
+	if content, err := os.ReadFile(filepath.Join(dir, "a.txt")); err != nil || string(content) != "a.txt" {
+		t.Fatalf("expected a.txt restored into the current directory: %v (%s)", err, m.statusMsg)
+	}
+	if !strings.Contains(m.statusMsg, "directory is gone") {
+		t.Errorf("expected the status to say where it went, got %q", m.statusMsg)
+	}
+}
+
+func TestDeleteBatchIntoTakenTrashNames(t *testing.T) {
+	dir, trashDir := t.TempDir(), t.TempDir()
+	for _, name := range []string{"x", "x_1"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The trash already holds an x from an earlier session
+	if err := os.WriteFile(filepath.Join(trashDir, "x"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(Options{StartPath: dir, TrashDir: trashDir, TrashRetention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	for i := range m.Entries {
+		m.Entries[i].Selected = true
+	}
+	m.deleteFile()
+
+	items, err := m.trash().Items()
+	if err != nil || len(items) != 2 || items[0].Name == items[1].Name {
+		t.Fatalf("expected both files in the trash under their own names, got %v, %v", items, err)
+	}
+	for _, item := range items {
+		content, err := os.ReadFile(filepath.Join(trashDir, item.Name))
+		if err != nil || string(content) != filepath.Base(item.Path) {
+			t.Errorf("expected %s to hold %s, got %q, %v", item.Name, item.Path, content, err)
+		}
+	}
+	if content, _ := os.ReadFile(filepath.Join(trashDir, "x")); string(content) != "old" {
+		t.Errorf("expected the earlier x kept, got %q", content)
+	}
+}
+
+// createLogFS records the files created in it
+type createLogFS struct {
+	FS
+	created *[]string
+}
+
+func (f createLogFS) Create(name string) (io.WriteCloser, error) {
+	*f.created = append(*f.created, filepath.Base(name))
+	return f.FS.Create(name)
+}
+
+func TestTrashLockFS(t *testing.T) {
+	trashDir := t.TempDir()
+	var created []string
+	trash := Trash{Dir: trashDir, FS: createLogFS{OS, &created}}
+
+	// The lock is taken and released on the trash's filesystem
+	if err := trash.record(filepath.Join(trashDir, "a.txt"), "/from/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(created) == 0 || created[0] != trashLockName {
+		t.Fatalf("expected the lock created first, got %v", created)
+	}
+	if _, err := os.Stat(filepath.Join(trashDir, trashLockName)); !os.IsNotExist(err) {
+		t.Errorf("expected the lock released, got %v", err)
+	}
+}
+
+func TestTrashSharedIndex(t *testing.T) {
+	trashDir := t.TempDir()
+	trash := Trash{Dir: trashDir}
+
+	// Several processes trashing at once each keep their entry
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("file%d", i)
+			if err := trash.record(filepath.Join(trashDir, name), "/from/"+name); err != nil {
+				t.Error(err)
+			}
+			if err := os.WriteFile(filepath.Join(trashDir, name), nil, 0644); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if items, err := trash.Items(); err != nil || len(items) != 20 {
+		t.Fatalf("expected 20 items, got %d, %v", len(items), err)
+	}
+
+	// Emptying runs in the background; a delete made meanwhile can still
+	// be undone
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "late.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &FileManager{CurrentPath: dir, trashDir: trashDir}
+	m.Entries, _ = m.readDirectory(dir)
+	cmd := m.confirmEmptyTrash()
+	if m.confirm == nil || !strings.Contains(m.confirm.prompt, "counting") {
+		t.Fatalf("expected the prompt while counting, got %+v", m.confirm)
+	}
+	m.Update(cmd())
+	emptied := m.confirm.actions["y"]()()
+	m.deleteFile()
+	m.Update(emptied)
+	if items, _ := trash.Items(); len(items) != 1 || items[0].Name != "late.txt" {
+		t.Fatalf("expected only late.txt left, got %v", items)
+	}
+	if len(m.undoStack) != 1 || !strings.Contains(m.statusMsg, "Emptied trash (20 items)") {
+		t.Errorf("expected the late delete undoable, got %v, %q", m.undoStack, m.statusMsg)
+	}
+}
@@ -0,0 +1,55 @@
+package browser
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// How long type-ahead waits for the next key before starting over
+const typeAheadTimeout = 800 * time.Millisecond
+
+// typingAhead reports whether a name is being typed ahead, so the next
+// printable key extends it instead of running a command
+func (m *FileManager) typingAhead() bool {
+	return m.typeAhead != "" && time.Since(m.typeAheadTime) < typeAheadTimeout
+}
+
+// handleTypeAhead adds a key to the name typed ahead and moves the cursor
+// to the first entry starting with it, or else containing it. It reports
+// false for keys that aren't text.
+func (m *FileManager) handleTypeAhead(msg tea.KeyMsg) bool {
+	if msg.Type != tea.KeyRunes || msg.Alt {
+		return false
+	}
+	if !m.typingAhead() {
+		m.typeAhead = ""
+	}
+	m.typeAhead += string(msg.Runes)
+	m.typeAheadTime = time.Now()
+
+	typed := strings.ToLower(m.typeAhead)
+	prefix, contains := -1, -1
+	for i, entry := range m.Entries {
+		name := strings.ToLower(entry.Name)
+		if strings.HasPrefix(name, typed) {
+			prefix = i
+			break
+		}
+		if contains < 0 && strings.Contains(name, typed) {
+			contains = i
+		}
+	}
+	match := prefix
+	if match < 0 {
+		match = contains
+	}
+	if match < 0 {
+		m.setStatus("No entry matches %q", m.typeAhead)
+		return true
+	}
+	m.Cursor = match
+	m.setStatus("Jump: %s", m.typeAhead)
+	return true
+}
@@ -0,0 +1,134 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Video extensions previewed with ffprobe
+var videoExtensions = map[string]bool{
+	".mp4":  true,
+	".mkv":  true,
+	".mov":  true,
+	".webm": true,
+	".avi":  true,
+}
+
+const ffprobeTimeout = 3 * time.Second
+
+// ffprobeOutput is the subset of `ffprobe -print_format json` we display
+type ffprobeOutput struct {
+	Format struct {
+		FormatLongName string `json:"format_long_name"`
+		Duration       string `json:"duration"`
+		BitRate        string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		Channels     int    `json:"channels"`
+		SampleRate   string `json:"sample_rate"`
+	} `json:"streams"`
+}
+
+// isVideoFile reports whether a file should get the video preview
+func isVideoFile(name string) bool {
+	return videoExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// probeVideo runs ffprobe on a file and formats the result as a key/value
+// list, or returns "" when ffprobe is unavailable
+func probeVideo(path string) string {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ffprobeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return ""
+	}
+
+	var preview strings.Builder
+	field := func(key, value string) {
+		if value != "" {
+			preview.WriteString(fmt.Sprintf("%-12s %s\n", key+":", value))
+		}
+	}
+
+	field("Format", probe.Format.FormatLongName)
+	field("Duration", formatDuration(probe.Format.Duration))
+	if bitRate, err := strconv.Atoi(probe.Format.BitRate); err == nil {
+		field("Bit rate", fmt.Sprintf("%d kb/s", bitRate/1000))
+	}
+
+	for _, stream := range probe.Streams {
+		switch stream.CodecType {
+		case "video":
+			field("Resolution", fmt.Sprintf("%dx%d", stream.Width, stream.Height))
+			field("Video", stream.CodecName)
+			field("Frame rate", formatFrameRate(stream.AvgFrameRate))
+		case "audio":
+			audio := stream.CodecName
+			if stream.Channels > 0 {
+				audio += fmt.Sprintf(" (%d ch", stream.Channels)
+				if stream.SampleRate != "" {
+					audio += ", " + stream.SampleRate + " Hz"
+				}
+				audio += ")"
+			}
+			field("Audio", audio)
+		case "subtitle":
+			field("Subtitles", stream.CodecName)
+		}
+	}
+
+	return preview.String()
+}
+
+// formatDuration turns ffprobe's seconds string into h:mm:ss
+func formatDuration(seconds string) string {
+	value, err := strconv.ParseFloat(seconds, 64)
+	if err != nil {
+		return ""
+	}
+	total := int(value)
+	return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+}
+
+// formatFrameRate turns ffprobe's "30000/1001" style rate into frames per second
+func formatFrameRate(rate string) string {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		return ""
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f fps", n/d)
+}
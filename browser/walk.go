@@ -0,0 +1,94 @@
+package browser
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+)
+
+// walkTree is filepath.WalkDir over fsys, parents before their children and
+// without following symlinks, but stopping with ctx's error as soon as ctx
+// is done
+func walkTree(ctx context.Context, fsys FS, root string, fn fs.WalkDirFunc) error {
+	info, err := lstat(fsys, root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(ctx, fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDir walks path and, for a directory, everything below it
+func walkDir(ctx context.Context, fsys FS, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Let fn decide whether an unreadable directory ends the walk
+		if err = fn(path, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := walkDir(ctx, fsys, filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkFiles calls fn for every file under root (anything that isn't a
+// directory), without following symlinks. Unreadable entries are skipped.
+// The walk stops with ctx's error as soon as ctx is done.
+func WalkFiles(ctx context.Context, root string, fn func(path string, info fs.FileInfo)) error {
+	return walkFiles(ctx, OS, root, fn)
+}
+
+// walkFiles is WalkFiles over fsys
+func walkFiles(ctx context.Context, fsys FS, root string, fn func(path string, info fs.FileInfo)) error {
+	return walkTree(ctx, fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip what we can't read instead of failing the whole walk
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			fn(path, info)
+		}
+		return nil
+	})
+}
+
+// dirSize returns the total size and number of files under root in fsys
+func dirSize(ctx context.Context, fsys FS, root string) (int64, int, error) {
+	var size int64
+	var files int
+	err := walkFiles(ctx, fsys, root, func(path string, info fs.FileInfo) {
+		size += info.Size()
+		files++
+	})
+	return size, files, err
+}
@@ -0,0 +1,126 @@
+package browser
+
+import (
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Events arriving this close together are handled with one reload, so busy
+// directories don't re-sort on every write
+const watchSettle = 200 * time.Millisecond
+
+// dirChangedMsg reports that a watched directory changed
+type dirChangedMsg struct {
+	watcher *fsnotify.Watcher // Watcher that saw the change
+	path    string            // Changed entry, or the directory itself
+	failed  bool              // The watcher reported an error, so changes may have been missed
+}
+
+// startWatching starts watching the current directory for changes
+func (m *FileManager) startWatching() (tea.Cmd, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	m.watcher = watcher
+	m.watchCurrentDir()
+	return waitForChange(watcher), nil
+}
+
+// stopWatching stops watching for changes, if we were
+func (m *FileManager) stopWatching() {
+	if m.watcher == nil {
+		return
+	}
+	m.watcher.Close()
+	m.watcher = nil
+	m.watchedPath = ""
+}
+
+// watchCurrentDir moves the watch to the current directory after navigating
+func (m *FileManager) watchCurrentDir() {
+	if m.watcher == nil || m.watchedPath == m.CurrentPath {
+		return
+	}
+	if m.watchedPath != "" {
+		// The old directory may be gone already
+		m.watcher.Remove(m.watchedPath)
+	}
+	m.watchedPath = ""
+	if err := m.watcher.Add(m.CurrentPath); err == nil {
+		m.watchedPath = m.CurrentPath
+	}
+}
+
+// waitForChange waits for the next change seen by watcher and lets it settle
+func waitForChange(watcher *fsnotify.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		var event fsnotify.Event
+		failed := false
+		for event.Name == "" && !failed {
+			var ok bool
+			select {
+			case event, ok = <-watcher.Events:
+			case _, ok = <-watcher.Errors:
+				// Overflows and the like: reload to catch up
+				failed = true
+			}
+			if !ok {
+				return nil // Watcher closed
+			}
+		}
+
+		// Fold the rest of a burst into this change
+		settle := time.NewTimer(watchSettle)
+		defer settle.Stop()
+		for {
+			select {
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return nil
+				}
+			case <-settle.C:
+				return dirChangedMsg{watcher: watcher, path: event.Name, failed: failed}
+			}
+		}
+	}
+}
+
+// handleDirChanged reloads the current directory when it changed, keeping
+// the cursor on the same entry, and waits for the next change
+func (m *FileManager) handleDirChanged(msg dirChangedMsg) tea.Cmd {
+	if msg.watcher != m.watcher {
+		return nil // Watching was turned off (or restarted) meanwhile
+	}
+	if msg.failed {
+		// The watch itself may be lost, so add it again
+		m.watchedPath = ""
+		m.watchCurrentDir()
+	}
+	changed := msg.failed || msg.path == m.CurrentPath || filepath.Dir(msg.path) == m.CurrentPath
+	if changed && m.loadingDir == "" {
+		m.refresh()
+	}
+	return waitForChange(m.watcher)
+}
+
+// toggleWatching turns watching the current directory on or off
+func (m *FileManager) toggleWatching() tea.Cmd {
+	if m.watcher != nil {
+		m.stopWatching()
+		m.setStatus("Stopped watching for changes")
+		return nil
+	}
+	cmd, err := m.startWatching()
+	if err != nil {
+		m.setError(err)
+		return nil
+	}
+	// Catch up with anything that changed while we weren't watching
+	m.reloadKeepingSelection()
+	m.setStatus("Watching for changes")
+	return cmd
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// bookmarksFile returns the path of the bookmarks store, shared by the CLI and the TUI
+func bookmarksFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bookmarks"), nil
+}
+
+// loadBookmarks reads bookmarks as name -> path. A missing file means no bookmarks.
+func loadBookmarks() (map[string]string, error) {
+	bookmarks := make(map[string]string)
+
+	path, err := bookmarksFile()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return bookmarks, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// One bookmark per line: name<TAB>path
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, target, ok := strings.Cut(scanner.Text(), "\t")
+		if ok && name != "" {
+			bookmarks[name] = target
+		}
+	}
+	return bookmarks, scanner.Err()
+}
+
+// saveBookmarks writes all bookmarks, replacing the file atomically
+func saveBookmarks(bookmarks map[string]string) error {
+	path, err := bookmarksFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	for _, name := range sortedKeys(bookmarks) {
+		content.WriteString(name + "\t" + bookmarks[name] + "\n")
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// browseBookmarks returns the bookmarks for the TUI, which starts without
+// them if they can't be read
+func browseBookmarks() map[string]string {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return nil
+	}
+	return bookmarks
+}
+
+// addBookmark saves one bookmark, keeping the others as they are on disk
+func addBookmark(name, path string) error {
+	bookmarks, err := loadBookmarks()
+	if err != nil {
+		return err
+	}
+	bookmarks[name] = path
+	return saveBookmarks(bookmarks)
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// bookmark command
+var bookmarkCmd = &cobra.Command{
+	Use:   "bookmark",
+	Short: "Manage directory bookmarks",
+}
+
+var bookmarkListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List bookmarks",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		bookmarks, err := loadBookmarks()
+		if err != nil {
+			fmt.Println("Error reading bookmarks:", err)
+			os.Exit(1)
+		}
+		if len(bookmarks) == 0 {
+			fmt.Println("No bookmarks")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tPATH")
+		for _, name := range sortedKeys(bookmarks) {
+			target := bookmarks[name]
+			if _, err := os.Stat(target); err != nil {
+				target += " (missing)"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", name, target)
+		}
+		w.Flush()
+	},
+}
+
+var bookmarkAddCmd = &cobra.Command{
+	Use:   "add <name> [path]",
+	Short: "Bookmark a directory (default is the current directory)",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if strings.ContainsAny(name, "\t\n") {
+			fmt.Println("Bookmark names cannot contain tabs or newlines")
+			os.Exit(1)
+		}
+
+		target := "."
+		if len(args) > 1 {
+			target = args[1]
+		}
+		absPath, err := filepath.Abs(target)
+		if err != nil {
+			fmt.Println("Error resolving path:", err)
+			os.Exit(1)
+		}
+		if info, err := os.Stat(absPath); err != nil || !info.IsDir() {
+			fmt.Println("Not a directory:", absPath)
+			os.Exit(1)
+		}
+
+		if err := addBookmark(name, absPath); err != nil {
+			fmt.Println("Error saving bookmarks:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Bookmarked %s as %s\n", absPath, name)
+	},
+}
+
+var bookmarkRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a bookmark",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		bookmarks, err := loadBookmarks()
+		if err != nil {
+			fmt.Println("Error reading bookmarks:", err)
+			os.Exit(1)
+		}
+		if _, ok := bookmarks[args[0]]; !ok {
+			fmt.Println("No bookmark named", args[0])
+			os.Exit(1)
+		}
+		delete(bookmarks, args[0])
+		if err := saveBookmarks(bookmarks); err != nil {
+			fmt.Println("Error saving bookmarks:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Removed bookmark", args[0])
+	},
+}
+
+func init() {
+	bookmarkCmd.AddCommand(bookmarkListCmd, bookmarkAddCmd, bookmarkRemoveCmd)
+	rootCmd.AddCommand(bookmarkCmd)
+}
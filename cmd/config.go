@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bytewer-lab/tfm/browser"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// Supported config formats, in lookup order
+var configExtensions = []string{"yaml", "yml", "toml", "json"}
+
+func init() {
+	// Defaults for settings that are on unless turned off
+	viper.SetDefault("auto_preview", true)
+	viper.SetDefault("preview_theme", "monokai")
+	viper.SetDefault("rename_overwrite", "ask")
+	viper.SetDefault("run_executables", "open")
+	viper.SetDefault("enter_files", "open")
+	viper.SetDefault("relative_times", true)
+	viper.SetDefault("trash_retention_days", 30)
+
+	// Open directories with the user's editor unless configured otherwise
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	viper.SetDefault("editor", editor)
+
+	// Spellings accepted for the same setting
+	viper.RegisterAlias("show_size", "show_sizes")
+}
+
+// configDir returns the user config directory for tfm, honoring $XDG_CONFIG_HOME
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "tfm"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "tfm"), nil
+}
+
+// profileFile returns the config file of a named profile
+func profileFile(name string) (string, error) {
+	if name != filepath.Base(name) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	path := findConfigFile(filepath.Join(dir, "profiles"), name)
+	if path == "" {
+		return "", fmt.Errorf("no profile %q in %s", name, filepath.Join(dir, "profiles"))
+	}
+	return path, nil
+}
+
+// templateDir returns where new-file templates are kept, or "" when there
+// is no config directory
+func templateDir() string {
+	dir, err := configDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "templates")
+}
+
+// trashDir returns the trash kept across sessions: trash_dir if set,
+// otherwise trash under the data directory, or "" when there is none
+func trashDir() string {
+	if dir := viper.GetString("trash_dir"); dir != "" {
+		return dir
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "trash")
+}
+
+// findConfigFile returns the first existing dir/name.<ext>, or "" if there is none
+func findConfigFile(dir, name string) string {
+	for _, ext := range configExtensions {
+		path := filepath.Join(dir, name+"."+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// discoverConfigFiles returns the config files to load, lowest precedence first:
+// the user config (or the legacy ~/.tfm.*), then a project-local .tfm.* in the
+// working directory
+func discoverConfigFiles() []string {
+	var files []string
+
+	if dir, err := configDir(); err == nil {
+		if path := findConfigFile(dir, "tfm"); path != "" {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		if home, err := os.UserHomeDir(); err == nil {
+			if path := findConfigFile(home, ".tfm"); path != "" {
+				files = append(files, path)
+			}
+		}
+	}
+
+	if cwd, err := os.Getwd(); err == nil {
+		if path := findConfigFile(cwd, ".tfm"); path != "" && (len(files) == 0 || files[0] != path) {
+			files = append(files, path)
+		}
+	}
+
+	return files
+}
+
+// loadConfigFiles merges the given config files into viper, later files
+// overriding earlier ones, and returns the ones that were read
+func loadConfigFiles(files []string) ([]string, error) {
+	var loaded []string
+	for _, path := range files {
+		viper.SetConfigFile(path)
+		if err := viper.MergeInConfig(); err != nil {
+			return loaded, err
+		}
+		loaded = append(loaded, path)
+	}
+	return loaded, nil
+}
+
+// openerSettings reads the open commands by extension. Each is either a
+// command, run in the background, or a map with command and foreground.
+func openerSettings() map[string]browser.Opener {
+	openers := make(map[string]browser.Opener)
+	for ext, value := range viper.GetStringMap("open") {
+		if opener, ok := parseOpener(value); ok {
+			openers[ext] = opener
+		}
+	}
+	return openers
+}
+
+// openWithSettings reads the applications the open with menu offers by
+// extension: a list of commands written like open ones, or a single one
+func openWithSettings() map[string][]browser.Opener {
+	apps := make(map[string][]browser.Opener)
+	for ext, value := range viper.GetStringMap("open_with") {
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+		for _, value := range values {
+			if opener, ok := parseOpener(value); ok {
+				apps[ext] = append(apps[ext], opener)
+			}
+		}
+	}
+	return apps
+}
+
+// parseOpener reads a command from the config, as a string or a map with
+// command and foreground
+func parseOpener(value any) (browser.Opener, bool) {
+	switch value := value.(type) {
+	case string:
+		return browser.Opener{Command: value}, true
+	case map[string]any:
+		return browser.Opener{
+			Command:    cast.ToString(value["command"]),
+			Foreground: cast.ToBool(value["foreground"]),
+		}, true
+	}
+	return browser.Opener{}, false
+}
+
+// sortSetting returns the sort mode for dir_sort or file_sort, falling back
+// to the shared sort key when the split one isn't set
+func sortSetting(key string) string {
+	if mode := viper.GetString(key); mode != "" {
+		return mode
+	}
+	return viper.GetString("sort")
+}
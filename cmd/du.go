@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bytewer-lab/tfm/browser"
+	"github.com/spf13/cobra"
+)
+
+// Flags for the du command
+var (
+	duDepth int
+	duSort  bool
+	duTop   int
+	duBytes bool
+)
+
+// dirTotal is the aggregated size of one directory
+type dirTotal struct {
+	path string
+	size int64
+}
+
+// dirTotals sums file sizes under root into every directory up to maxDepth
+// levels below it. The root itself is always included.
+func dirTotals(ctx context.Context, root string, maxDepth int) ([]dirTotal, error) {
+	sizes := map[string]int64{root: 0}
+
+	err := browser.WalkFiles(ctx, root, func(path string, info fs.FileInfo) {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return
+		}
+
+		// Add the file to each ancestor directory we report on
+		parts := strings.Split(filepath.Dir(rel), string(filepath.Separator))
+		if parts[0] == "." {
+			parts = nil
+		}
+		dir := root
+		sizes[dir] += info.Size()
+		for depth, part := range parts {
+			if depth >= maxDepth {
+				break
+			}
+			dir = filepath.Join(dir, part)
+			sizes[dir] += info.Size()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make([]dirTotal, 0, len(sizes))
+	for path, size := range sizes {
+		totals = append(totals, dirTotal{path: path, size: size})
+	}
+	return totals, nil
+}
+
+// du command
+var duCmd = &cobra.Command{
+	Use:   "du [path]",
+	Short: "Print recursive directory sizes",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		root := "."
+		if len(args) > 0 {
+			root = args[0]
+		}
+		root = filepath.Clean(root)
+
+		if info, err := os.Stat(root); err != nil {
+			fmt.Println("Error accessing directory:", err)
+			os.Exit(1)
+		} else if !info.IsDir() {
+			fmt.Println("The specified path is not a directory")
+			os.Exit(1)
+		}
+
+		totals, err := dirTotals(context.Background(), root, duDepth)
+		if err != nil {
+			fmt.Println("Error reading directory:", err)
+			os.Exit(1)
+		}
+
+		// Largest first when sorting, otherwise by path like du
+		sort.Slice(totals, func(i, j int) bool {
+			if duSort && totals[i].size != totals[j].size {
+				return totals[i].size > totals[j].size
+			}
+			return totals[i].path < totals[j].path
+		})
+		if duTop > 0 && len(totals) > duTop {
+			totals = totals[:duTop]
+		}
+
+		for _, total := range totals {
+			size := browser.HumanSize(total.size)
+			if duBytes {
+				size = fmt.Sprint(total.size)
+			}
+			fmt.Printf("%s\t%s\n", size, total.path)
+		}
+	},
+}
+
+func init() {
+	duCmd.Flags().IntVarP(&duDepth, "depth", "d", 1, "how many levels below path to report sizes for")
+	duCmd.Flags().BoolVarP(&duSort, "sort", "s", false, "sort by size, largest first")
+	duCmd.Flags().IntVarP(&duTop, "top", "n", 0, "only print the first N entries")
+	duCmd.Flags().BoolVarP(&duBytes, "bytes", "b", false, "print sizes in bytes instead of human-readable units")
+	rootCmd.AddCommand(duCmd)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bytewer-lab/tfm/browser"
+	"github.com/spf13/cobra"
+)
+
+// Whether the picked path is printed, with the TUI drawn on stderr
+var pickToStdout bool
+
+// writePicked hands the path picked with ctrl+y over: to --out-file if set,
+// to stdout for pick-path, otherwise to the clipboard
+func writePicked(path string) error {
+	switch {
+	case outFile != "":
+		return os.WriteFile(outFile, []byte(path+"\n"), 0644)
+	case pickToStdout:
+		_, err := fmt.Println(path)
+		return err
+	default:
+		return browser.CopyToClipboard(path)
+	}
+}
+
+// pick-path command
+var pickPathCmd = &cobra.Command{
+	Use:   "pick-path [path]",
+	Short: "Browse to a path, pick it with ctrl+y and print it",
+	Long: `Browse as usual; ctrl+y prints the selected path and quits, for shell
+wrappers such as cd "$(tfm pick-path --pick dir)". Quitting any other way
+prints nothing.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pickToStdout = true
+		browseCmd.Run(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pickPathCmd)
+	addBrowseFlags(pickPathCmd.Flags())
+}
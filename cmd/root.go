@@ -29,7 +29,11 @@ import (
 	"github.com/spf13/viper"
 )
 
-var cfgFile string
+var (
+	cfgFile string
+	profile string
+	verbose bool
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -58,7 +62,9 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.tfm.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $XDG_CONFIG_HOME/tfm/tfm.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "load profiles/<name>.yaml from the config directory over the config")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "print which config files were loaded")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -67,24 +73,44 @@ func init() {
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	var files []string
 	if cfgFile != "" {
 		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
+		files = []string{cfgFile}
 	} else {
-		// Find home directory.
-		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
-
-		// Search config in home directory with name ".tfm" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".tfm")
+		// User config (tfm.yaml/.toml/.json), then a project-local .tfm.* on top
+		files = discoverConfigFiles()
+	}
+
+	// A profile is layered over everything else
+	var profilePath string
+	if profile != "" {
+		path, err := profileFile(profile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading profile:", err)
+			os.Exit(1)
+		}
+		profilePath = path
+		files = append(files, path)
 	}
 
 	viper.AutomaticEnv() // read in environment variables that match
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	loaded, err := loadConfigFiles(files)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading config file:", err)
+		// A config asked for by name must be used; a discovered one that
+		// fails to load is skipped
+		if failed := files[len(loaded)]; failed == cfgFile || failed == profilePath {
+			os.Exit(1)
+		}
+	}
+	if verbose {
+		if profile != "" {
+			fmt.Fprintln(os.Stderr, "Using profile:", profile)
+		}
+		for _, path := range loaded {
+			fmt.Fprintln(os.Stderr, "Using config file:", path)
+		}
 	}
 }
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stateDir returns the state directory for tfm, honoring $XDG_STATE_HOME
+func stateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "tfm"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "tfm"), nil
+}
+
+// dataDir returns the data directory for tfm, honoring $XDG_DATA_HOME
+func dataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "tfm"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "tfm"), nil
+}
+
+// lastDirFile returns the path of the file remembering the last directory
+func lastDirFile() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "last_dir"), nil
+}
+
+// loadLastDir returns the directory tfm was last closed in, or "" when it
+// isn't known or no longer exists
+func loadLastDir() string {
+	path, err := lastDirFile()
+	if err != nil {
+		return ""
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	dir := strings.TrimSpace(string(content))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+	return dir
+}
+
+// saveLastDir remembers the directory tfm was closed in
+func saveLastDir(dir string) error {
+	path, err := lastDirFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(dir+"\n"), 0644)
+}
+
+// recentDirsFile returns the path of the file listing recent directories
+func recentDirsFile() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "recent_dirs"), nil
+}
+
+// loadRecentDirs returns the recently visited directories, most recent
+// first. A missing file means none.
+func loadRecentDirs() []string {
+	path, err := recentDirsFile()
+	if err != nil {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs
+}
+
+// saveRecentDirs writes the recent directories, one per line
+func saveRecentDirs(dirs []string) error {
+	path, err := recentDirsFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var content strings.Builder
+	for _, dir := range dirs {
+		content.WriteString(dir + "\n")
+	}
+	return os.WriteFile(path, []byte(content.String()), 0644)
+}
+
+// marksFile returns the path of the file keeping marks
+func marksFile() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "marks"), nil
+}
+
+// loadMarks reads marks as letter -> path. A missing file means no marks.
+func loadMarks() map[string]string {
+	marks := make(map[string]string)
+	path, err := marksFile()
+	if err != nil {
+		return marks
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return marks
+	}
+	// One mark per line: letter<TAB>path
+	for _, line := range strings.Split(string(content), "\n") {
+		if letter, target, ok := strings.Cut(line, "\t"); ok {
+			marks[letter] = target
+		}
+	}
+	return marks
+}
+
+// saveMarks writes all marks, replacing the file
+func saveMarks(marks map[string]string) error {
+	path, err := marksFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var content strings.Builder
+	for _, letter := range sortedKeys(marks) {
+		content.WriteString(letter + "\t" + marks[letter] + "\n")
+	}
+	return os.WriteFile(path, []byte(content.String()), 0644)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bytewer-lab/tfm/browser"
+	"github.com/spf13/cobra"
+)
+
+// Skips the confirmation of trash empty
+var emptyForce bool
+
+// openTrash returns the trash shared with the TUI, exiting if there is none
+func openTrash() browser.Trash {
+	dir := trashDir()
+	if dir == "" {
+		fmt.Println("No trash directory: set trash_dir or $HOME")
+		os.Exit(1)
+	}
+	return browser.Trash{Dir: dir}
+}
+
+// countItems returns "1 item" or "n items"
+func countItems(n int) string {
+	if n == 1 {
+		return "1 item"
+	}
+	return fmt.Sprintf("%d items", n)
+}
+
+// trash command
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore and empty deleted files",
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List what is in the trash, oldest first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		items, err := openTrash().Items()
+		if err != nil {
+			fmt.Println("Error reading trash:", err)
+			os.Exit(1)
+		}
+		if len(items) == 0 {
+			fmt.Println("Trash is empty")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tDELETED\tFROM")
+		for _, item := range items {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", item.Name, item.DeletedAt.Format("2006-01-02 15:04"), item.Path)
+		}
+		w.Flush()
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <name>...",
+	Short: "Move entries from the trash back to where they were deleted from",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		trash := openTrash()
+		failed := false
+		for _, name := range args {
+			dest, err := trash.Restore(name)
+			if err != nil {
+				fmt.Printf("Error restoring %s: %v\n", name, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("Restored %s to %s\n", name, dest)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently delete everything in the trash",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		trash := openTrash()
+		items, err := trash.Items()
+		if err != nil {
+			fmt.Println("Error reading trash:", err)
+			os.Exit(1)
+		}
+		if len(items) == 0 {
+			fmt.Println("Trash is empty")
+			return
+		}
+
+		if !emptyForce {
+			var size int64
+			for _, item := range items {
+				browser.WalkFiles(context.Background(), filepath.Join(trash.Dir, item.Name), func(path string, info fs.FileInfo) {
+					size += info.Size()
+				})
+			}
+			fmt.Printf("Permanently delete %s (%s) from trash? [y/N] ", countItems(len(items)), browser.HumanSize(size))
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+				fmt.Println("Trash not emptied")
+				return
+			}
+		}
+
+		emptied, err := trash.Empty()
+		if err != nil {
+			fmt.Println("Error emptying trash:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Emptied %s from trash\n", countItems(emptied))
+	},
+}
+
+func init() {
+	trashEmptyCmd.Flags().BoolVarP(&emptyForce, "force", "y", false, "empty without asking first")
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd, trashEmptyCmd)
+	rootCmd.AddCommand(trashCmd)
+}